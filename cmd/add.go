@@ -9,7 +9,7 @@ import (
 )
 
 func newAddCmd() *cobra.Command {
-	return &cobra.Command{
+	addCmd := &cobra.Command{
 		Use:   "add <branch-name>",
 		Short: "Create a new layer (branch) on top of the current branch.",
 		Args:  cobra.ExactArgs(1),
@@ -18,9 +18,10 @@ func newAddCmd() *cobra.Command {
 			defer locks.UnlockRepo()
 
 			branchName := args[0]
+			stackName, _ := cmd.Flags().GetString("stack")
 			logs.Info("Creating new stack layer: %s", branchName)
 
-			err := service.GetStackService().CreateNewLayer(branchName)
+			err := service.GetStackService().CreateNewLayer(branchName, stackName)
 			if err != nil {
 				logs.Error("Failed to create new layer '%s': %v", branchName, err)
 				return err
@@ -30,4 +31,6 @@ func newAddCmd() *cobra.Command {
 			return nil
 		},
 	}
+	addCmd.Flags().String("stack", "", "Named stack for the new layer (defaults to the parent's stack)")
+	return addCmd
 }
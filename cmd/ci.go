@@ -2,8 +2,12 @@ package cmd
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"strata/internal/locks"
+	"strata/internal/output"
 	"strata/internal/service"
+	"strata/internal/store"
 
 	"github.com/spf13/cobra"
 )
@@ -12,28 +16,212 @@ func newCICmd() *cobra.Command {
 	ciCmd := &cobra.Command{
 		Use:   "ci",
 		Short: "CI/CD related commands for Strata (checks if a branch can be merged, etc.)",
+		Long: `CI/CD related commands for Strata.
+
+They work in a fresh CI clone without a stack file: the branch defaults to the PR's
+head from the job environment (GitHub Actions, Gitea/Forgejo Actions, GitLab CI, or
+STRATA_CI_BRANCH), and the stack is rebuilt from the forge's open PRs using their
+bases and the parent recorded in each PR's Strata section. The forge token comes
+from the usual variables (GITHUB_TOKEN/GH_TOKEN, GITLAB_TOKEN, GITEA_TOKEN).`,
 	}
 
 	checkCmd := &cobra.Command{
-		Use:   "check <branch>",
+		Use:   "check [branch]",
 		Short: "Check if <branch> can be merged based on the stack state.",
-		Args:  cobra.ExactArgs(1),
+		Long: `Check that <branch> can be merged into its target (its parent, or the trunk once
+the parents have landed). Each rule is reported as pass, fail or skip:
+
+  parent_landed    the branch sits directly on a trunk
+  contains_parent  the branch contains the target's current tip
+  merges_cleanly   a test merge (git merge-tree, git 2.38+) has no conflicting files
+  pr_base          the branch's PR targets the target; skipped without a PR or forge
+
+Your org's merge rules come from .strata/policy.yaml (or --policy). Each rule has a
+severity of error (the default), warning or off; only errors fail the check:
+
+  max_commits:          {max: 5}
+  max_diff_lines:       {max: 400, severity: warning}
+  linear_history:       {}
+  conventional_commits: {types: [feat, fix, chore]}
+  forbidden_paths:      {paths: ["vendor/**"], layers: {"docs/*": ["src/**"]}}
+  max_stack_depth:      {max: 4}
+  ticket_reference:     {pattern: "[A-Z]+-[0-9]+", in: commits}   # or any, branch
+
+Local branches are used when present, otherwise origin's copies. The exit code is 3
+when the test merge conflicts and 1 when another rule fails. Besides --output json,
+--junit and --sarif write the results as JUnit XML or SARIF ("-" for stdout).`,
+		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			locks.LockRepo()
 			defer locks.UnlockRepo()
 
-			branch := args[0]
-			err := service.GetCIService().CheckMergeFeasibility(branch)
+			branch, err := service.GetCIService().PrepareCI(firstArg(args))
+			if err != nil {
+				return err
+			}
+			policy, _ := cmd.Flags().GetString("policy")
+			junit, _ := cmd.Flags().GetString("junit")
+			sarif, _ := cmd.Flags().GetString("sarif")
+			report, err := service.GetCIService().CheckMergeFeasibility(branch, policy)
+			doc := newCICheckDoc(report, err)
+			if len(report.Rules) > 0 {
+				if wErr := writeCIReport(junit, doc, output.WriteJUnit); wErr != nil {
+					return wErr
+				}
+				if wErr := writeCIReport(sarif, doc, output.WriteSARIF); wErr != nil {
+					return wErr
+				}
+				if junit == "-" || sarif == "-" {
+					cmd.SilenceUsage = true
+					return output.Reported(err)
+				}
+			}
+			if output.Structured() {
+				if pErr := output.Print(output.KindCICheck, doc); pErr != nil {
+					return pErr
+				}
+				// the verdict already describes the failure; only the exit code is left to report
+				return output.Reported(err)
+			}
+			if len(report.Rules) > 0 {
+				fmt.Println(service.RenderCIReport(report))
+			}
 			if err != nil {
+				cmd.SilenceUsage = true
 				fmt.Println("CI check failed:", err)
 				// return an error so the pipeline can fail
 				return err
 			}
-			fmt.Printf("Branch '%s' can be safely merged.\n", branch)
+			fmt.Printf("Branch '%s' can be safely merged into '%s'.\n", branch, report.Target)
+			return nil
+		},
+	}
+
+	checkCmd.Flags().String("policy", "", "Policy file with the org's merge rules, relative to the repository root (default .strata/policy.yaml)")
+	checkCmd.Flags().String("junit", "", "Write the results as JUnit XML to this file (\"-\" for stdout)")
+	checkCmd.Flags().String("sarif", "", "Write the results as SARIF to this file (\"-\" for stdout)")
+
+	affectedCmd := &cobra.Command{
+		Use:   "affected-layers [branch]",
+		Short: "List the branch and every layer stacked on it, parents first",
+		Long: `List the branch and every layer stacked on it, parents first: the layers a change
+to the branch affects, e.g. to decide which jobs to run or which PRs to restack.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			locks.LockRepo()
+			defer locks.UnlockRepo()
+
+			branch, err := service.GetCIService().PrepareCI(firstArg(args))
+			if err != nil {
+				return err
+			}
+			layers := service.GetCIService().AffectedLayers(branch)
+			if output.Structured() {
+				return output.Print(output.KindCIAffected, output.CIAffectedDoc{Branch: branch, Layers: layers})
+			}
+			for _, l := range layers {
+				fmt.Println(l)
+			}
 			return nil
 		},
 	}
 
-	ciCmd.AddCommand(checkCmd)
+	annotateCmd := &cobra.Command{
+		Use:   "annotate [branch]",
+		Short: "Report the ci check results in the CI system's own format",
+		Long: `Run the same rules as "strata ci check" and report them where the CI system shows
+them: on GitHub, Gitea and Forgejo Actions as workflow annotations plus a job summary
+with the stack graph (when $GITHUB_STEP_SUMMARY is set), on GitLab CI as a Code Quality
+report (--codequality). Elsewhere the text report is printed.
+
+Failing rules don't fail annotate; gate the pipeline with "strata ci check".`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			locks.LockRepo()
+			defer locks.UnlockRepo()
+
+			svc := service.GetCIService()
+			branch, err := svc.PrepareCI(firstArg(args))
+			if err != nil {
+				return err
+			}
+			policy, _ := cmd.Flags().GetString("policy")
+			report, err := svc.CheckMergeFeasibility(branch, policy)
+			if len(report.Rules) == 0 {
+				return err
+			}
+
+			doc := newCICheckDoc(report, err)
+			switch service.DetectCIContext().Provider {
+			case service.CIGitHub, service.CIGitea:
+				if err := output.WriteGitHubAnnotations(os.Stdout, doc); err != nil {
+					return err
+				}
+				if path := os.Getenv("GITHUB_STEP_SUMMARY"); path != "" {
+					return appendStepSummary(path, doc)
+				}
+			case service.CIGitLab:
+				path, _ := cmd.Flags().GetString("codequality")
+				location := report.Policy
+				if location == "" {
+					location = store.PolicyFileName
+				}
+				return writeCIReport(path, doc, func(w io.Writer, r output.CICheckDoc) error {
+					return output.WriteCodeQuality(w, r, location)
+				})
+			default:
+				fmt.Println(service.RenderCIReport(report))
+			}
+			return nil
+		},
+	}
+	annotateCmd.Flags().String("policy", "", "Policy file with the org's merge rules, relative to the repository root (default .strata/policy.yaml)")
+	annotateCmd.Flags().String("codequality", "gl-code-quality-report.json", "Where to write the GitLab Code Quality report")
+
+	ciCmd.AddCommand(checkCmd, affectedCmd, annotateCmd)
 	return ciCmd
 }
+
+func firstArg(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return args[0]
+}
+
+// appendStepSummary adds the report and the stack graph to the job's summary page.
+func appendStepSummary(path string, report output.CICheckDoc) error {
+	stack := service.GetStackService().GetStack()
+	graph, err := service.GetPRService().ExportStackGraph(stack, report.Branch, service.GraphMermaid, true)
+	if err != nil {
+		graph = ""
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open the step summary: %v", err)
+	}
+	if err := output.WriteStepSummary(f, report, graph); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeCIReport renders report with write into path, or to stdout when path is "-".
+func writeCIReport(path string, report output.CICheckDoc, write func(io.Writer, output.CICheckDoc) error) error {
+	if path == "" {
+		return nil
+	}
+	if path == "-" {
+		return write(os.Stdout, report)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create report file: %v", err)
+	}
+	if err := write(f, report); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"fmt"
+	"strata/internal/locks"
+	"strata/internal/output"
+	"strata/internal/service"
+
+	"github.com/spf13/cobra"
+)
+
+func newConflictsCmd() *cobra.Command {
+	conflictsCmd := &cobra.Command{
+		Use:   "conflicts",
+		Short: "List and forget the conflict resolutions Strata replays.",
+		Long: `Strata runs its rebases and merges with git rerere on: when you resolve a conflict,
+the resolution is recorded, and the same conflict showing up again, typically in every
+layer above during 'strata update', is resolved the same way and the operation goes on.
+Strata says so whenever it replays a resolution. The recordings live in the repository's
+rr-cache, so all layers and worktrees share them.
+
+Forget a resolution that turned out wrong so the conflict stops you again next time.
+Set the rerere config value to false to stop reusing resolutions.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return listConflicts()
+		},
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the recorded conflict resolutions, newest first",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return listConflicts()
+		},
+	}
+
+	forgetCmd := &cobra.Command{
+		Use:   "forget <id|file|layer>... | --all",
+		Short: "Delete recorded resolutions so their conflicts are resolved by hand again",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			locks.LockRepo()
+			defer locks.UnlockRepo()
+
+			all, _ := cmd.Flags().GetBool("all")
+			forgotten, err := service.ForgetResolutions(args, all)
+			if err != nil {
+				return err
+			}
+			for _, r := range forgotten {
+				if r.Path != "" {
+					fmt.Printf("Forgot the resolution %s of '%s'\n", service.ShortResolutionID(r), r.Path)
+				} else {
+					fmt.Printf("Forgot the resolution %s\n", service.ShortResolutionID(r))
+				}
+			}
+			if len(forgotten) == 0 {
+				fmt.Println("No conflict resolutions recorded.")
+			}
+			return nil
+		},
+	}
+	forgetCmd.Flags().Bool("all", false, "Forget every recorded resolution")
+
+	conflictsCmd.AddCommand(listCmd, forgetCmd)
+	return conflictsCmd
+}
+
+func listConflicts() error {
+	list, err := service.ConflictResolutions()
+	if err != nil {
+		return err
+	}
+	if output.Structured() {
+		return output.Print(output.KindConflicts, newConflictsDoc(list))
+	}
+	fmt.Println(service.RenderResolutions(list))
+	return nil
+}
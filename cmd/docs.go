@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"strata/internal/errs"
+	"strata/internal/git"
+	"strata/internal/model"
+	"strata/internal/output"
+	"strata/internal/service"
+)
+
+// The conversions of service results into the documents --output prints. They live here
+// so that output only knows about formats, and any package can use it.
+
+// newStackDoc converts the stack tree into its stable document form, parents first.
+func newStackDoc(st model.StackTree, current string) output.StackDoc {
+	doc := output.StackDoc{Roots: st.Roots(), Branches: []output.BranchDoc{}}
+	if _, ok := st[current]; ok {
+		doc.Current = current
+	}
+	for _, br := range st.Topological() {
+		node := st[br]
+		bd := output.BranchDoc{
+			Name:      br,
+			Parent:    node.ParentBranch,
+			Children:  append([]string{}, node.Children...),
+			Stack:     node.Stack,
+			CreatedBy: node.CreatedBy,
+		}
+		if !node.CreatedAt.IsZero() {
+			t := node.CreatedAt
+			bd.CreatedAt = &t
+		}
+		if !node.UpdatedAt.IsZero() {
+			t := node.UpdatedAt
+			bd.UpdatedAt = &t
+		}
+		doc.Branches = append(doc.Branches, bd)
+	}
+	return doc
+}
+
+// newStatusDoc converts branch statuses into their stable document form.
+func newStatusDoc(statuses []service.BranchStatus) output.StatusDoc {
+	doc := output.StatusDoc{Branches: []output.BranchStatusDoc{}}
+	for _, st := range statuses {
+		bd := output.BranchStatusDoc{
+			Name:         st.Branch,
+			Parent:       st.Parent,
+			Current:      st.Current,
+			CreatedBy:    st.CreatedBy,
+			Commits:      []output.CommitDoc{},
+			AheadParent:  st.AheadParent,
+			BehindParent: st.BehindParent,
+			NeedsRestack: st.NeedsRestack,
+		}
+		for _, c := range st.Commits {
+			bd.Commits = append(bd.Commits, output.CommitDoc{Hash: c.Hash, Subject: c.Subject, Author: c.Author})
+		}
+		if st.HasRemote {
+			bd.Remote = &output.RemoteDoc{Ahead: st.AheadRemote, Behind: st.BehindRemote}
+		}
+		if st.PRNumber != 0 {
+			bd.PR = &output.PRDoc{Number: st.PRNumber, State: st.PRState, URL: st.PRURL, Review: st.PRReview}
+		}
+		doc.Branches = append(doc.Branches, bd)
+	}
+	return doc
+}
+
+// newPRCreateDoc converts the results of `strata pr create` and its verdict into their stable document form.
+func newPRCreateDoc(results []service.PRResult, err error) output.PRCreateDoc {
+	doc := output.PRCreateDoc{Passed: err == nil, Layers: []output.PRResultDoc{}}
+	if err != nil {
+		doc.Code = errs.CodeOf(err)
+		doc.Message = err.Error()
+	}
+	for _, r := range results {
+		doc.Layers = append(doc.Layers, output.PRResultDoc{
+			Branch: r.Branch, Base: r.Base, Action: r.Action, Number: r.Number, URL: r.URL, Reason: r.Reason,
+		})
+	}
+	return doc
+}
+
+// newPRSyncDoc converts the result of a PR base sync into its stable document form.
+func newPRSyncDoc(changes []service.BaseChange, dryRun bool) output.PRSyncDoc {
+	doc := output.PRSyncDoc{DryRun: dryRun, Changes: []output.BaseChangeDoc{}}
+	for _, c := range changes {
+		doc.Changes = append(doc.Changes, output.BaseChangeDoc{
+			Branch:  c.Branch,
+			Number:  c.Number,
+			URL:     c.URL,
+			From:    c.From,
+			To:      c.To,
+			Applied: c.Applied,
+			Error:   c.Error,
+		})
+	}
+	return doc
+}
+
+// newPRStatusDoc converts layer PR statuses into their stable document form.
+func newPRStatusDoc(statuses []service.LayerPRStatus, blocked bool) output.PRStatusDoc {
+	doc := output.PRStatusDoc{Blocked: blocked, Layers: []output.LayerStatusDoc{}}
+	for _, st := range statuses {
+		ld := output.LayerStatusDoc{
+			Branch:            st.Branch,
+			Parent:            st.Parent,
+			Bottom:            st.Bottom,
+			Review:            st.Review,
+			Mergeable:         st.Mergeable,
+			ChecksPassed:      st.ChecksPassed,
+			ChecksFailed:      st.ChecksFailed,
+			ChecksPending:     st.ChecksPending,
+			UnresolvedThreads: st.UnresolvedThreads,
+			Base:              st.Base,
+			ExpectedBase:      st.ExpectedBase,
+			BehindBase:        st.BehindBase,
+			BaseStale:         st.BaseStale(),
+			Blockers:          append([]string{}, st.Blockers...),
+		}
+		if st.PRNumber != 0 {
+			state := "OPEN"
+			if st.Draft {
+				state = "DRAFT"
+			}
+			ld.PR = &output.PRDoc{Number: st.PRNumber, State: state, URL: st.PRURL}
+		}
+		doc.Layers = append(doc.Layers, ld)
+	}
+	return doc
+}
+
+// newPRCommentsDoc converts review threads into their stable document form.
+func newPRCommentsDoc(layers []service.LayerThreads) output.PRCommentsDoc {
+	doc := output.PRCommentsDoc{Layers: []output.LayerThreadsDoc{}}
+	for _, lt := range layers {
+		ld := output.LayerThreadsDoc{
+			Branch:  lt.Branch,
+			PR:      output.PRDoc{Number: lt.PRNumber, State: "OPEN", URL: lt.PRURL},
+			Threads: []output.ThreadDoc{},
+		}
+		for _, t := range lt.Threads {
+			td := output.ThreadDoc{
+				Ref:      t.Ref,
+				ID:       t.ID,
+				Path:     t.Path,
+				Line:     t.Line,
+				Resolved: t.Resolved,
+				Outdated: t.Outdated,
+				Comments: []output.ThreadCommentDoc{},
+			}
+			for _, c := range t.Comments {
+				cd := output.ThreadCommentDoc{Author: c.Author, Body: c.Body}
+				if !c.CreatedAt.IsZero() {
+					ts := c.CreatedAt
+					cd.CreatedAt = &ts
+				}
+				td.Comments = append(td.Comments, cd)
+			}
+			ld.Threads = append(ld.Threads, td)
+		}
+		doc.Layers = append(doc.Layers, ld)
+	}
+	return doc
+}
+
+// newCICheckDoc converts a merge-feasibility report and its verdict into their stable document form.
+func newCICheckDoc(r service.CIReport, err error) output.CICheckDoc {
+	doc := output.CICheckDoc{Branch: r.Branch, Target: r.Target, Passed: err == nil, Policy: r.Policy, Rules: []output.CIRuleDoc{}}
+	if err != nil {
+		doc.Code = errs.CodeOf(err)
+		doc.Message = err.Error()
+	}
+	for _, rule := range r.Rules {
+		doc.Rules = append(doc.Rules, output.CIRuleDoc{
+			Name: rule.Name, Severity: rule.Severity, Status: rule.Status,
+			Message: rule.Message, Files: rule.Files, Details: rule.Details, Policy: rule.Policy,
+		})
+	}
+	return doc
+}
+
+// newExecDoc converts the results of `strata exec` and its verdict into their stable document form.
+func newExecDoc(r service.ExecReport, err error) output.ExecDoc {
+	doc := output.ExecDoc{Command: r.Command, Passed: err == nil, Layers: []output.ExecLayerDoc{}}
+	if err != nil {
+		doc.Code = errs.CodeOf(err)
+		doc.Message = err.Error()
+	}
+	for _, l := range r.Layers {
+		doc.Layers = append(doc.Layers, output.ExecLayerDoc{
+			Branch: l.Branch, Commit: l.Commit, Status: l.Status, ExitCode: l.ExitCode,
+			DurationMS: l.Duration.Milliseconds(), Cached: l.Cached, Log: l.Log, Reason: l.Reason,
+		})
+	}
+	return doc
+}
+
+// newUpdatePlanDoc converts an update plan into its stable document form.
+func newUpdatePlanDoc(p service.UpdatePlan) output.UpdatePlanDoc {
+	doc := output.UpdatePlanDoc{
+		Layers:      []output.PlannedLayerDoc{},
+		Rebases:     p.Count(service.PlanRebase) + p.Count(service.PlanConflict),
+		Conflicts:   p.Count(service.PlanConflict),
+		CommitCount: p.Commits(),
+	}
+	for _, l := range p.Layers {
+		doc.Layers = append(doc.Layers, output.PlannedLayerDoc{
+			Branch: l.Branch, Parent: l.Parent, Action: l.Action, Commits: l.Commits, Files: l.Files, Note: l.Note,
+		})
+	}
+	return doc
+}
+
+// newConflictsDoc converts the recorded conflict resolutions into their stable document form.
+func newConflictsDoc(list []git.Resolution) output.ConflictsDoc {
+	doc := output.ConflictsDoc{Resolutions: []output.ResolutionDoc{}}
+	for _, r := range list {
+		d := output.ResolutionDoc{ID: r.ID, Path: r.Path, Branch: r.Branch, Resolved: r.Resolved, RecordedAt: r.RecordedAt}
+		if r.Resolved {
+			lastUsed := r.LastUsed
+			d.LastUsed = &lastUsed
+		}
+		doc.Resolutions = append(doc.Resolutions, d)
+	}
+	return doc
+}
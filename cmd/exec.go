@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"strata/internal/locks"
+	"strata/internal/output"
+	"strata/internal/service"
+
+	"github.com/spf13/cobra"
+)
+
+func newExecCmd() *cobra.Command {
+	execCmd := &cobra.Command{
+		Use:     "exec [flags] -- <command> [args...]",
+		Aliases: []string{"test-stack"},
+		Short:   "Run a command on every layer of the stack, each in a throwaway worktree.",
+		Long: `Check out every layer, parents first, in a throwaway worktree and run the command
+there, so you know each layer builds and passes its tests on its own, not just the
+top of the stack. Your own checkout is never touched.
+
+A single argument is run through the shell ("make build && make test"), several are
+run as they are. The command sees the layer in STRATA_LAYER, its parent in
+STRATA_PARENT and the commit in STRATA_COMMIT. Without a command, test-stack runs
+the test_command config value.
+
+The first failure skips the layers not started yet unless --keep-going is given;
+--jobs runs several layers at once. Passes are cached per commit SHA and command, so
+layers that haven't changed aren't run again (--no-cache reruns them); failures are
+only cached with --cache-failures. Worktrees are cleaned with "git clean -fdx"
+between layers, so build output doesn't carry over. Each layer's output is kept in
+.git/strata/exec/<layer>.log.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			locks.LockRepo()
+			defer locks.UnlockRepo()
+
+			opts := service.ExecOptions{Command: args}
+			if len(opts.Command) == 0 {
+				opts.Command = service.GetExecService().TestCommand()
+			}
+			opts.Stack, _ = cmd.Flags().GetString("stack")
+			opts.From, _ = cmd.Flags().GetString("from")
+			opts.Upto, _ = cmd.Flags().GetString("upto")
+			opts.Jobs, _ = cmd.Flags().GetInt("jobs")
+			opts.KeepGoing, _ = cmd.Flags().GetBool("keep-going")
+			opts.NoCache, _ = cmd.Flags().GetBool("no-cache")
+			opts.CacheFailures, _ = cmd.Flags().GetBool("cache-failures")
+			if !output.Structured() {
+				opts.Report = func(msg string) { fmt.Println(msg) }
+			}
+
+			report, err := service.GetExecService().Exec(opts)
+			if output.Structured() {
+				if len(report.Layers) == 0 {
+					return err
+				}
+				if pErr := output.Print(output.KindExec, newExecDoc(report, err)); pErr != nil {
+					return pErr
+				}
+				return output.Reported(err)
+			}
+			if len(report.Layers) > 0 {
+				fmt.Println()
+				fmt.Println(service.RenderExecReport(report))
+				if err != nil {
+					cmd.SilenceUsage = true
+				}
+			}
+			return err
+		},
+	}
+	// everything after the command's name belongs to the command
+	execCmd.Flags().SetInterspersed(false)
+	execCmd.Flags().String("stack", "", "Only run on the layers of the named stack")
+	execCmd.Flags().String("from", "", "Start at this layer: skip the layers below it")
+	execCmd.Flags().String("upto", "", "Stop at this layer: skip the layers stacked on it")
+	execCmd.Flags().IntP("jobs", "j", 1, "Run this many layers at once")
+	execCmd.Flags().BoolP("keep-going", "k", false, "Run every layer even after one fails")
+	execCmd.Flags().Bool("no-cache", false, "Rerun layers that already have a result for their commit")
+	execCmd.Flags().Bool("cache-failures", false, "Cache failed results too, not only passes")
+	return execCmd
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"strata/internal/locks"
+	"strata/internal/logs"
+	"strata/internal/service"
+)
+
+func newLandCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "land [branch]",
+		Short: "Merge PRs through the forge from the bottom of the stack up to a branch",
+		Long: `Merge the bottom PR of the stack through the forge and wait for it to land, then
+rebase the layers stacked on it onto the trunk, force-push them and retarget their
+PRs. This repeats up to and including the given branch (default: the current one).
+
+The merge method comes from --method or the "land_method" config key (merge, squash
+or rebase). Pending checks are waited for; a failing check, a missing or rejected
+review, conflicts or unresolved threads stop the run with exit code 7 (blocked).
+
+With --queue each PR goes through the merge queue (GitHub) or merge train (GitLab)
+instead, and the next layer is enqueued once the previous one merges. Progress is
+saved in .git/strata/land_run.yaml: if the queue ejects a PR the run stops, and running
+"strata land --queue" again after the fix resumes it. --abort drops a saved run.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			locks.LockRepo()
+			defer locks.UnlockRepo()
+
+			target := ""
+			if len(args) == 1 {
+				target = args[0]
+			}
+			queue, _ := cmd.Flags().GetBool("queue")
+			abort, _ := cmd.Flags().GetBool("abort")
+			opts := service.LandOptions{Report: func(msg string) { fmt.Println(msg) }}
+			opts.Method, _ = cmd.Flags().GetString("method")
+			opts.Timeout, _ = cmd.Flags().GetDuration("timeout")
+			opts.Interval, _ = cmd.Flags().GetDuration("interval")
+
+			if abort {
+				run, err := service.GetLandService().AbortLandQueue()
+				if err != nil {
+					return err
+				}
+				if run == nil {
+					fmt.Println("No merge queue run in progress.")
+				} else {
+					fmt.Printf("Dropped the merge queue run for '%s' (%d landed, %d left).\n", run.Target, len(run.Landed), len(run.Remaining))
+				}
+				return nil
+			}
+
+			if queue {
+				run, err := service.GetLandService().LandQueue(target, opts)
+				if run != nil && len(run.Landed) > 0 {
+					fmt.Printf("Landed: %s\n", strings.Join(run.Landed, ", "))
+				}
+				if err != nil {
+					logs.Error("Land stopped: %v", err)
+					if run != nil && len(run.Remaining) > 0 {
+						fmt.Printf("Still to land: %s\n", strings.Join(run.Remaining, ", "))
+					}
+					return err
+				}
+				return nil
+			}
+
+			landed, err := service.GetLandService().Land(target, opts)
+			if len(landed) > 0 {
+				fmt.Printf("Landed: %s\n", strings.Join(landed, ", "))
+			}
+			if err != nil {
+				logs.Error("Land stopped: %v", err)
+				return err
+			}
+			return nil
+		},
+	}
+	cmd.Flags().String("method", "", "Merge method: merge, squash or rebase (default from land_method)")
+	cmd.Flags().Duration("timeout", 30*time.Minute, "How long to wait for pending checks and for each merge")
+	cmd.Flags().Duration("interval", 15*time.Second, "How often to poll the forge while waiting")
+	cmd.Flags().Bool("queue", false, "Land through the merge queue, resuming a saved run if there is one")
+	cmd.Flags().Bool("abort", false, "Drop the saved merge queue run")
+	return cmd
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"strata/internal/locks"
+	"strata/internal/logs"
+	"strata/internal/output"
+	"strata/internal/service"
+	"strata/internal/ui"
+)
+
+func newLogCmd() *cobra.Command {
+	logCmd := &cobra.Command{
+		Use:   "log",
+		Short: "Show the stack with commits, ahead/behind counts and PR status for each layer.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			locks.LockRepo()
+			defer locks.UnlockRepo()
+
+			noPR, _ := cmd.Flags().GetBool("no-pr")
+			noColor, _ := cmd.Flags().GetBool("no-color")
+			if noColor {
+				ui.SetColorEnabled(false)
+			}
+
+			if output.Structured() {
+				statuses, err := service.GetStackService().BranchStatuses(!noPR)
+				if err != nil {
+					logs.Error("Failed to collect stack status: %v", err)
+					return err
+				}
+				return output.Print(output.KindStackStatus, newStatusDoc(statuses))
+			}
+
+			out, err := service.GetStackService().RenderStackLog(!noPR)
+			if err != nil {
+				logs.Error("Failed to render stack log: %v", err)
+				return err
+			}
+
+			fmt.Println(out)
+			return nil
+		},
+	}
+	logCmd.Flags().Bool("no-pr", false, "Skip pull request state")
+	logCmd.Flags().Bool("no-color", false, "Disable colored output")
+	return logCmd
+}
@@ -11,7 +11,7 @@ import (
 func newMergeCmd() *cobra.Command {
 	return &cobra.Command{
 		Use:   "merge <branch-name>",
-		Short: "Merge a stack layer into its parent (or main if no parent).",
+		Short: "Merge a stack layer into its parent (or its trunk if no parent).",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			locks.LockRepo()
@@ -3,6 +3,8 @@ package cmd
 import (
 	"fmt"
 	"os/exec"
+	"strata/internal/errs"
+	"strata/internal/git"
 	"strata/internal/service"
 	"strata/internal/utils"
 
@@ -25,7 +27,7 @@ func newNextCmd() *cobra.Command {
 
 			node, ok := stack[curr]
 			if !ok {
-				return fmt.Errorf("current branch '%s' not found in stack", curr)
+				return errs.NotInStack("current branch '%s' not found in stack", curr)
 			}
 
 			if len(node.Children) == 0 {
@@ -35,16 +37,10 @@ func newNextCmd() *cobra.Command {
 			// For now, just take the first child
 			nextBranch := node.Children[0]
 
-			// Execute git checkout
-			gitCmd := exec.Command("git", "checkout", nextBranch)
-			if out, err := gitCmd.CombinedOutput(); err != nil {
-				return fmt.Errorf("failed to checkout branch '%s': %v\n%s", nextBranch, err, string(out))
-			}
-
-			fmt.Printf("Switched to branch '%s'\n", nextBranch)
-			return nil
+			return switchToLayer(cmd, nextBranch)
 		},
 	}
+	cmd.Flags().Bool("path", false, "Only print the directory the branch is checked out in, e.g. for cd \"$(strata next --path)\"")
 	return cmd
 }
 
@@ -64,22 +60,44 @@ func newPrevCmd() *cobra.Command {
 
 			node, ok := stack[curr]
 			if !ok {
-				return fmt.Errorf("current branch '%s' not found in stack", curr)
+				return errs.NotInStack("current branch '%s' not found in stack", curr)
 			}
 
 			if node.ParentBranch == "" {
 				return fmt.Errorf("no previous branch found - '%s' is at the root", curr)
 			}
 
-			// Execute git checkout
-			gitCmd := exec.Command("git", "checkout", node.ParentBranch)
-			if out, err := gitCmd.CombinedOutput(); err != nil {
-				return fmt.Errorf("failed to checkout branch '%s': %v\n%s", node.ParentBranch, err, string(out))
-			}
-
-			fmt.Printf("Switched to branch '%s'\n", node.ParentBranch)
-			return nil
+			return switchToLayer(cmd, node.ParentBranch)
 		},
 	}
+	cmd.Flags().Bool("path", false, "Only print the directory the branch is checked out in, e.g. for cd \"$(strata prev --path)\"")
 	return cmd
 }
+
+// switchToLayer checks out branch, unless it has a worktree of its own: git won't check it
+// out twice, so its path is printed to cd to instead. With --path only the directory the
+// branch ends up checked out in is printed.
+func switchToLayer(cmd *cobra.Command, branch string) error {
+	pathOnly, _ := cmd.Flags().GetBool("path")
+	dir := service.GetStackService().LayerPath(branch)
+	top, _ := git.TopLevel()
+	if dir == "" || dir == top {
+		// Execute git checkout
+		gitCmd := exec.Command("git", "checkout", branch)
+		if out, err := gitCmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to checkout branch '%s': %v\n%s", branch, err, string(out))
+		}
+		if pathOnly {
+			fmt.Println(top)
+			return nil
+		}
+		fmt.Printf("Switched to branch '%s'\n", branch)
+		return nil
+	}
+	if pathOnly {
+		fmt.Println(dir)
+		return nil
+	}
+	fmt.Printf("Branch '%s' is checked out in its own worktree:\n  cd %s\n", branch, dir)
+	return nil
+}
@@ -2,43 +2,255 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"strata/internal/errs"
 	"strata/internal/locks"
 	"strata/internal/logs"
+	"strata/internal/output"
 	"strata/internal/service"
+	"strata/internal/ui"
 )
 
 func newPrCmd() *cobra.Command {
 	prCmd := &cobra.Command{
 		Use:   "pr",
-		Short: "Manage GitHub pull requests for your stack",
-		Long: `Create or update pull requests on GitHub / GitHub Enterprise for 
-individual stack layers or for all unmerged layers in the stack.`,
+		Short: "Manage pull requests (or merge requests) for your stack",
+		Long: `Create or update pull requests on GitHub / GitHub Enterprise, merge requests on
+GitLab, or pull requests on Gitea / Forgejo for individual stack layers or for all
+unmerged layers in the stack. The forge is detected from origin's host; set the
+"forge" config key (github, gitlab, gitea) to override it.`,
 	}
 
 	createCmd := &cobra.Command{
-		Use:   "create [--all]",
+		Use:   "create [--all | --stack name] [--from branch] [--upto branch]",
 		Short: "Create PR(s) for the current or all stacked branches.",
+		Long: `Create or refresh PRs, parents first, so every PR can target its parent's branch.
+Without --all, --stack, --from or --upto only the current branch gets a PR.
+
+--from keeps the given layer and everything stacked on it, --upto the given layer
+and everything below it. Layers with no commits beyond their parent are skipped,
+and so are the layers above them. A table of what was created, updated or skipped
+is printed at the end, or a "pr_create" document with --output json|yaml.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			locks.LockRepo()
 			defer locks.UnlockRepo()
 
-			all, _ := cmd.Flags().GetBool("all")
-			logs.Info("Creating PR(s) on GitHub (all=%v)", all)
+			opts := service.PROptions{}
+			opts.All, _ = cmd.Flags().GetBool("all")
+			opts.Stack, _ = cmd.Flags().GetString("stack")
+			opts.From, _ = cmd.Flags().GetString("from")
+			opts.Upto, _ = cmd.Flags().GetString("upto")
+			opts.Draft, _ = cmd.Flags().GetBool("draft")
+			opts.Reviewers, _ = cmd.Flags().GetStringSlice("reviewer")
+			opts.Labels, _ = cmd.Flags().GetStringSlice("label")
+			opts.Assignees, _ = cmd.Flags().GetStringSlice("assignee")
+			opts.Milestone, _ = cmd.Flags().GetString("milestone")
+			opts.Edit, _ = cmd.Flags().GetBool("edit")
+			logs.Info("Creating PR(s) (all=%v, stack=%s)", opts.All, opts.Stack)
 
-			err := service.GetPRService().CreatePR(all)
+			results, err := service.GetPRService().CreatePR(opts)
+			if output.Structured() {
+				if pErr := output.Print(output.KindPRCreate, newPRCreateDoc(results, err)); pErr != nil {
+					return pErr
+				}
+				return output.Reported(err)
+			}
+			if len(results) > 0 {
+				fmt.Println(service.RenderPRResults(results))
+			}
 			if err != nil {
 				logs.Error("Failed to create PR(s): %v", err)
 				return err
 			}
-
-			fmt.Println("PR(s) created successfully on GitHub.")
 			return nil
 		},
 	}
 	createCmd.Flags().Bool("all", false, "Create PRs for all unmerged branches")
+	createCmd.Flags().String("stack", "", "Create PRs for every layer of the named stack")
+	createCmd.Flags().String("from", "", "Start at this layer: skip the layers below it")
+	createCmd.Flags().String("upto", "", "Stop at this layer: skip the layers stacked on it")
+	createCmd.Flags().Bool("draft", false, "Open new PRs as drafts (default from pr_draft)")
+	createCmd.Flags().StringSlice("reviewer", nil, "Request a review from this user or team (repeatable, default from pr_reviewers)")
+	createCmd.Flags().StringSlice("label", nil, "Add this label (repeatable, default from pr_labels)")
+	createCmd.Flags().StringSlice("assignee", nil, "Assign this user (repeatable, default from pr_assignees)")
+	createCmd.Flags().String("milestone", "", "Add the PR to this milestone (default from pr_milestone)")
+	createCmd.Flags().Bool("edit", false, "Edit the generated title and body in $EDITOR before creating (default from pr_edit)")
+
+	syncCmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Retarget open PRs whose base no longer matches the layer's parent",
+		Long: `Compare each open PR's base branch with its layer's parent (or the trunk, once the
+parent has landed and its branch is gone) and retarget the PRs that differ.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			locks.LockRepo()
+			defer locks.UnlockRepo()
+
+			stack, _ := cmd.Flags().GetString("stack")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			changes, err := service.GetPRService().SyncPRBases(stack, dryRun)
+
+			if output.Structured() {
+				if pErr := output.Print(output.KindPRSync, newPRSyncDoc(changes, dryRun)); pErr != nil {
+					return pErr
+				}
+				return output.Reported(err)
+			}
+			if len(changes) == 0 && err == nil {
+				fmt.Println("All PR bases are up to date.")
+				return nil
+			}
+			for _, c := range changes {
+				switch {
+				case c.Error != "":
+					fmt.Printf("✗ #%d %s: %s -> %s failed: %s\n", c.Number, c.Branch, c.From, c.To, c.Error)
+				case c.Applied:
+					fmt.Printf("✓ #%d %s: retargeted %s -> %s\n", c.Number, c.Branch, c.From, c.To)
+				default:
+					fmt.Printf("• #%d %s: would retarget %s -> %s\n", c.Number, c.Branch, c.From, c.To)
+				}
+			}
+			return err
+		},
+	}
+	syncCmd.Flags().String("stack", "", "Only sync PRs of the named stack")
+	syncCmd.Flags().Bool("dry-run", false, "Report mismatched bases without changing any PR")
+
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show review, checks and mergeability of every layer's PR",
+		Long: `Show, for every layer, the PR number, review decision, required-check rollup,
+mergeable state, unresolved review threads and whether the base is stale.
+
+Exits with code 7 (blocked) when anything blocks the bottom of the stack, i.e. the
+layers that target a trunk directly. With --watch the overview refreshes until the
+bottom of the stack can be merged.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// a blocked stack is a verdict, not a usage mistake
+			cmd.SilenceUsage = true
+			stack, _ := cmd.Flags().GetString("stack")
+			watch, _ := cmd.Flags().GetBool("watch")
+			interval, _ := cmd.Flags().GetDuration("interval")
+
+			for {
+				statuses, err := service.GetPRService().StackPRStatus(stack)
+				blocked := errs.CodeOf(err) == errs.CodeBlocked
+				if err != nil && !blocked {
+					return err
+				}
+
+				if output.Structured() {
+					if pErr := output.Print(output.KindPRStatus, newPRStatusDoc(statuses, blocked)); pErr != nil {
+						return pErr
+					}
+				} else {
+					if watch && ui.ColorEnabled() {
+						fmt.Print("\033[H\033[2J")
+					}
+					fmt.Println(service.RenderPRStatus(statuses))
+					if watch && blocked {
+						fmt.Printf("\nRefreshing every %v (Ctrl-C to stop)...\n", interval)
+					}
+				}
+
+				if !watch || !blocked {
+					if output.Structured() {
+						return output.Reported(err)
+					}
+					return err
+				}
+				time.Sleep(interval)
+			}
+		},
+	}
+	statusCmd.Flags().String("stack", "", "Only show layers of the named stack")
+	statusCmd.Flags().Bool("watch", false, "Refresh until the bottom of the stack is unblocked")
+	statusCmd.Flags().Duration("interval", 30*time.Second, "Refresh interval for --watch")
 
 	prCmd.AddCommand(createCmd)
+	prCmd.AddCommand(newPRCommentsCmd())
+	prCmd.AddCommand(syncCmd)
+	prCmd.AddCommand(statusCmd)
 	return prCmd
 }
+
+func newPRCommentsCmd() *cobra.Command {
+	commentsCmd := &cobra.Command{
+		Use:   "comments [--all]",
+		Short: "Show review threads per layer, grouped by file and line",
+		Long: `Show the review threads of the current layer's PR, or of every layer with --all,
+grouped by layer and file:line, resolved or not.
+
+Each thread has a ref such as "feature-x:2" (or just "2" on the current layer) that
+the jump, reply and resolve subcommands take. With --output json the threads come
+as a "pr_comments" document, e.g. for editor plugins.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			all, _ := cmd.Flags().GetBool("all")
+			stack, _ := cmd.Flags().GetString("stack")
+			unresolved, _ := cmd.Flags().GetBool("unresolved")
+
+			layers, err := service.GetPRService().ReviewThreads(all, stack, unresolved)
+			if err != nil {
+				return err
+			}
+			if output.Structured() {
+				return output.Print(output.KindPRComments, newPRCommentsDoc(layers))
+			}
+			fmt.Println(service.RenderThreads(layers))
+			return nil
+		},
+	}
+	commentsCmd.Flags().Bool("all", false, "Show threads of every layer's PR")
+	commentsCmd.Flags().String("stack", "", "Show threads of the named stack's PRs")
+	commentsCmd.Flags().Bool("unresolved", false, "Only show unresolved threads")
+
+	jumpCmd := &cobra.Command{
+		Use:   "jump <ref>",
+		Short: "Open $EDITOR at the file and line of a review thread",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			_, err := service.GetPRService().JumpToThread(args[0])
+			return err
+		},
+	}
+
+	replyCmd := &cobra.Command{
+		Use:   "reply <ref> <message>",
+		Short: "Reply to a review thread",
+		Args:  cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			thread, err := service.GetPRService().ReplyToThread(args[0], strings.Join(args[1:], " "))
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Replied to %s (%s:%d).\n", thread.Ref, thread.Path, thread.Line)
+			return nil
+		},
+	}
+
+	resolveCmd := &cobra.Command{
+		Use:   "resolve <ref>...",
+		Short: "Mark review threads as resolved",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			for _, ref := range args {
+				thread, err := service.GetPRService().ResolveThread(ref)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("Resolved %s (%s:%d).\n", thread.Ref, thread.Path, thread.Line)
+			}
+			return nil
+		},
+	}
+
+	commentsCmd.AddCommand(jumpCmd, replyCmd, resolveCmd)
+	return commentsCmd
+}
@@ -1,27 +1,46 @@
 package cmd
 
 import (
+	"strata/internal/config"
+	"strata/internal/git"
 	"strata/internal/logs"
+	"strata/internal/output"
 	"strata/internal/ui"
 
 	"github.com/spf13/cobra"
 )
 
 var (
-	verbose bool
+	verbose      bool
+	outputFormat string
 )
 
 // rootCmd is the base command when called without subcommands.
 var rootCmd = &cobra.Command{
 	Use:   "strata",
 	Short: "Strata is a robust, production-ready Git stacking tool.",
-	Long: `Strata streamlines stacked PR workflows for GitHub and GitHub Enterprise,
+	Long: `Strata streamlines stacked PR workflows for GitHub, GitLab and Gitea,
 including merges, rebases, collaboration, and offline support—fully tested and production-ready.`,
+	// Errors are printed by ReportError so they can be rendered in the selected output format.
+	SilenceErrors: true,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 		logs.SetVerbose(verbose)
 		if err := logs.InitLogger(); err != nil {
 			return err
 		}
+		format, err := output.ParseFormat(outputFormat)
+		if err != nil {
+			return err
+		}
+		output.SetFormat(format)
+		// every worktree of the repo shares the stack kept in the main one
+		if root, err := git.MainWorktree(); err == nil {
+			config.SetRepoRoot(root)
+		}
+		if output.Structured() {
+			// Keep stdout a single parseable document.
+			cmd.SilenceUsage = true
+		}
 		return nil
 	},
 	PersistentPostRun: func(cmd *cobra.Command, args []string) {
@@ -34,26 +53,40 @@ func Execute() error {
 	return rootCmd.Execute()
 }
 
+// ReportError prints err in the format selected with --output.
+func ReportError(err error) {
+	output.PrintError(err)
+}
+
 func init() {
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose logging")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "text", "Output format: text, json or yaml")
 
 	rootCmd.AddCommand(
 		newInitCmd(),
 		newDaemonCmd(),
 		newAddCmd(),
+		newStackCmd(),
+		newTrunkCmd(),
 		newRenameCmd(),
 		newMergeCmd(),
+		newLandCmd(),
 		newUpdateCmd(),
 		newPrCmd(),
 		newShareCmd(),
 		newUseCmd(),
 		newViewCmd(),
+		newLogCmd(),
+		newTuiCmd(),
 		newConfigCmd(),
 		newHookCmd(),
 		newPushCmd(),
 		newRebaseCmd(),
 		newServerCmd(),
 		newCICmd(),
+		newExecCmd(),
+		newWorktreeCmd(),
+		newConflictsCmd(),
 		newNextCmd(),
 		newPrevCmd(),
 	)
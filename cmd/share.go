@@ -10,14 +10,15 @@ import (
 )
 
 func newShareCmd() *cobra.Command {
-	return &cobra.Command{
+	shareCmd := &cobra.Command{
 		Use:   "share",
 		Short: "Generate a share code so another user can clone your stack locally.",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			locks.LockRepo()
 			defer locks.UnlockRepo()
 
-			code, err := service.GetCollabService().GenerateShareCode()
+			stackName, _ := cmd.Flags().GetString("stack")
+			code, err := service.GetCollabService().GenerateShareCode(stackName)
 			if err != nil {
 				logs.Error("Failed to generate share code: %v", err)
 				return err
@@ -28,4 +29,6 @@ func newShareCmd() *cobra.Command {
 			return nil
 		},
 	}
+	shareCmd.Flags().String("stack", "", "Share only the layers of the named stack")
+	return shareCmd
 }
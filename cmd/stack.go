@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"strata/internal/locks"
+	"strata/internal/logs"
+	"strata/internal/service"
+)
+
+func newStackCmd() *cobra.Command {
+	stackCmd := &cobra.Command{
+		Use:   "stack",
+		Short: "Manage named stacks (groups of layers with their own metadata).",
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List named stacks.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			locks.LockRepo()
+			defer locks.UnlockRepo()
+
+			all, _ := cmd.Flags().GetBool("all")
+			svc := service.GetStackService()
+			names := svc.StackNames(all)
+			if len(names) == 0 {
+				fmt.Println("No named stacks. Create one with `strata stack new <name>`.")
+				return nil
+			}
+			for _, name := range names {
+				st := svc.Stacks()[name]
+				layers := len(svc.GetStack().Filter(name))
+				line := fmt.Sprintf("%-24s %d layer(s)", name, layers)
+				if st.Trunk != "" {
+					line += fmt.Sprintf("  on %s", st.Trunk)
+				}
+				if st.Owner != "" {
+					line += fmt.Sprintf("  by %s", st.Owner)
+				}
+				if len(st.Labels) > 0 {
+					line += fmt.Sprintf("  [%s]", strings.Join(st.Labels, ", "))
+				}
+				if st.Archived {
+					line += "  (archived)"
+				}
+				fmt.Println(line)
+			}
+			return nil
+		},
+	}
+	listCmd.Flags().Bool("all", false, "Include archived stacks")
+
+	newCmd := &cobra.Command{
+		Use:   "new <name>",
+		Short: "Create a named stack, optionally adopting an existing branch and its descendants.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			locks.LockRepo()
+			defer locks.UnlockRepo()
+
+			name := args[0]
+			description, _ := cmd.Flags().GetString("description")
+			trunk, _ := cmd.Flags().GetString("trunk")
+			labels, _ := cmd.Flags().GetStringSlice("label")
+			from, _ := cmd.Flags().GetString("from")
+
+			logs.Info("Creating named stack '%s'", name)
+			if err := service.GetStackService().CreateStack(name, description, trunk, labels, from); err != nil {
+				logs.Error("Failed to create stack '%s': %v", name, err)
+				return err
+			}
+			fmt.Printf("Stack '%s' created.\n", name)
+			return nil
+		},
+	}
+	newCmd.Flags().StringP("description", "d", "", "Short description of the stack")
+	newCmd.Flags().String("trunk", "", "Branch this stack lands on (defaults to the repository trunk)")
+	newCmd.Flags().StringSlice("label", nil, "Label to attach to the stack (repeatable)")
+	newCmd.Flags().String("from", "", "Move this branch and every layer on top of it into the new stack")
+
+	describeCmd := &cobra.Command{
+		Use:   "describe <name>",
+		Short: "Show a named stack's metadata and layers.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			locks.LockRepo()
+			defer locks.UnlockRepo()
+
+			out, err := service.GetStackService().DescribeStack(args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Println(out)
+			return nil
+		},
+	}
+
+	archiveCmd := &cobra.Command{
+		Use:   "archive <name>",
+		Short: "Archive a named stack. Its branches are left untouched.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			locks.LockRepo()
+			defer locks.UnlockRepo()
+
+			name := args[0]
+			if err := service.GetStackService().ArchiveStack(name); err != nil {
+				logs.Error("Failed to archive stack '%s': %v", name, err)
+				return err
+			}
+			fmt.Printf("Stack '%s' archived.\n", name)
+			return nil
+		},
+	}
+
+	stackCmd.AddCommand(listCmd, newCmd, describeCmd, archiveCmd)
+	return stackCmd
+}
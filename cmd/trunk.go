@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"strata/internal/locks"
+	"strata/internal/service"
+	"strata/internal/utils"
+)
+
+func newTrunkCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "trunk [branch]",
+		Short: "Show the trunk branches and which trunk a layer lands on.",
+		Long: `Trunks are resolved from the trunk_branch config value, then origin/HEAD, then main/master.
+Extra trunks (e.g. release branches) can be listed in trunk_branches, comma separated,
+and named stacks can set their own trunk with 'strata stack new --trunk'.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			locks.LockRepo()
+			defer locks.UnlockRepo()
+
+			svc := service.GetStackService()
+			branch := utils.CurrentBranch()
+			if len(args) == 1 {
+				branch = args[0]
+			}
+
+			fmt.Printf("Default trunk: %s\n", service.DefaultTrunk())
+			fmt.Println("Trunks:")
+			for _, t := range svc.Trunks() {
+				fmt.Println(" -", t)
+			}
+			if branch != "" && !svc.IsTrunk(branch) {
+				fmt.Printf("'%s' lands on: %s\n", branch, svc.TrunkFor(branch))
+			}
+			return nil
+		},
+	}
+}
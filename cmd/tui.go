@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"strata/internal/locks"
+	"strata/internal/logs"
+	"strata/internal/tui"
+)
+
+func newTuiCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "tui",
+		Short: "Open an interactive, full-screen dashboard for the stack.",
+		Long: `Shows the stack tree next to the selected layer's commits, diffstat and PR/CI status.
+Keys: ↑/↓ or j/k move, Enter/c checkout, r restack, p push, o open PR,
+n rename, f fold into parent, d delete, g refresh PR state, q quit.
+The view refreshes automatically when the stack file or branches change.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			locks.LockRepo()
+			defer locks.UnlockRepo()
+
+			if err := tui.Run(); err != nil {
+				logs.Error("Dashboard failed: %v", err)
+				return err
+			}
+			return nil
+		},
+	}
+}
@@ -5,21 +5,48 @@ import (
 	"github.com/spf13/cobra"
 	"strata/internal/locks"
 	"strata/internal/logs"
+	"strata/internal/output"
 	"strata/internal/service"
 )
 
 func newUpdateCmd() *cobra.Command {
-	return &cobra.Command{
+	updateCmd := &cobra.Command{
 		Use:   "update",
 		Short: "Update the entire stack by rebasing or merging each branch on its parent.",
 		Long: `Attempts to bring all branches up-to-date with their parents. 
-Ensures minimal conflicts and offers interactive resolution if needed.`,
+Ensures minimal conflicts and offers interactive resolution if needed.
+
+Only the current branch is rebased in your working copy, and only when it has to
+move. Other layers are rebased where they are checked out, or in a throwaway
+worktree, so your working files stay untouched.
+
+Conflict resolutions are recorded with git rerere: once you resolve a conflict, the
+same conflict in the layers above is resolved the same way and the update goes on,
+saying which files it reused resolutions for. See 'strata conflicts'.
+
+--plan predicts the update without changing anything: for every layer, parents
+first, whether it is up to date, replays cleanly or conflicts and in which files
+(tested with git merge-tree, git 2.38+), plus an estimate of the work. Trunks are
+compared with origin as last fetched.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			locks.LockRepo()
 			defer locks.UnlockRepo()
 
+			stackName, _ := cmd.Flags().GetString("stack")
+			if plan, _ := cmd.Flags().GetBool("plan"); plan {
+				p, err := service.GetStackService().PlanUpdate(stackName)
+				if err != nil {
+					return err
+				}
+				if output.Structured() {
+					return output.Print(output.KindUpdatePlan, newUpdatePlanDoc(p))
+				}
+				fmt.Println(service.RenderUpdatePlan(p))
+				return nil
+			}
+
 			logs.Info("Updating entire stack via rebase/merge strategy...")
-			err := service.GetStackService().UpdateEntireStack()
+			err := service.GetStackService().UpdateEntireStack(stackName)
 			if err != nil {
 				logs.Error("Update failed: %v", err)
 				return err
@@ -30,4 +57,7 @@ Ensures minimal conflicts and offers interactive resolution if needed.`,
 			return nil
 		},
 	}
+	updateCmd.Flags().String("stack", "", "Only update the layers of the named stack")
+	updateCmd.Flags().Bool("plan", false, "Only show what the update would do and where it would conflict")
+	return updateCmd
 }
@@ -6,18 +6,49 @@ import (
 	"github.com/spf13/cobra"
 	"strata/internal/locks"
 	"strata/internal/logs"
+	"strata/internal/output"
 	"strata/internal/service"
+	"strata/internal/ui"
+	"strata/internal/utils"
 )
 
 func newViewCmd() *cobra.Command {
-	return &cobra.Command{
+	viewCmd := &cobra.Command{
 		Use:   "view",
 		Short: "View the current stack in a tree-like format.",
+		Long: `View the current stack, one line per layer with its PR state, commit count,
+ahead/behind counts against its parent and origin, and a marker when it needs a
+restack; 'strata log' adds the commits. Use --format to export the stack graph as
+Mermaid, Graphviz DOT or SVG (SVG requires Graphviz to be installed).`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			locks.LockRepo()
 			defer locks.UnlockRepo()
 
-			tree, err := service.GetStackService().ViewStackTree()
+			format, _ := cmd.Flags().GetString("format")
+			noPR, _ := cmd.Flags().GetBool("no-pr")
+			if noColor, _ := cmd.Flags().GetBool("no-color"); noColor {
+				ui.SetColorEnabled(false)
+			}
+			stack := service.GetStackService().GetStack()
+
+			if format != service.GraphTree {
+				if output.Structured() {
+					return fmt.Errorf("--format %s cannot be combined with --output %s", format, output.CurrentFormat())
+				}
+				graph, err := service.GetPRService().ExportStackGraph(stack, utils.CurrentBranch(), format, !noPR)
+				if err != nil {
+					logs.Error("Failed to export stack graph: %v", err)
+					return err
+				}
+				fmt.Print(graph)
+				return nil
+			}
+
+			if output.Structured() {
+				return output.Print(output.KindStack, newStackDoc(stack, utils.CurrentBranch()))
+			}
+
+			tree, err := service.GetStackService().RenderStackTree(!noPR)
 			if err != nil {
 				logs.Error("Failed to view stack tree: %v", err)
 				return err
@@ -27,4 +58,8 @@ func newViewCmd() *cobra.Command {
 			return nil
 		},
 	}
+	viewCmd.Flags().String("format", service.GraphTree, "Graph format: tree, mermaid, dot or svg")
+	viewCmd.Flags().Bool("no-pr", false, "Skip pull request state")
+	viewCmd.Flags().Bool("no-color", false, "Disable colored output")
+	return viewCmd
 }
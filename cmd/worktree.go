@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"strata/internal/locks"
+	"strata/internal/service"
+	"strata/internal/ui"
+
+	"github.com/spf13/cobra"
+)
+
+func newWorktreeCmd() *cobra.Command {
+	wtCmd := &cobra.Command{
+		Use:   "worktree",
+		Short: "Give stack layers their own git worktrees.",
+		Long: `Check layers out in worktrees of their own, so switching layers doesn't wipe build
+caches or editor state. Worktrees are created under the worktree_dir config value
+(default ../<repo>.worktrees), one directory per branch.
+
+Strata works from any worktree of the repo: the stack is shared through the main
+worktree. Restacks rebase a layer inside its worktree, and next/prev print the
+worktree to cd to (--path prints only the directory).`,
+	}
+
+	addCmd := &cobra.Command{
+		Use:   "add [branch...] [--all | --stack name]",
+		Short: "Check layers out in their own worktrees",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			locks.LockRepo()
+			defer locks.UnlockRepo()
+
+			all, _ := cmd.Flags().GetBool("all")
+			stackName, _ := cmd.Flags().GetString("stack")
+			results, err := service.GetStackService().AddWorktrees(args, all, stackName)
+			for _, r := range results {
+				if r.Created {
+					fmt.Printf("Checked out '%s' in %s\n", r.Branch, r.Path)
+				} else {
+					fmt.Printf("'%s' is already checked out in %s\n", r.Branch, r.Path)
+				}
+			}
+			return err
+		},
+	}
+	addCmd.Flags().Bool("all", false, "Give every layer a worktree")
+	addCmd.Flags().String("stack", "", "Give every layer of the named stack a worktree")
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the stack's branches and the worktrees they are checked out in",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			layers, err := service.GetStackService().LayerWorktrees()
+			if err != nil {
+				return err
+			}
+			for _, l := range layers {
+				switch {
+				case l.Current:
+					fmt.Printf("* %s  %s\n", ui.Colorize(l.Branch, ui.FgGreen), l.Path)
+				case l.Path != "":
+					fmt.Printf("  %s  %s\n", l.Branch, l.Path)
+				default:
+					fmt.Printf("  %s\n", ui.Colorize(l.Branch+"  (no worktree)", ui.Dim))
+				}
+			}
+			return nil
+		},
+	}
+
+	pruneCmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove the worktrees of layers that left the stack",
+		Long: `Remove the worktrees Strata created for layers that are no longer in the stack,
+e.g. after they landed, and drop git's records of worktrees whose directory is gone.
+Worktrees with uncommitted changes are kept unless --force is given.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			locks.LockRepo()
+			defer locks.UnlockRepo()
+
+			force, _ := cmd.Flags().GetBool("force")
+			results, err := service.GetStackService().PruneWorktrees(force)
+			for _, r := range results {
+				name := r.Branch
+				if name == "" {
+					name = "detached HEAD"
+				}
+				if r.Reason != "" {
+					fmt.Printf("Kept %s (%s): %s\n", r.Path, name, r.Reason)
+				} else {
+					fmt.Printf("Removed %s (%s)\n", r.Path, name)
+				}
+			}
+			if err == nil && len(results) == 0 {
+				fmt.Println("No worktrees to prune.")
+			}
+			return err
+		},
+	}
+	pruneCmd.Flags().Bool("force", false, "Also remove worktrees with uncommitted changes")
+
+	wtCmd.AddCommand(addCmd, listCmd, pruneCmd)
+	return wtCmd
+}
@@ -30,6 +30,20 @@ func getXDGConfigPath() (string, error) {
 	return p, nil
 }
 
+// repoRoot is where the repo's Strata files live. It is the main worktree, so linked
+// worktrees of the repo share its stack and config.
+var repoRoot = "."
+
+// SetRepoRoot points the repo's Strata files at dir; call it before any config is read.
+func SetRepoRoot(dir string) {
+	repoRoot = dir
+}
+
+// RepoFile returns the path of one of the repo's Strata files.
+func RepoFile(name string) string {
+	return filepath.Join(repoRoot, name)
+}
+
 var (
 	globalConfig = make(map[string]string)
 	localConfig  = make(map[string]string)
@@ -78,7 +92,7 @@ func InitializeRepoConfig() error {
 	if localLoaded {
 		return nil
 	}
-	localPath := filepath.Join(".", LocalConfigFile)
+	localPath := RepoFile(LocalConfigFile)
 	if _, err := os.Stat(localPath); os.IsNotExist(err) {
 		def := map[string]string{
 			"repo_name": guessRepoName(),
@@ -98,7 +112,31 @@ func InitializeRepoConfig() error {
 	return nil
 }
 
+// ensureLoaded reads whichever config files already exist. Unlike the Initialize* functions
+// it never creates files, so commands run outside an initialized repo stay side-effect free.
+func ensureLoaded() {
+	if !globalLoaded {
+		if configPath, err := getXDGConfigPath(); err == nil {
+			if data, err := loadYAML(configPath); err == nil {
+				for k, v := range data {
+					globalConfig[k] = v
+				}
+				globalLoaded = true
+			}
+		}
+	}
+	if !localLoaded {
+		if data, err := loadYAML(RepoFile(LocalConfigFile)); err == nil {
+			for k, v := range data {
+				localConfig[k] = v
+			}
+			localLoaded = true
+		}
+	}
+}
+
 func GetConfigValue(key string) string {
+	ensureLoaded()
 	// local overrides global
 	if val, ok := localConfig[key]; ok {
 		return val
@@ -110,6 +148,8 @@ func GetConfigValue(key string) string {
 }
 
 func SetConfigValue(key, value string, global bool) error {
+	// Load first so saving one key doesn't drop the others from the file.
+	ensureLoaded()
 	if global {
 		configPath, err := getXDGConfigPath()
 		if err != nil {
@@ -120,7 +160,7 @@ func SetConfigValue(key, value string, global bool) error {
 	}
 	// local
 	localConfig[key] = value
-	localPath := filepath.Join(".", LocalConfigFile)
+	localPath := RepoFile(LocalConfigFile)
 	return saveYAML(localPath, localConfig)
 }
 
@@ -145,7 +185,7 @@ func loadYAML(path string) (map[string]string, error) {
 }
 
 func guessRepoName() string {
-	cwd, _ := os.Getwd()
-	parts := strings.Split(cwd, string(os.PathSeparator))
+	root, _ := filepath.Abs(repoRoot)
+	parts := strings.Split(root, string(os.PathSeparator))
 	return parts[len(parts)-1]
 }
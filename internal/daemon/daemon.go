@@ -62,6 +62,7 @@ func Run() error {
 			// For now, we'll assume there's only one local repo unless we expand Strata to truly handle multiple repos in a single daemon instance.
 
 			syncSharedStackIfNeeded()
+			refreshPRCacheIfStale()
 		}
 
 		time.Sleep(pollInterval)
@@ -92,3 +93,15 @@ func syncSharedStackIfNeeded() {
 
 	logs.Info("[Daemon] Sync complete for shared stack.")
 }
+
+// refreshPRCacheIfStale keeps the PR cache warm so that view, log and diagrams rarely have
+// to wait on the forge themselves.
+func refreshPRCacheIfStale() {
+	prSvc := service.GetPRService()
+	if !prSvc.PRCacheStale() {
+		return
+	}
+	if err := prSvc.RefreshPRCache(); err != nil {
+		logs.Warn("[Daemon] Failed to refresh PR cache: %v", err)
+	}
+}
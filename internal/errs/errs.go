@@ -0,0 +1,99 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Exit codes are part of Strata's scripting contract; never renumber an existing one.
+const (
+	ExitGeneric    = 1
+	ExitConflict   = 3
+	ExitNotInStack = 4
+	ExitDirtyTree  = 5
+	ExitAuth       = 6
+	ExitBlocked    = 7
+)
+
+// Codes are the stable, machine-readable names for the categories above.
+const (
+	CodeGeneric    = "error"
+	CodeConflict   = "conflict"
+	CodeNotInStack = "not_in_stack"
+	CodeDirtyTree  = "dirty_tree"
+	CodeAuth       = "auth"
+	CodeBlocked    = "blocked"
+)
+
+// Error tags an underlying error with a category so the CLI can pick an exit code.
+type Error struct {
+	Code string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func newError(code, format string, args ...interface{}) error {
+	return &Error{Code: code, Err: fmt.Errorf(format, args...)}
+}
+
+// Conflict reports a merge or rebase that stopped on conflicting changes.
+func Conflict(format string, args ...interface{}) error {
+	return newError(CodeConflict, format, args...)
+}
+
+// NotInStack reports a branch that Strata is not tracking.
+func NotInStack(format string, args ...interface{}) error {
+	return newError(CodeNotInStack, format, args...)
+}
+
+// DirtyTree reports uncommitted changes blocking an operation.
+func DirtyTree(format string, args ...interface{}) error {
+	return newError(CodeDirtyTree, format, args...)
+}
+
+// Auth reports missing or rejected forge credentials.
+func Auth(format string, args ...interface{}) error {
+	return newError(CodeAuth, format, args...)
+}
+
+// Blocked reports a stack that cannot land yet, e.g. failing checks or missing approval.
+func Blocked(format string, args ...interface{}) error {
+	return newError(CodeBlocked, format, args...)
+}
+
+// CodeOf returns the category of err, or CodeGeneric when it has none.
+func CodeOf(err error) string {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return CodeGeneric
+}
+
+// ExitCode maps err to the process exit code.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	switch CodeOf(err) {
+	case CodeConflict:
+		return ExitConflict
+	case CodeNotInStack:
+		return ExitNotInStack
+	case CodeDirtyTree:
+		return ExitDirtyTree
+	case CodeAuth:
+		return ExitAuth
+	case CodeBlocked:
+		return ExitBlocked
+	default:
+		return ExitGeneric
+	}
+}
@@ -0,0 +1,233 @@
+// Package forge abstracts the code hosting service behind PR workflows: GitHub pull requests,
+// GitLab merge requests and Gitea/Forgejo pull requests all look the same to the services.
+package forge
+
+import (
+	"errors"
+	"fmt"
+	"strata/internal/config"
+	"strata/internal/git"
+	"strings"
+	"time"
+)
+
+// Config keys for forge selection.
+const (
+	// ForgeConfigKey forces a forge: github, gitlab or gitea. Otherwise it is guessed from origin's host.
+	ForgeConfigKey = "forge"
+	// GitHubClientKey selects how GitHub is reached: "gh" (default) shells out to the gh CLI,
+	// "api" uses the native REST/GraphQL client.
+	GitHubClientKey = "github_client"
+)
+
+// ErrExists is returned by Create when the head branch already has an open change request.
+var ErrExists = errors.New("a change request already exists for this branch")
+
+// Change states, normalised across forges.
+const (
+	StateOpen   = "OPEN"
+	StateMerged = "MERGED"
+	StateClosed = "CLOSED"
+)
+
+// Review decisions, normalised across forges.
+const (
+	ReviewApproved         = "APPROVED"
+	ReviewChangesRequested = "CHANGES_REQUESTED"
+	ReviewRequired         = "REVIEW_REQUIRED"
+)
+
+// Check results, normalised across forges.
+const (
+	CheckPassed  = "passed"
+	CheckFailed  = "failed"
+	CheckPending = "pending"
+)
+
+// ChangeRequest is a pull request (GitHub, Gitea) or merge request (GitLab).
+type ChangeRequest struct {
+	Number int // PR number, or MR iid on GitLab
+	URL    string
+	Title  string
+	Body   string
+	State  string
+	Draft  bool
+	Head   string
+	Base   string
+}
+
+// CreateOptions describes a new change request.
+type CreateOptions struct {
+	Base  string
+	Head  string
+	Title string
+	Body  string
+	Draft bool
+	Metadata
+}
+
+// Metadata is the reviewer/label bookkeeping applied to a change request.
+type Metadata struct {
+	Reviewers []string
+	Labels    []string
+	Assignees []string
+	Milestone string
+}
+
+func (m Metadata) empty() bool {
+	return len(m.Reviewers) == 0 && len(m.Labels) == 0 && len(m.Assignees) == 0 && m.Milestone == ""
+}
+
+// Mergeability, normalised across forges.
+const (
+	MergeableYes     = "mergeable"
+	MergeableNo      = "conflicting"
+	MergeableUnknown = "unknown"
+)
+
+// Check is one CI job or status context. Result is one of the Check* constants.
+type Check struct {
+	Name     string
+	Result   string
+	Required bool
+}
+
+// Status is the review and CI state of a change request.
+type Status struct {
+	Review            string // one of the Review* constants, or "" when no review is required
+	Mergeable         string // one of the Mergeable* constants
+	Checks            []Check
+	UnresolvedThreads int
+}
+
+// CheckCounts tallies checks into passed, failed and pending. With requiredOnly, checks the
+// forge doesn't require for merging are ignored.
+func (s Status) CheckCounts(requiredOnly bool) (int, int, int) {
+	passed, failed, pending := 0, 0, 0
+	for _, c := range s.Checks {
+		if requiredOnly && !c.Required {
+			continue
+		}
+		switch c.Result {
+		case CheckPassed:
+			passed++
+		case CheckFailed:
+			failed++
+		default:
+			pending++
+		}
+	}
+	return passed, failed, pending
+}
+
+// Forge is the set of operations Strata needs from a code hosting service.
+type Forge interface {
+	// Name is the forge's display name, e.g. "GitHub".
+	Name() string
+	// OpenChanges returns the open change request of each branch that has one, fetched in bulk.
+	OpenChanges(branches []string) (map[string]ChangeRequest, error)
+	// LatestChanges returns the change request of each branch that has one in any state:
+	// the open one, otherwise the most recent merged or closed one.
+	LatestChanges(branches []string) (map[string]ChangeRequest, error)
+	// Create opens a change request, returning ErrExists if head already has one.
+	Create(opts CreateOptions) (ChangeRequest, error)
+	UpdateBody(number int, body string) error
+	SetBase(number int, base string) error
+	// AddMetadata adds reviewers, labels, assignees and milestone to an existing change request.
+	AddMetadata(number int, meta Metadata) error
+	// UpsertComment edits the first comment containing marker, or posts body as a new one.
+	UpsertComment(number int, marker, body string) error
+	Status(number int) (Status, error)
+	// Merge merges a change request; method is merge, squash or rebase.
+	Merge(number int, method string) error
+	// Threads lists the review threads (inline discussions) of a change request.
+	Threads(number int) ([]Thread, error)
+	ReplyThread(number int, thread Thread, body string) error
+	ResolveThread(number int, thread Thread) error
+}
+
+// Thread is an inline review discussion on one line of a change request's diff.
+type Thread struct {
+	// ID is the forge's handle for replying to or resolving the thread.
+	ID       string
+	Path     string
+	Line     int
+	Resolved bool
+	// Outdated threads point at code that has changed since they were written.
+	Outdated bool
+	Comments []ThreadComment
+}
+
+type ThreadComment struct {
+	Author    string
+	Body      string
+	CreatedAt time.Time
+}
+
+const (
+	QueueQueued  = "queued"
+	QueueMerged  = "merged"
+	QueueEjected = "ejected"
+)
+
+// QueueEntry is a change request's place in a merge queue.
+type QueueEntry struct {
+	// State is QueueQueued, QueueMerged or QueueEjected (left the queue unmerged).
+	State    string
+	Position int
+	// Reason explains an ejection when the forge reports one.
+	Reason string
+}
+
+// MergeQueue is implemented by forges that can land changes through a merge queue
+// (GitHub merge queues, GitLab merge trains).
+type MergeQueue interface {
+	// Enqueue adds a change request to its base branch's queue. Forges whose queue has
+	// its own merge method ignore method.
+	Enqueue(number int, method string) error
+	// QueueState reports where a change request enqueued at since stands; it only counts
+	// it as ejected when it left the queue after that.
+	QueueState(number int, since time.Time) (QueueEntry, error)
+}
+
+// ForRepo picks the forge for the current repository, from the forge config key or origin's host.
+func ForRepo() (Forge, error) {
+	remote, err := git.OriginRemote()
+	if err != nil {
+		return nil, err
+	}
+
+	kind := config.GetConfigValue(ForgeConfigKey)
+	if kind == "" {
+		kind = guessKind(remote.Host)
+	}
+	switch kind {
+	case "github":
+		switch mode := config.GetConfigValue(GitHubClientKey); mode {
+		case "", "gh":
+			return GitHubCLI{}, nil
+		case "api":
+			return NewGitHub()
+		default:
+			return nil, fmt.Errorf("unknown %s '%s' (expected gh or api)", GitHubClientKey, mode)
+		}
+	case "gitlab":
+		return NewGitLab(remote)
+	case "gitea", "forgejo":
+		return NewGitea(remote)
+	default:
+		return nil, fmt.Errorf("unknown %s '%s' (expected github, gitlab or gitea)", ForgeConfigKey, kind)
+	}
+}
+
+func guessKind(host string) string {
+	h := strings.ToLower(host)
+	switch {
+	case strings.Contains(h, "gitlab"):
+		return "gitlab"
+	case strings.Contains(h, "gitea"), strings.Contains(h, "forgejo"), h == "codeberg.org":
+		return "gitea"
+	default:
+		return "github"
+	}
+}
@@ -0,0 +1,474 @@
+package forge
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strata/internal/config"
+	"strata/internal/errs"
+	"strata/internal/git"
+	"strings"
+	"time"
+)
+
+// Gitea config keys; they also cover Forgejo. The API URL defaults to https://<origin host>/api/v1.
+const (
+	GiteaTokenKey  = "gitea_token"
+	GiteaAPIURLKey = "gitea_api_url"
+)
+
+// Gitea works with pull requests on Gitea and Forgejo instances.
+type Gitea struct {
+	api   *restClient
+	owner string
+	repo  string
+}
+
+// NewGitea builds a Gitea forge for remote. The token comes from gitea_token, $GITEA_TOKEN or $FORGEJO_TOKEN.
+func NewGitea(remote git.Remote) (*Gitea, error) {
+	token := config.GetConfigValue(GiteaTokenKey)
+	for _, env := range []string{"GITEA_TOKEN", "FORGEJO_TOKEN"} {
+		if token == "" {
+			token = os.Getenv(env)
+		}
+	}
+	if token == "" {
+		return nil, errs.Auth("no Gitea token found; set %s or $GITEA_TOKEN", GiteaTokenKey)
+	}
+	baseURL := config.GetConfigValue(GiteaAPIURLKey)
+	if baseURL == "" {
+		baseURL = fmt.Sprintf("https://%s/api/v1", remote.Host)
+	}
+	return NewGiteaClient(baseURL, token, remote.Owner, remote.Repo), nil
+}
+
+// NewGiteaClient builds a Gitea forge for an explicit API URL and repository.
+func NewGiteaClient(baseURL, token, owner, repo string) *Gitea {
+	return &Gitea{
+		api:   newRESTClient("Gitea", baseURL, "Authorization", "token "+token),
+		owner: owner,
+		repo:  repo,
+	}
+}
+
+func (g *Gitea) Name() string { return "Gitea" }
+
+type giteaPull struct {
+	Number  int    `json:"number"`
+	HTMLURL string `json:"html_url"`
+	Title   string `json:"title"`
+	Body    string `json:"body"`
+	State   string `json:"state"` // open, closed
+	Merged  bool   `json:"merged"`
+	Draft   bool   `json:"draft"`
+	// Mergeable is false when the PR conflicts with its base.
+	Mergeable bool `json:"mergeable"`
+	Head      struct {
+		Ref string `json:"ref"`
+		Sha string `json:"sha"`
+	} `json:"head"`
+	Base struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+}
+
+func (p giteaPull) toChange() ChangeRequest {
+	state := StateOpen
+	switch {
+	case p.Merged:
+		state = StateMerged
+	case p.State == "closed":
+		state = StateClosed
+	}
+	return ChangeRequest{
+		Number: p.Number,
+		URL:    p.HTMLURL,
+		Title:  p.Title,
+		Body:   p.Body,
+		State:  state,
+		Draft:  p.Draft,
+		Head:   p.Head.Ref,
+		Base:   p.Base.Ref,
+	}
+}
+
+func (g *Gitea) repoPath(format string, args ...interface{}) string {
+	return fmt.Sprintf("/repos/%s/%s", g.owner, g.repo) + fmt.Sprintf(format, args...)
+}
+
+// giteaPageSize is how many items list requests ask for; a shorter page is the last one.
+const giteaPageSize = 50
+
+// listAll gets every page of the list at path, which may already have a query.
+func listAll[T any](g *Gitea, path string) ([]T, error) {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	all := []T{}
+	for page := 1; ; page++ {
+		var items []T
+		if err := g.api.do(http.MethodGet, fmt.Sprintf("%s%slimit=%d&page=%d", path, sep, giteaPageSize, page), nil, &items); err != nil {
+			return nil, err
+		}
+		all = append(all, items...)
+		if len(items) < giteaPageSize {
+			return all, nil
+		}
+	}
+}
+
+func (g *Gitea) OpenChanges(branches []string) (map[string]ChangeRequest, error) {
+	wanted := map[string]bool{}
+	for _, br := range branches {
+		wanted[br] = true
+	}
+	changes := map[string]ChangeRequest{}
+	for page := 1; ; page++ {
+		var pulls []giteaPull
+		if err := g.api.do(http.MethodGet, g.repoPath("/pulls?state=open&limit=%d&page=%d", giteaPageSize, page), nil, &pulls); err != nil {
+			return nil, err
+		}
+		for _, p := range pulls {
+			if wanted[p.Head.Ref] {
+				changes[p.Head.Ref] = p.toChange()
+			}
+		}
+		if len(pulls) < giteaPageSize {
+			return changes, nil
+		}
+	}
+}
+
+// LatestChanges pages through the open PRs first and then, for the branches without one,
+// through the closed PRs, most recently updated first, until each has been found.
+func (g *Gitea) LatestChanges(branches []string) (map[string]ChangeRequest, error) {
+	changes, err := g.OpenChanges(branches)
+	if err != nil {
+		return nil, err
+	}
+	missing := map[string]bool{}
+	for _, br := range branches {
+		if _, ok := changes[br]; !ok {
+			missing[br] = true
+		}
+	}
+	for page := 1; len(missing) > 0; page++ {
+		var pulls []giteaPull
+		if err := g.api.do(http.MethodGet, g.repoPath("/pulls?state=closed&sort=recentupdate&limit=%d&page=%d", giteaPageSize, page), nil, &pulls); err != nil {
+			return nil, err
+		}
+		for _, p := range pulls {
+			if missing[p.Head.Ref] {
+				changes[p.Head.Ref] = p.toChange()
+				delete(missing, p.Head.Ref)
+			}
+		}
+		if len(pulls) < giteaPageSize {
+			break
+		}
+	}
+	return changes, nil
+}
+
+func (g *Gitea) Create(opts CreateOptions) (ChangeRequest, error) {
+	title := opts.Title
+	if opts.Draft {
+		// Gitea marks PRs as work in progress by title prefix
+		title = "WIP: " + title
+	}
+	req := map[string]interface{}{
+		"base":  opts.Base,
+		"head":  opts.Head,
+		"title": title,
+		"body":  opts.Body,
+	}
+	if len(opts.Assignees) > 0 {
+		req["assignees"] = opts.Assignees
+	}
+	if len(opts.Labels) > 0 {
+		ids, err := g.labelIDs(opts.Labels)
+		if err != nil {
+			return ChangeRequest{}, err
+		}
+		req["labels"] = ids
+	}
+	if opts.Milestone != "" {
+		id, err := g.milestoneID(opts.Milestone)
+		if err != nil {
+			return ChangeRequest{}, err
+		}
+		req["milestone"] = id
+	}
+
+	var pull giteaPull
+	if err := g.api.do(http.MethodPost, g.repoPath("/pulls"), req, &pull); err != nil {
+		var apiErr *apiError
+		if errors.As(err, &apiErr) && apiErr.status == http.StatusConflict {
+			return ChangeRequest{}, ErrExists
+		}
+		return ChangeRequest{}, fmt.Errorf("failed to create PR for '%s': %w", opts.Head, err)
+	}
+	if len(opts.Reviewers) > 0 {
+		if err := g.requestReviewers(pull.Number, opts.Reviewers); err != nil {
+			return pull.toChange(), err
+		}
+	}
+	return pull.toChange(), nil
+}
+
+func (g *Gitea) labelIDs(names []string) ([]int64, error) {
+	labels, err := listAll[struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	}](g, g.repoPath("/labels"))
+	if err != nil {
+		return nil, err
+	}
+	ids := []int64{}
+	for _, name := range names {
+		found := false
+		for _, l := range labels {
+			if l.Name == name {
+				ids = append(ids, l.ID)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("label '%s' not found", name)
+		}
+	}
+	return ids, nil
+}
+
+func (g *Gitea) milestoneID(title string) (int64, error) {
+	milestones, err := listAll[struct {
+		ID    int64  `json:"id"`
+		Title string `json:"title"`
+	}](g, g.repoPath("/milestones?state=open"))
+	if err != nil {
+		return 0, err
+	}
+	for _, m := range milestones {
+		if m.Title == title {
+			return m.ID, nil
+		}
+	}
+	return 0, fmt.Errorf("milestone '%s' not found", title)
+}
+
+func (g *Gitea) requestReviewers(number int, reviewers []string) error {
+	return g.api.do(http.MethodPost, g.repoPath("/pulls/%d/requested_reviewers", number),
+		map[string]interface{}{"reviewers": reviewers}, nil)
+}
+
+func (g *Gitea) UpdateBody(number int, body string) error {
+	return g.api.do(http.MethodPatch, g.repoPath("/pulls/%d", number), map[string]interface{}{"body": body}, nil)
+}
+
+func (g *Gitea) SetBase(number int, base string) error {
+	return g.api.do(http.MethodPatch, g.repoPath("/pulls/%d", number), map[string]interface{}{"base": base}, nil)
+}
+
+func (g *Gitea) AddMetadata(number int, meta Metadata) error {
+	if len(meta.Reviewers) > 0 {
+		if err := g.requestReviewers(number, meta.Reviewers); err != nil {
+			return err
+		}
+	}
+	if len(meta.Labels) > 0 {
+		ids, err := g.labelIDs(meta.Labels)
+		if err != nil {
+			return err
+		}
+		if err := g.api.do(http.MethodPost, g.repoPath("/issues/%d/labels", number), map[string]interface{}{"labels": ids}, nil); err != nil {
+			return err
+		}
+	}
+	edit := map[string]interface{}{}
+	if len(meta.Assignees) > 0 {
+		edit["assignees"] = meta.Assignees
+	}
+	if meta.Milestone != "" {
+		id, err := g.milestoneID(meta.Milestone)
+		if err != nil {
+			return err
+		}
+		edit["milestone"] = id
+	}
+	if len(edit) == 0 {
+		return nil
+	}
+	return g.api.do(http.MethodPatch, g.repoPath("/issues/%d", number), edit, nil)
+}
+
+func (g *Gitea) UpsertComment(number int, marker, body string) error {
+	var comments []struct {
+		ID   int64  `json:"id"`
+		Body string `json:"body"`
+	}
+	if err := g.api.do(http.MethodGet, g.repoPath("/issues/%d/comments", number), nil, &comments); err != nil {
+		return err
+	}
+	for _, c := range comments {
+		if strings.Contains(c.Body, marker) {
+			return g.api.do(http.MethodPatch, g.repoPath("/issues/comments/%d", c.ID), map[string]interface{}{"body": body}, nil)
+		}
+	}
+	return g.api.do(http.MethodPost, g.repoPath("/issues/%d/comments", number), map[string]interface{}{"body": body}, nil)
+}
+
+func (g *Gitea) Status(number int) (Status, error) {
+	var pull giteaPull
+	if err := g.api.do(http.MethodGet, g.repoPath("/pulls/%d", number), nil, &pull); err != nil {
+		return Status{}, err
+	}
+	st := Status{Mergeable: MergeableNo, Checks: []Check{}}
+	if pull.Mergeable {
+		st.Mergeable = MergeableYes
+	}
+
+	reviews, err := listAll[struct {
+		ID   int64 `json:"id"`
+		User struct {
+			Login string `json:"login"`
+		} `json:"user"`
+		State     string `json:"state"` // APPROVED, REQUEST_CHANGES, COMMENT, PENDING
+		Dismissed bool   `json:"dismissed"`
+		Stale     bool   `json:"stale"`
+	}](g, g.repoPath("/pulls/%d/reviews", number))
+	if err != nil {
+		return Status{}, err
+	}
+	// only each reviewer's latest approval or change request counts, like on GitHub
+	latest := map[string]int{}
+	for i, r := range reviews {
+		if r.Dismissed || (r.State != "APPROVED" && r.State != "REQUEST_CHANGES") {
+			continue
+		}
+		if j, ok := latest[r.User.Login]; !ok || r.ID > reviews[j].ID {
+			latest[r.User.Login] = i
+		}
+	}
+	st.Review = ReviewRequired
+	for _, i := range latest {
+		r := reviews[i]
+		if r.Stale {
+			continue
+		}
+		switch r.State {
+		case "REQUEST_CHANGES":
+			st.Review = ReviewChangesRequested
+		case "APPROVED":
+			if st.Review != ReviewChangesRequested {
+				st.Review = ReviewApproved
+			}
+		}
+	}
+
+	threads, err := g.Threads(number)
+	if err != nil {
+		return Status{}, err
+	}
+	for _, t := range threads {
+		if !t.Resolved {
+			st.UnresolvedThreads++
+		}
+	}
+
+	var combined struct {
+		Statuses []struct {
+			Context string `json:"context"`
+			Status  string `json:"status"` // pending, success, error, failure, warning
+		} `json:"statuses"`
+	}
+	if err := g.api.do(http.MethodGet, g.repoPath("/commits/%s/status", pull.Head.Sha), nil, &combined); err != nil {
+		return Status{}, err
+	}
+	for _, s := range combined.Statuses {
+		// Gitea doesn't expose which contexts branch protection requires, so all of them count
+		check := Check{Name: s.Context, Result: CheckPending, Required: true}
+		switch s.Status {
+		case "success", "warning":
+			check.Result = CheckPassed
+		case "failure", "error":
+			check.Result = CheckFailed
+		}
+		st.Checks = append(st.Checks, check)
+	}
+	return st, nil
+}
+
+// Threads groups a PR's review comments into one thread per file and line, the way Gitea
+// shows them as conversations.
+func (g *Gitea) Threads(number int) ([]Thread, error) {
+	reviews, err := listAll[struct {
+		ID            int64 `json:"id"`
+		CommentsCount int   `json:"comments_count"`
+	}](g, g.repoPath("/pulls/%d/reviews", number))
+	if err != nil {
+		return nil, err
+	}
+
+	threads := []Thread{}
+	byLine := map[string]int{}
+	for _, r := range reviews {
+		if r.CommentsCount == 0 {
+			continue
+		}
+		var comments []struct {
+			ID   int64  `json:"id"`
+			Body string `json:"body"`
+			User struct {
+				Login string `json:"login"`
+			} `json:"user"`
+			Path             string    `json:"path"`
+			Position         int       `json:"position"`
+			OriginalPosition int       `json:"original_position"`
+			CreatedAt        time.Time `json:"created_at"`
+			Resolver         *struct{} `json:"resolver"`
+		}
+		if err := g.api.do(http.MethodGet, g.repoPath("/pulls/%d/reviews/%d/comments", number, r.ID), nil, &comments); err != nil {
+			return nil, err
+		}
+		for _, c := range comments {
+			line, outdated := c.Position, false
+			if line == 0 {
+				line, outdated = c.OriginalPosition, true
+			}
+			key := fmt.Sprintf("%s:%d", c.Path, line)
+			i, ok := byLine[key]
+			if !ok {
+				i = len(threads)
+				byLine[key] = i
+				threads = append(threads, Thread{ID: fmt.Sprint(c.ID), Path: c.Path, Line: line, Outdated: outdated, Resolved: true})
+			}
+			// a conversation counts as resolved once all of its comments are
+			threads[i].Resolved = threads[i].Resolved && c.Resolver != nil
+			threads[i].Comments = append(threads[i].Comments, ThreadComment{Author: c.User.Login, Body: c.Body, CreatedAt: c.CreatedAt})
+		}
+	}
+	return threads, nil
+}
+
+// ReplyThread posts a single-comment review on the thread's line, which Gitea adds to the
+// same conversation.
+func (g *Gitea) ReplyThread(number int, thread Thread, body string) error {
+	review := map[string]interface{}{
+		"event": "COMMENT",
+		"comments": []map[string]interface{}{
+			{"path": thread.Path, "new_position": thread.Line, "body": body},
+		},
+	}
+	return g.api.do(http.MethodPost, g.repoPath("/pulls/%d/reviews", number), review, nil)
+}
+
+func (g *Gitea) ResolveThread(number int, thread Thread) error {
+	return fmt.Errorf("the Gitea API cannot resolve review conversations; resolve it in the web UI")
+}
+
+func (g *Gitea) Merge(number int, method string) error {
+	return g.api.do(http.MethodPost, g.repoPath("/pulls/%d/merge", number), map[string]interface{}{"Do": method}, nil)
+}
@@ -0,0 +1,212 @@
+package forge
+
+import (
+	"errors"
+	"fmt"
+	"strata/internal/github"
+	"strata/internal/logs"
+	"strings"
+	"time"
+)
+
+// GitHub reaches github.com or GitHub Enterprise through the native API client.
+type GitHub struct {
+	client *github.Client
+}
+
+// NewGitHub builds a GitHub forge for the current repository.
+func NewGitHub() (*GitHub, error) {
+	client, err := github.NewClientFromRepo()
+	if err != nil {
+		return nil, err
+	}
+	return &GitHub{client: client}, nil
+}
+
+func (g *GitHub) Name() string { return "GitHub" }
+
+func fromPullRequest(pr github.PullRequest) ChangeRequest {
+	return ChangeRequest{
+		Number: pr.Number,
+		URL:    pr.URL,
+		Title:  pr.Title,
+		Body:   pr.Body,
+		State:  pr.State,
+		Draft:  pr.IsDraft,
+		Head:   pr.HeadRefName,
+		Base:   pr.BaseRefName,
+	}
+}
+
+func (g *GitHub) OpenChanges(branches []string) (map[string]ChangeRequest, error) {
+	prs, err := g.client.OpenPullRequests(branches)
+	if err != nil {
+		return nil, err
+	}
+	out := map[string]ChangeRequest{}
+	for br, pr := range prs {
+		out[br] = fromPullRequest(pr)
+	}
+	return out, nil
+}
+
+func (g *GitHub) LatestChanges(branches []string) (map[string]ChangeRequest, error) {
+	prs, err := g.client.LatestPullRequests(branches)
+	if err != nil {
+		return nil, err
+	}
+	out := map[string]ChangeRequest{}
+	for br, pr := range prs {
+		out[br] = fromPullRequest(pr)
+	}
+	return out, nil
+}
+
+func (g *GitHub) Create(opts CreateOptions) (ChangeRequest, error) {
+	pr, err := g.client.CreatePullRequest(opts.Base, opts.Head, opts.Title, opts.Body, opts.Draft)
+	if errors.Is(err, github.ErrAlreadyExists) {
+		return ChangeRequest{}, ErrExists
+	}
+	if err != nil {
+		return ChangeRequest{}, fmt.Errorf("failed to create PR for '%s': %w", opts.Head, err)
+	}
+	if err := g.AddMetadata(pr.Number, opts.Metadata); err != nil {
+		logs.Warn("Created PR #%d but could not apply reviewers/labels: %v", pr.Number, err)
+	}
+	return fromPullRequest(pr), nil
+}
+
+func (g *GitHub) UpdateBody(number int, body string) error {
+	return g.client.UpdatePullRequest(number, map[string]interface{}{"body": body})
+}
+
+func (g *GitHub) SetBase(number int, base string) error {
+	return g.client.UpdatePullRequest(number, map[string]interface{}{"base": base})
+}
+
+func (g *GitHub) AddMetadata(number int, meta Metadata) error {
+	if len(meta.Reviewers) > 0 {
+		if err := g.client.RequestReviewers(number, meta.Reviewers); err != nil {
+			return err
+		}
+	}
+	if len(meta.Labels) > 0 {
+		if err := g.client.AddLabels(number, meta.Labels); err != nil {
+			return err
+		}
+	}
+	if len(meta.Assignees) > 0 {
+		if err := g.client.AddAssignees(number, meta.Assignees); err != nil {
+			return err
+		}
+	}
+	if meta.Milestone != "" {
+		return g.client.SetMilestone(number, meta.Milestone)
+	}
+	return nil
+}
+
+func (g *GitHub) UpsertComment(number int, marker, body string) error {
+	comments, err := g.client.Comments(number)
+	if err != nil {
+		return err
+	}
+	for _, c := range comments {
+		if strings.Contains(c.Body, marker) {
+			return g.client.UpdateComment(c.ID, body)
+		}
+	}
+	return g.client.CreateComment(number, body)
+}
+
+func (g *GitHub) Status(number int) (Status, error) {
+	st, err := g.client.PullRequestStatus(number)
+	if err != nil {
+		return Status{}, err
+	}
+	return githubStatus(st), nil
+}
+
+func (g *GitHub) Merge(number int, method string) error {
+	return g.client.MergePullRequest(number, method)
+}
+
+func (g *GitHub) Enqueue(number int, method string) error {
+	return g.client.EnqueuePullRequest(number)
+}
+
+func (g *GitHub) QueueState(number int, since time.Time) (QueueEntry, error) {
+	st, err := g.client.MergeQueueStatus(number)
+	if err != nil {
+		return QueueEntry{}, err
+	}
+	return githubQueueEntry(st, since), nil
+}
+
+func (g *GitHub) Threads(number int) ([]Thread, error) {
+	threads, err := g.client.ReviewThreads(number)
+	if err != nil {
+		return nil, err
+	}
+	return githubThreads(threads), nil
+}
+
+func (g *GitHub) ReplyThread(number int, thread Thread, body string) error {
+	return g.client.ReplyToThread(thread.ID, body)
+}
+
+func (g *GitHub) ResolveThread(number int, thread Thread) error {
+	return g.client.ResolveThread(thread.ID)
+}
+
+func githubThreads(threads []github.ReviewThread) []Thread {
+	out := []Thread{}
+	for _, t := range threads {
+		th := Thread{ID: t.ID, Path: t.Path, Line: t.Line, Resolved: t.IsResolved, Outdated: t.IsOutdated}
+		for _, c := range t.Comments {
+			th.Comments = append(th.Comments, ThreadComment{Author: c.Author, Body: c.Body, CreatedAt: c.CreatedAt})
+		}
+		out = append(out, th)
+	}
+	return out
+}
+
+// githubQueueEntry reads an open PR without a queue entry as ejected once it was removed
+// from the queue after since, the time it was enqueued. Until then the entry may just not
+// show yet, and an older removal is left over from an earlier attempt.
+func githubQueueEntry(st github.MergeQueueStatus, since time.Time) QueueEntry {
+	switch {
+	case st.State == StateMerged:
+		return QueueEntry{State: QueueMerged}
+	case st.State == StateClosed:
+		return QueueEntry{State: QueueEjected, Reason: "closed"}
+	case st.Entry != "":
+		return QueueEntry{State: QueueQueued, Position: st.Position}
+	case st.LastRemoval != "" && !st.LastRemovedAt.Before(since):
+		return QueueEntry{State: QueueEjected, Reason: strings.ToLower(strings.ReplaceAll(st.LastRemoval, "_", " "))}
+	default:
+		return QueueEntry{State: QueueQueued}
+	}
+}
+
+// githubStatus normalises GitHub's review decision, mergeable state and check results.
+func githubStatus(pr github.PullRequestStatus) Status {
+	st := Status{Review: pr.ReviewDecision, Mergeable: MergeableUnknown, Checks: []Check{}, UnresolvedThreads: pr.UnresolvedThreads}
+	switch pr.Mergeable {
+	case "MERGEABLE":
+		st.Mergeable = MergeableYes
+	case "CONFLICTING":
+		st.Mergeable = MergeableNo
+	}
+	for _, c := range pr.Checks {
+		check := Check{Name: c.Name, Result: CheckPending, Required: c.Required}
+		switch c.Result {
+		case "SUCCESS", "NEUTRAL", "SKIPPED":
+			check.Result = CheckPassed
+		case "FAILURE", "ERROR", "CANCELLED", "TIMED_OUT", "ACTION_REQUIRED", "STARTUP_FAILURE":
+			check.Result = CheckFailed
+		}
+		st.Checks = append(st.Checks, check)
+	}
+	return st
+}
@@ -0,0 +1,266 @@
+package forge
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strata/internal/errs"
+	"strata/internal/github"
+	"strata/internal/logs"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GitHubCLI reaches GitHub by shelling out to the gh CLI. It is the default for GitHub remotes.
+type GitHubCLI struct{}
+
+func (GitHubCLI) Name() string { return "GitHub" }
+
+func ghError(out []byte, err error, format string, args ...interface{}) error {
+	if strings.Contains(string(out), "Authentication") {
+		logs.Error("GH CLI authentication error: %s", string(out))
+		return errs.Auth("gh auth error: %v", err)
+	}
+	return fmt.Errorf("%s: %v\n%s", fmt.Sprintf(format, args...), err, string(out))
+}
+
+// OpenChanges asks for each branch's PR by head rather than listing open PRs, so it isn't
+// limited to one page of them.
+func (GitHubCLI) OpenChanges(branches []string) (map[string]ChangeRequest, error) {
+	return ghHeadChanges(branches, github.StateOpen)
+}
+
+func (GitHubCLI) LatestChanges(branches []string) (map[string]ChangeRequest, error) {
+	return ghHeadChanges(branches, github.StateOpen, github.StateMerged, github.StateClosed)
+}
+
+// ghHeadChanges looks the branches' change requests in states up with the API client's
+// batched GraphQL queries, run through `gh api graphql` so gh's authentication is used.
+func ghHeadChanges(branches []string, states ...string) (map[string]ChangeRequest, error) {
+	prs := map[string]github.PullRequest{}
+	for _, q := range github.HeadQueries(branches, states...) {
+		// gh fills in {owner} and {repo} from the current repository
+		fields := []string{"-F", "owner={owner}", "-F", "repo={repo}"}
+		for k, v := range q.Vars {
+			fields = append(fields, "-f", k+"="+v)
+		}
+		data, err := ghGraphQL("get PR info", q.Query, fields...)
+		if err != nil {
+			return nil, err
+		}
+		if err := q.Collect(data, prs); err != nil {
+			return nil, err
+		}
+	}
+	changes := map[string]ChangeRequest{}
+	for br, pr := range prs {
+		changes[br] = fromPullRequest(pr)
+	}
+	return changes, nil
+}
+
+func (GitHubCLI) Create(opts CreateOptions) (ChangeRequest, error) {
+	args := []string{"pr", "create",
+		"--base", opts.Base,
+		"--head", opts.Head,
+		"--title", opts.Title,
+		"--body", opts.Body,
+	}
+	if opts.Draft {
+		args = append(args, "--draft")
+	}
+	for _, r := range opts.Reviewers {
+		args = append(args, "--reviewer", r)
+	}
+	for _, l := range opts.Labels {
+		args = append(args, "--label", l)
+	}
+	for _, a := range opts.Assignees {
+		args = append(args, "--assignee", a)
+	}
+	if opts.Milestone != "" {
+		args = append(args, "--milestone", opts.Milestone)
+	}
+
+	out, err := exec.Command("gh", args...).CombinedOutput()
+	if err != nil {
+		if strings.Contains(string(out), "already exists") {
+			return ChangeRequest{}, ErrExists
+		}
+		return ChangeRequest{}, ghError(out, err, "failed to create PR for '%s'", opts.Head)
+	}
+
+	// gh prints the new PR's URL last; it ends in the PR number
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	url := strings.TrimSpace(lines[len(lines)-1])
+	number, _ := strconv.Atoi(url[strings.LastIndex(url, "/")+1:])
+	return ChangeRequest{
+		Number: number,
+		URL:    url,
+		Title:  opts.Title,
+		Body:   opts.Body,
+		State:  StateOpen,
+		Draft:  opts.Draft,
+		Head:   opts.Head,
+		Base:   opts.Base,
+	}, nil
+}
+
+func (GitHubCLI) UpdateBody(number int, body string) error {
+	out, err := exec.Command("gh", "pr", "edit", strconv.Itoa(number), "--body", body).CombinedOutput()
+	if err != nil {
+		return ghError(out, err, "failed to update PR body")
+	}
+	return nil
+}
+
+func (GitHubCLI) SetBase(number int, base string) error {
+	out, err := exec.Command("gh", "pr", "edit", strconv.Itoa(number), "--base", base).CombinedOutput()
+	if err != nil {
+		return ghError(out, err, "failed to retarget PR #%d", number)
+	}
+	return nil
+}
+
+// AddMetadata does not touch the draft state of existing PRs.
+func (GitHubCLI) AddMetadata(number int, meta Metadata) error {
+	if meta.empty() {
+		return nil
+	}
+	args := []string{"pr", "edit", strconv.Itoa(number)}
+	for _, r := range meta.Reviewers {
+		args = append(args, "--add-reviewer", r)
+	}
+	for _, l := range meta.Labels {
+		args = append(args, "--add-label", l)
+	}
+	for _, a := range meta.Assignees {
+		args = append(args, "--add-assignee", a)
+	}
+	if meta.Milestone != "" {
+		args = append(args, "--milestone", meta.Milestone)
+	}
+	if out, err := exec.Command("gh", args...).CombinedOutput(); err != nil {
+		return ghError(out, err, "failed to edit PR #%d", number)
+	}
+	return nil
+}
+
+func (GitHubCLI) UpsertComment(number int, marker, body string) error {
+	cmd := exec.Command("gh", "api", "--paginate",
+		fmt.Sprintf("repos/{owner}/{repo}/issues/%d/comments", number),
+		"--jq", `.[] | select(.body | contains("`+marker+`")) | .id`,
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return ghError(out, err, "failed to list comments on PR #%d", number)
+	}
+
+	ids := strings.Fields(string(out))
+	if len(ids) == 0 {
+		cmd = exec.Command("gh", "pr", "comment", strconv.Itoa(number), "--body", body)
+	} else {
+		cmd = exec.Command("gh", "api", "-X", "PATCH",
+			fmt.Sprintf("repos/{owner}/{repo}/issues/comments/%s", ids[0]),
+			"-f", "body="+body,
+		)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return ghError(out, err, "failed to update comment on PR #%d", number)
+	}
+	return nil
+}
+
+// ghGraphQL runs a query through `gh api graphql` and returns its "data" object. fields are
+// gh flags: "-F" for typed values, where gh fills in {owner} and {repo}, "-f" for raw strings.
+func ghGraphQL(what, query string, fields ...string) (json.RawMessage, error) {
+	args := append([]string{"api", "graphql", "-f", "query=" + query}, fields...)
+	out, err := exec.Command("gh", args...).CombinedOutput()
+	if err != nil {
+		return nil, ghError(out, err, "failed to %s", what)
+	}
+	var resp struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(out, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse response to %s: %v", what, err)
+	}
+	return resp.Data, nil
+}
+
+// prFields addresses a PR of the current repository in a query taking $owner, $repo and $number.
+func prFields(number int) []string {
+	return []string{"-F", "owner={owner}", "-F", "repo={repo}", "-F", fmt.Sprintf("number=%d", number)}
+}
+
+func (GitHubCLI) Status(number int) (Status, error) {
+	data, err := ghGraphQL(fmt.Sprintf("get status of PR #%d", number), github.StatusQuery,
+		prFields(number)...)
+	if err != nil {
+		return Status{}, err
+	}
+	pr, err := github.DecodeStatus(data)
+	if err != nil {
+		return Status{}, err
+	}
+	return githubStatus(pr), nil
+}
+
+func (GitHubCLI) Merge(number int, method string) error {
+	out, err := exec.Command("gh", "pr", "merge", strconv.Itoa(number), "--"+method).CombinedOutput()
+	if err != nil {
+		return ghError(out, err, "failed to merge PR #%d", number)
+	}
+	return nil
+}
+
+func (GitHubCLI) queueStatus(number int) (github.MergeQueueStatus, error) {
+	data, err := ghGraphQL(fmt.Sprintf("get merge queue status of PR #%d", number), github.QueueQuery,
+		prFields(number)...)
+	if err != nil {
+		return github.MergeQueueStatus{}, err
+	}
+	return github.DecodeMergeQueue(data)
+}
+
+func (g GitHubCLI) Enqueue(number int, method string) error {
+	st, err := g.queueStatus(number)
+	if err != nil {
+		return err
+	}
+	_, err = ghGraphQL(fmt.Sprintf("enqueue PR #%d", number), github.EnqueueMutation, "-f", "id="+st.ID)
+	return err
+}
+
+func (g GitHubCLI) QueueState(number int, since time.Time) (QueueEntry, error) {
+	st, err := g.queueStatus(number)
+	if err != nil {
+		return QueueEntry{}, err
+	}
+	return githubQueueEntry(st, since), nil
+}
+
+func (GitHubCLI) Threads(number int) ([]Thread, error) {
+	threads, err := github.CollectThreads(func(after string) ([]byte, error) {
+		fields := prFields(number)
+		if after != "" {
+			fields = append(fields, "-f", "after="+after)
+		}
+		return ghGraphQL(fmt.Sprintf("get review threads of PR #%d", number), github.ThreadsQuery, fields...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return githubThreads(threads), nil
+}
+
+func (GitHubCLI) ReplyThread(number int, thread Thread, body string) error {
+	_, err := ghGraphQL(fmt.Sprintf("reply on PR #%d", number), github.ReplyMutation, "-f", "thread="+thread.ID, "-f", "body="+body)
+	return err
+}
+
+func (GitHubCLI) ResolveThread(number int, thread Thread) error {
+	_, err := ghGraphQL(fmt.Sprintf("resolve thread on PR #%d", number), github.ResolveMutation, "-f", "thread="+thread.ID)
+	return err
+}
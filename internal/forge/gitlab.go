@@ -0,0 +1,453 @@
+package forge
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strata/internal/config"
+	"strata/internal/errs"
+	"strata/internal/git"
+	"strings"
+	"time"
+)
+
+// GitLab config keys. The API URL defaults to https://<origin host>/api/v4.
+const (
+	GitLabTokenKey  = "gitlab_token"
+	GitLabAPIURLKey = "gitlab_api_url"
+)
+
+// GitLab works with merge requests on gitlab.com or a self-hosted instance.
+type GitLab struct {
+	api     *restClient
+	project string // URL-escaped "group/subgroup/project"
+}
+
+// NewGitLab builds a GitLab forge for remote. The token comes from gitlab_token or $GITLAB_TOKEN.
+func NewGitLab(remote git.Remote) (*GitLab, error) {
+	token := config.GetConfigValue(GitLabTokenKey)
+	if token == "" {
+		token = os.Getenv("GITLAB_TOKEN")
+	}
+	if token == "" {
+		return nil, errs.Auth("no GitLab token found; set %s or $GITLAB_TOKEN", GitLabTokenKey)
+	}
+	baseURL := config.GetConfigValue(GitLabAPIURLKey)
+	if baseURL == "" {
+		baseURL = fmt.Sprintf("https://%s/api/v4", remote.Host)
+	}
+	return NewGitLabClient(baseURL, token, remote.Owner+"/"+remote.Repo), nil
+}
+
+// NewGitLabClient builds a GitLab forge for an explicit API URL and project path.
+func NewGitLabClient(baseURL, token, projectPath string) *GitLab {
+	return &GitLab{
+		api:     newRESTClient("GitLab", baseURL, "PRIVATE-TOKEN", token),
+		project: url.PathEscape(projectPath),
+	}
+}
+
+func (g *GitLab) Name() string { return "GitLab" }
+
+type gitlabMR struct {
+	IID          int    `json:"iid"`
+	WebURL       string `json:"web_url"`
+	Title        string `json:"title"`
+	Description  string `json:"description"`
+	State        string `json:"state"` // opened, merged, closed, locked
+	Draft        bool   `json:"draft"`
+	SourceBranch string `json:"source_branch"`
+	TargetBranch string `json:"target_branch"`
+	MergeStatus  string `json:"detailed_merge_status"`
+	HeadPipeline *struct {
+		Status string `json:"status"`
+	} `json:"head_pipeline"`
+}
+
+func (mr gitlabMR) toChange() ChangeRequest {
+	state := StateOpen
+	switch mr.State {
+	case "merged":
+		state = StateMerged
+	case "closed", "locked":
+		state = StateClosed
+	}
+	return ChangeRequest{
+		Number: mr.IID,
+		URL:    mr.WebURL,
+		Title:  mr.Title,
+		Body:   mr.Description,
+		State:  state,
+		Draft:  mr.Draft,
+		Head:   mr.SourceBranch,
+		Base:   mr.TargetBranch,
+	}
+}
+
+func (g *GitLab) mrPath(format string, args ...interface{}) string {
+	return fmt.Sprintf("/projects/%s/merge_requests", g.project) + fmt.Sprintf(format, args...)
+}
+
+func (g *GitLab) OpenChanges(branches []string) (map[string]ChangeRequest, error) {
+	wanted := map[string]bool{}
+	for _, br := range branches {
+		wanted[br] = true
+	}
+	changes := map[string]ChangeRequest{}
+	for page := 1; ; page++ {
+		var mrs []gitlabMR
+		if err := g.api.do(http.MethodGet, g.mrPath("?state=opened&per_page=100&page=%d", page), nil, &mrs); err != nil {
+			return nil, err
+		}
+		for _, mr := range mrs {
+			if wanted[mr.SourceBranch] {
+				changes[mr.SourceBranch] = mr.toChange()
+			}
+		}
+		if len(mrs) < 100 {
+			return changes, nil
+		}
+	}
+}
+
+// LatestChanges asks for each branch's newest merge requests, since GitLab filters them by
+// source branch.
+func (g *GitLab) LatestChanges(branches []string) (map[string]ChangeRequest, error) {
+	changes := map[string]ChangeRequest{}
+	for _, br := range branches {
+		var mrs []gitlabMR
+		path := g.mrPath("?source_branch=%s&state=all&order_by=created_at&sort=desc&per_page=5", url.QueryEscape(br))
+		if err := g.api.do(http.MethodGet, path, nil, &mrs); err != nil {
+			return nil, err
+		}
+		for i, mr := range mrs {
+			if i == 0 || mr.State == "opened" {
+				changes[br] = mr.toChange()
+			}
+			if mr.State == "opened" {
+				break
+			}
+		}
+	}
+	return changes, nil
+}
+
+func (g *GitLab) Create(opts CreateOptions) (ChangeRequest, error) {
+	title := opts.Title
+	if opts.Draft {
+		title = "Draft: " + title
+	}
+	req := map[string]interface{}{
+		"source_branch":        opts.Head,
+		"target_branch":        opts.Base,
+		"title":                title,
+		"description":          opts.Body,
+		"remove_source_branch": false,
+	}
+	if len(opts.Labels) > 0 {
+		req["labels"] = strings.Join(opts.Labels, ",")
+	}
+	if err := g.resolveMetadata(req, opts.Metadata); err != nil {
+		return ChangeRequest{}, err
+	}
+
+	var mr gitlabMR
+	if err := g.api.do(http.MethodPost, g.mrPath(""), req, &mr); err != nil {
+		var apiErr *apiError
+		if errors.As(err, &apiErr) && apiErr.status == http.StatusConflict {
+			return ChangeRequest{}, ErrExists
+		}
+		return ChangeRequest{}, fmt.Errorf("failed to create MR for '%s': %w", opts.Head, err)
+	}
+	return mr.toChange(), nil
+}
+
+// resolveMetadata translates usernames and the milestone title into the ids GitLab expects.
+func (g *GitLab) resolveMetadata(req map[string]interface{}, meta Metadata) error {
+	if len(meta.Reviewers) > 0 {
+		ids, err := g.userIDs(meta.Reviewers)
+		if err != nil {
+			return err
+		}
+		req["reviewer_ids"] = ids
+	}
+	if len(meta.Assignees) > 0 {
+		ids, err := g.userIDs(meta.Assignees)
+		if err != nil {
+			return err
+		}
+		req["assignee_ids"] = ids
+	}
+	if meta.Milestone != "" {
+		var milestones []struct {
+			ID    int    `json:"id"`
+			Title string `json:"title"`
+		}
+		path := fmt.Sprintf("/projects/%s/milestones?state=active&title=%s", g.project, url.QueryEscape(meta.Milestone))
+		if err := g.api.do(http.MethodGet, path, nil, &milestones); err != nil {
+			return err
+		}
+		if len(milestones) == 0 {
+			return fmt.Errorf("milestone '%s' not found", meta.Milestone)
+		}
+		req["milestone_id"] = milestones[0].ID
+	}
+	return nil
+}
+
+func (g *GitLab) userIDs(usernames []string) ([]int, error) {
+	ids := []int{}
+	for _, name := range usernames {
+		var users []struct {
+			ID int `json:"id"`
+		}
+		if err := g.api.do(http.MethodGet, "/users?username="+url.QueryEscape(name), nil, &users); err != nil {
+			return nil, err
+		}
+		if len(users) == 0 {
+			return nil, fmt.Errorf("GitLab user '%s' not found", name)
+		}
+		ids = append(ids, users[0].ID)
+	}
+	return ids, nil
+}
+
+func (g *GitLab) UpdateBody(number int, body string) error {
+	return g.api.do(http.MethodPut, g.mrPath("/%d", number), map[string]interface{}{"description": body}, nil)
+}
+
+func (g *GitLab) SetBase(number int, base string) error {
+	return g.api.do(http.MethodPut, g.mrPath("/%d", number), map[string]interface{}{"target_branch": base}, nil)
+}
+
+func (g *GitLab) AddMetadata(number int, meta Metadata) error {
+	if meta.empty() {
+		return nil
+	}
+	req := map[string]interface{}{}
+	if len(meta.Labels) > 0 {
+		req["add_labels"] = strings.Join(meta.Labels, ",")
+	}
+	if err := g.resolveMetadata(req, meta); err != nil {
+		return err
+	}
+	return g.api.do(http.MethodPut, g.mrPath("/%d", number), req, nil)
+}
+
+func (g *GitLab) UpsertComment(number int, marker, body string) error {
+	for page := 1; ; page++ {
+		var notes []struct {
+			ID     int64  `json:"id"`
+			Body   string `json:"body"`
+			System bool   `json:"system"`
+		}
+		if err := g.api.do(http.MethodGet, g.mrPath("/%d/notes?per_page=100&page=%d", number, page), nil, &notes); err != nil {
+			return err
+		}
+		for _, n := range notes {
+			if !n.System && strings.Contains(n.Body, marker) {
+				return g.api.do(http.MethodPut, g.mrPath("/%d/notes/%d", number, n.ID), map[string]interface{}{"body": body}, nil)
+			}
+		}
+		if len(notes) < 100 {
+			break
+		}
+	}
+	return g.api.do(http.MethodPost, g.mrPath("/%d/notes", number), map[string]interface{}{"body": body}, nil)
+}
+
+func (g *GitLab) Status(number int) (Status, error) {
+	var mr gitlabMR
+	if err := g.api.do(http.MethodGet, g.mrPath("/%d", number), nil, &mr); err != nil {
+		return Status{}, err
+	}
+	var approvals struct {
+		Approved          bool `json:"approved"`
+		ApprovalsRequired int  `json:"approvals_required"`
+	}
+	if err := g.api.do(http.MethodGet, g.mrPath("/%d/approvals", number), nil, &approvals); err != nil {
+		return Status{}, err
+	}
+
+	st := Status{Mergeable: MergeableUnknown, Checks: []Check{}}
+	switch mr.MergeStatus {
+	case "mergeable":
+		st.Mergeable = MergeableYes
+	case "broken_status", "conflict", "need_rebase":
+		st.Mergeable = MergeableNo
+	}
+	switch {
+	case approvals.Approved && approvals.ApprovalsRequired > 0:
+		st.Review = ReviewApproved
+	case !approvals.Approved:
+		st.Review = ReviewRequired
+	}
+	if mr.HeadPipeline != nil {
+		// GitLab gates merges on the pipeline as a whole
+		check := Check{Name: "pipeline", Result: CheckPending, Required: true}
+		switch mr.HeadPipeline.Status {
+		case "success", "skipped", "manual":
+			check.Result = CheckPassed
+		case "failed", "canceled":
+			check.Result = CheckFailed
+		}
+		st.Checks = append(st.Checks, check)
+	}
+
+	threads, err := g.Threads(number)
+	if err != nil {
+		return Status{}, err
+	}
+	for _, t := range threads {
+		if !t.Resolved {
+			st.UnresolvedThreads++
+		}
+	}
+	return st, nil
+}
+
+// Threads returns the resolvable discussions of an MR, i.e. review threads and not plain comments.
+func (g *GitLab) Threads(number int) ([]Thread, error) {
+	threads := []Thread{}
+	for page := 1; ; page++ {
+		var discussions []struct {
+			ID    string `json:"id"`
+			Notes []struct {
+				Body   string `json:"body"`
+				Author struct {
+					Username string `json:"username"`
+				} `json:"author"`
+				CreatedAt  time.Time `json:"created_at"`
+				System     bool      `json:"system"`
+				Resolvable bool      `json:"resolvable"`
+				Resolved   bool      `json:"resolved"`
+				Position   *struct {
+					NewPath string `json:"new_path"`
+					NewLine int    `json:"new_line"`
+					OldPath string `json:"old_path"`
+					OldLine int    `json:"old_line"`
+				} `json:"position"`
+			} `json:"notes"`
+		}
+		if err := g.api.do(http.MethodGet, g.mrPath("/%d/discussions?per_page=100&page=%d", number, page), nil, &discussions); err != nil {
+			return nil, err
+		}
+		for _, d := range discussions {
+			if len(d.Notes) == 0 || !d.Notes[0].Resolvable {
+				continue
+			}
+			t := Thread{ID: d.ID, Resolved: d.Notes[0].Resolved}
+			if pos := d.Notes[0].Position; pos != nil {
+				t.Path, t.Line = pos.NewPath, pos.NewLine
+				if t.Line == 0 {
+					// comments on removed lines only have the old side
+					t.Path, t.Line = pos.OldPath, pos.OldLine
+				}
+			}
+			for _, n := range d.Notes {
+				if !n.System {
+					t.Comments = append(t.Comments, ThreadComment{Author: n.Author.Username, Body: n.Body, CreatedAt: n.CreatedAt})
+				}
+			}
+			threads = append(threads, t)
+		}
+		if len(discussions) < 100 {
+			return threads, nil
+		}
+	}
+}
+
+func (g *GitLab) ReplyThread(number int, thread Thread, body string) error {
+	return g.api.do(http.MethodPost, g.mrPath("/%d/discussions/%s/notes", number, thread.ID), map[string]interface{}{"body": body}, nil)
+}
+
+func (g *GitLab) ResolveThread(number int, thread Thread) error {
+	return g.api.do(http.MethodPut, g.mrPath("/%d/discussions/%s", number, thread.ID), map[string]interface{}{"resolved": true}, nil)
+}
+
+// gitlabRebaseTimeout bounds the wait for GitLab to rebase an MR before merging it, and
+// gitlabRebaseInterval is how often the MR is checked meanwhile.
+const (
+	gitlabRebaseTimeout  = 5 * time.Minute
+	gitlabRebaseInterval = 2 * time.Second
+)
+
+func (g *GitLab) Merge(number int, method string) error {
+	req := map[string]interface{}{"squash": method == "squash"}
+	if method == "rebase" {
+		sha, err := g.rebase(number)
+		if err != nil {
+			return err
+		}
+		// merge exactly what was rebased, not whatever was pushed meanwhile
+		req["sha"] = sha
+	}
+	return g.api.do(http.MethodPut, g.mrPath("/%d/merge", number), req, nil)
+}
+
+// rebase has GitLab rebase the MR onto its target branch and waits for it to finish, since
+// GitLab rebases in the background. It returns the MR's new head commit.
+func (g *GitLab) rebase(number int) (string, error) {
+	if err := g.api.do(http.MethodPut, g.mrPath("/%d/rebase", number), nil, nil); err != nil {
+		return "", err
+	}
+	deadline := time.Now().Add(gitlabRebaseTimeout)
+	for {
+		var mr struct {
+			SHA              string `json:"sha"`
+			RebaseInProgress bool   `json:"rebase_in_progress"`
+			MergeError       string `json:"merge_error"`
+		}
+		if err := g.api.do(http.MethodGet, g.mrPath("/%d?include_rebase_in_progress=true", number), nil, &mr); err != nil {
+			return "", err
+		}
+		switch {
+		case mr.RebaseInProgress:
+		case mr.MergeError != "":
+			return "", errs.Conflict("GitLab could not rebase !%d: %s", number, mr.MergeError)
+		default:
+			return mr.SHA, nil
+		}
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("GitLab is still rebasing !%d after %v", number, gitlabRebaseTimeout)
+		}
+		time.Sleep(gitlabRebaseInterval)
+	}
+}
+
+// Enqueue adds the MR to its target branch's merge train.
+func (g *GitLab) Enqueue(number int, method string) error {
+	path := fmt.Sprintf("/projects/%s/merge_trains/merge_requests/%d", g.project, number)
+	return g.api.do(http.MethodPost, path, map[string]interface{}{"squash": method == "squash"}, nil)
+}
+
+// QueueState asks the merge train about the MR. GitLab reports no removal times, so since
+// goes unused.
+func (g *GitLab) QueueState(number int, since time.Time) (QueueEntry, error) {
+	var car struct {
+		Status string `json:"status"` // idle, stale, fresh, merging, merged, skip_merged
+	}
+	path := fmt.Sprintf("/projects/%s/merge_trains/merge_requests/%d", g.project, number)
+	err := g.api.do(http.MethodGet, path, nil, &car)
+	var apiErr *apiError
+	switch {
+	case err == nil && (car.Status == "merged" || car.Status == "skip_merged"):
+		return QueueEntry{State: QueueMerged}, nil
+	case err == nil:
+		return QueueEntry{State: QueueQueued}, nil
+	case !errors.As(err, &apiErr) || apiErr.status != http.StatusNotFound:
+		return QueueEntry{}, err
+	}
+
+	// off the train: either it merged and the car was cleaned up, or it was dropped
+	var mr gitlabMR
+	if err := g.api.do(http.MethodGet, g.mrPath("/%d", number), nil, &mr); err != nil {
+		return QueueEntry{}, err
+	}
+	if mr.State == "merged" {
+		return QueueEntry{State: QueueMerged}, nil
+	}
+	return QueueEntry{State: QueueEjected, Reason: strings.ReplaceAll(mr.MergeStatus, "_", " ")}, nil
+}
@@ -0,0 +1,65 @@
+package forge
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strata/internal/errs"
+	"strata/internal/restapi"
+	"strings"
+)
+
+// restClient is a small JSON client shared by the GitLab and Gitea forges. Retries and rate
+// limits are handled by restapi, like for the GitHub client.
+type restClient struct {
+	baseURL string
+	api     *restapi.Client
+}
+
+func newRESTClient(name, baseURL, authHeader, authValue string) *restClient {
+	headers := map[string]string{"Accept": "application/json", authHeader: authValue}
+	errorFor := func(status int, data []byte) error {
+		msg := errorMessage(data)
+		if status == http.StatusUnauthorized {
+			return errs.Auth("%s rejected the token: %s", name, msg)
+		}
+		return &apiError{forge: name, status: status, message: msg}
+	}
+	return &restClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		api:     restapi.New(name, headers, errorFor),
+	}
+}
+
+// apiError is a non-2xx response.
+type apiError struct {
+	forge   string
+	status  int
+	message string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%s API error %d: %s", e.forge, e.status, e.message)
+}
+
+// do sends a request relative to baseURL and decodes the JSON response into out (if non-nil).
+func (c *restClient) do(method, path string, body interface{}, out interface{}) error {
+	return c.api.Send(method, c.baseURL+path, body, out)
+}
+
+// errorMessage pulls the human-readable message out of a GitLab or Gitea error body.
+func errorMessage(data []byte) string {
+	var body struct {
+		Message interface{} `json:"message"`
+		Error   string      `json:"error"`
+	}
+	if err := json.Unmarshal(data, &body); err == nil {
+		if body.Message != nil {
+			return fmt.Sprint(body.Message)
+		}
+		if body.Error != "" {
+			return body.Error
+		}
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strata/internal/config"
+	"strata/internal/errs"
 	"strata/internal/logs"
 	"strings"
 	"time"
@@ -53,41 +55,93 @@ func RenameBranch(oldName, newName string) error {
 	return nil
 }
 
+// DeleteBranch force-deletes a local branch. The remote branch is left alone.
+func DeleteBranch(branch string) error {
+	cmd := exec.Command("git", "branch", "-D", branch)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git branch -D %s failed: %v\n%s", branch, err, string(out))
+	}
+	return nil
+}
+
+// CheckoutBranch switches the working copy to an existing branch.
+func CheckoutBranch(branch string) error {
+	return checkoutBranch(branch)
+}
+
+// PushBranch pushes branch to origin without checking it out. Rewritten history is
+// pushed with --force-with-lease so a teammate's newer commits are never clobbered.
+func PushBranch(branch string, force bool) error {
+	args := []string{"push", "-u", "origin", branch}
+	if force {
+		args = []string{"push", "--force-with-lease", "-u", "origin", branch}
+	}
+	cmd := exec.Command("git", args...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git push %s error: %v\n%s", branch, err, string(out))
+	}
+	return nil
+}
+
 func MergeBranch(src, target string) error {
 	// Create a save point
-	txTag := createTxTag("merge")
+	txTag := createTxTag("", "merge")
 	defer cleanupTxTag(txTag)
 
 	// checkout target
 	if err := checkoutBranch(target); err != nil {
-		revertToTag(txTag)
+		revertToTag("", txTag)
 		return err
 	}
 
-	cmd := exec.Command("git", "merge", "--no-ff", src)
+	cmd := gitRerereIn("", "merge", "--no-ff", src)
 	out, err := cmd.CombinedOutput()
+	if err != nil && strings.Contains(string(out), "CONFLICT") &&
+		len(reportReused("", target, string(out))) > 0 && len(unmergedFiles("")) == 0 {
+		// every conflict had a recorded resolution; commit the merge as git would have
+		out, err = gitRerereIn("", "commit", "--no-edit").CombinedOutput()
+	}
 	if err != nil {
-		exec.Command("git", "merge", "--abort").Run()
-		revertToTag(txTag)
+		gitRerereIn("", "merge", "--abort").Run()
+		revertToTag("", txTag)
+		if strings.Contains(string(out), "CONFLICT") {
+			return errs.Conflict("merge %s -> %s stopped on conflicts: %v\n%s", src, target, err, string(out))
+		}
 		return fmt.Errorf("merge %s -> %s failed: %v\n%s", src, target, err, string(out))
 	}
 	return nil
 }
 
 // createTxTag creates a temporary tag like `strata-tx-merge-<timestamp>`
-func createTxTag(prefix string) string {
+// of the HEAD in dir (the current worktree when dir is empty)
+func createTxTag(dir, prefix string) string {
 	t := time.Now().UnixNano()
 	tagName := fmt.Sprintf("strata-tx-%s-%d", prefix, t)
-	exec.Command("git", "tag", tagName).Run() // Ignoring error
+	gitIn(dir, "tag", tagName).Run() // Ignoring error
 	return tagName
 }
 
-func revertToTag(tag string) {
+func revertToTag(dir, tag string) {
 	// revert HEAD to that tag
-	cmd := exec.Command("git", "reset", "--hard", tag)
+	cmd := gitIn(dir, "reset", "--hard", tag)
 	cmd.Run() // ignore errors (we do best effort)
 }
 
+// gitIn prepares a git command run in dir, or in the current directory when dir is empty.
+func gitIn(dir string, args ...string) *exec.Cmd {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	return cmd
+}
+
+// isCurrentWorktree reports whether dir is the worktree git runs in.
+func isCurrentWorktree(dir string) bool {
+	top, err := TopLevel()
+	return err == nil && filepath.Clean(top) == filepath.Clean(dir)
+}
+
 func cleanupTxTag(tag string) {
 	// remove the tag
 	exec.Command("git", "tag", "-d", tag).Run()
@@ -105,7 +159,7 @@ func checkoutBranch(branch string) error {
 func PushCurrentBranch() error {
 	if err := ensureCleanWorkingTree(); err != nil {
 		// We allow pushing with uncommitted changes in Git, but let's be strict here to avoid partial pushes
-		return fmt.Errorf("cannot push with uncommitted changes: %v", err)
+		return fmt.Errorf("cannot push with uncommitted changes: %w", err)
 	}
 	cmd := exec.Command("git", "push", "-u", "origin", "HEAD")
 	out, err := cmd.CombinedOutput()
@@ -117,78 +171,269 @@ func PushCurrentBranch() error {
 
 // RebaseBranch performs an interactive rebase with fallback to manual conflict resolution prompt
 func RebaseBranch(branch, onto string) error {
+	if IsAncestor(onto, branch) {
+		// already on top of onto; git would leave it as it is, so don't touch the worktree
+		logs.Debug("'%s' already contains '%s'; nothing to rebase", branch, onto)
+		return nil
+	}
+	return rebase(branch, fmt.Sprintf("rebase %s onto %s", branch, onto), onto)
+}
+
+// RebaseOnto moves the commits of branch that follow upstream onto newBase
+// (`git rebase --onto`). Use it when upstream was rewritten or squash-merged, so its
+// old commits are not replayed a second time.
+func RebaseOnto(branch, newBase, upstream string) error {
+	return rebase(branch, fmt.Sprintf("rebase %s onto %s (from %s)", branch, newBase, upstream), "--onto", newBase, upstream)
+}
+
+// rebase runs `git rebase args` on branch where it is checked out. The current branch is
+// rebased in place, moving the working files with it, and a branch checked out in another
+// worktree is rebased there, since git won't check it out twice. Any other branch is
+// rebased in a throwaway worktree, so the user's working copy, uncommitted changes
+// included, is never touched; conflicts are resolved in that worktree.
+func rebase(branch, desc string, args ...string) error {
+	dir := WorktreeFor(branch)
+	switch {
+	case dir != "" && isCurrentWorktree(dir):
+		return rebaseIn("", branch, desc, args...)
+	case dir != "":
+		logs.Info("'%s' is checked out in %s; rebasing it there", branch, dir)
+		return rebaseIn(dir, branch, desc, args...)
+	}
+
+	tmp, err := os.MkdirTemp("", "strata-rebase-")
+	if err != nil {
+		return fmt.Errorf("failed to create a directory for %s: %v", desc, err)
+	}
+	dir = filepath.Join(tmp, "worktree")
+	if err := CheckoutWorktree(dir, branch); err != nil {
+		os.RemoveAll(tmp)
+		return err
+	}
+	logs.Debug("Running %s in temporary worktree %s", desc, dir)
+	err = rebaseIn(dir, branch, desc, args...)
+	if err != nil && rebaseInProgress(dir) {
+		// the worktree holds the stopped rebase, so it stays until the user is done with it
+		return fmt.Errorf("%w; then remove the worktree with `git worktree remove %s`", err, dir)
+	}
+	if rmErr := RemoveWorktree(dir); rmErr != nil {
+		logs.Warn("%v", rmErr)
+	}
+	os.RemoveAll(tmp)
+	return err
+}
+
+// rebaseIn rebases branch in the worktree at dir, or checks it out and rebases it in the
+// current worktree when dir is empty.
+func rebaseIn(dir, branch, desc string, args ...string) error {
 	// Create a save point
-	txTag := createTxTag("rebase")
+	txTag := createTxTag(dir, "rebase")
 	defer cleanupTxTag(txTag)
 
-	if err := ensureCleanWorkingTree(); err != nil {
-		revertToTag(txTag)
+	// nothing has changed yet, and resetting would throw the uncommitted changes away
+	if err := ensureCleanWorkingTreeIn(dir); err != nil {
 		return err
 	}
 	// checkout the target branch
-	if err := checkoutBranch(branch); err != nil {
-		revertToTag(txTag)
-		return err
+	if dir == "" {
+		if err := checkoutBranch(branch); err != nil {
+			revertToTag(dir, txTag)
+			return err
+		}
 	}
 
-	cmd := exec.Command("git", "rebase", onto)
+	cmd := gitRerereIn(dir, append([]string{"rebase"}, args...)...)
 	out, err := cmd.CombinedOutput()
 	if err != nil {
 		if strings.Contains(string(out), "CONFLICT") {
+			// conflicts resolved before, e.g. in the layer below, are resolved the same way
+			done, rErr := reuseResolutions(dir, branch, string(out))
+			if rErr != nil {
+				gitRerereIn(dir, "rebase", "--abort").Run()
+				revertToTag(dir, txTag)
+				return rErr
+			}
+			if done {
+				return nil
+			}
 			// Handle conflict
-			cErr := handleRebaseConflict()
+			cErr := handleRebaseConflict(dir, branch)
 			if cErr != nil {
+				if rebaseInProgress(dir) {
+					// resetting would wreck the stopped rebase, which the user can still finish
+					return unfinishedRebase(dir, cErr)
+				}
 				// user might abort
-				revertToTag(txTag)
+				revertToTag(dir, txTag)
 				return cErr
 			}
 			// If the user successfully continues, it's presumably fine
 			return nil
 		}
 		// general fail
-		exec.Command("git", "rebase", "--abort").Run()
-		revertToTag(txTag)
-		return fmt.Errorf("rebase %s onto %s failed: %v\n%s", branch, onto, err, string(out))
+		gitRerereIn(dir, "rebase", "--abort").Run()
+		revertToTag(dir, txTag)
+		return fmt.Errorf("%s failed: %v\n%s", desc, err, string(out))
+	}
+	return nil
+}
+
+// FastForwardBranch moves branch to ref if that is a fast-forward. The checked-out
+// branch is updated with `git merge --ff-only` so the working tree follows.
+func FastForwardBranch(branch, ref string) error {
+	var cmd *exec.Cmd
+	if headBranch() == branch {
+		cmd = exec.Command("git", "merge", "--ff-only", ref)
+	} else {
+		if !IsAncestor(branch, ref) {
+			return fmt.Errorf("cannot fast-forward '%s' to '%s': branches have diverged", branch, ref)
+		}
+		cmd = exec.Command("git", "update-ref", "refs/heads/"+branch, ref)
+	}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("fast-forward %s to %s failed: %v\n%s", branch, ref, err, string(out))
 	}
 	return nil
 }
 
-func handleRebaseConflict() error {
+// headBranch returns the branch checked out in the current worktree, or "" when HEAD is
+// detached.
+func headBranch() string {
+	head, _ := exec.Command("git", "symbolic-ref", "--quiet", "--short", "HEAD").Output()
+	return strings.TrimSpace(string(head))
+}
+
+// FetchPrune fetches origin and drops remote-tracking branches that were deleted there,
+// e.g. head branches removed after their PR merged.
+func FetchPrune() error {
+	cmd := exec.Command("git", "fetch", "--prune", "origin")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git fetch --prune origin failed: %v\n%s", err, string(out))
+	}
+	return nil
+}
+
+// FetchBranches fetches the named branches from origin into their remote-tracking refs,
+// which single-branch CI checkouts lack.
+func FetchBranches(branches []string) error {
+	args := []string{"fetch", "--no-tags", "origin"}
+	for _, br := range branches {
+		args = append(args, fmt.Sprintf("+refs/heads/%s:refs/remotes/origin/%s", br, br))
+	}
+	out, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git fetch origin %s failed: %v\n%s", strings.Join(branches, " "), err, string(out))
+	}
+	return nil
+}
+
+// handleRebaseConflict resolves a stopped rebase of branch in dir (the current worktree
+// when empty) as auto_conflict_resolution says.
+func handleRebaseConflict(dir, branch string) error {
 	policy := config.GetConfigValue("auto_conflict_resolution")
 	switch policy {
-	case "ours":
-		// automatically choose ours for conflicting files
-		exec.Command("git", "checkout", "--ours", ".").Run()
-		exec.Command("git", "add", ".").Run()
-		exec.Command("git", "rebase", "--continue").Run()
-		// we'd still check if more conflicts remain.
-		return nil
-	case "theirs":
-		exec.Command("git", "checkout", "--theirs", ".").Run()
-		exec.Command("git", "add", ".").Run()
-		exec.Command("git", "rebase", "--continue").Run()
-		return nil
+	case "ours", "theirs":
+		return resolveRebaseConflicts(dir, branch, policy)
 	default:
-		return handleRebaseConflictManually()
+		return handleRebaseConflictManually(dir, branch)
+	}
+}
+
+// resolveRebaseConflicts settles the conflicts of the rebase of branch stopped in dir by
+// taking side ("ours" or "theirs") of every conflicting file, commit after commit, until
+// the rebase finishes. When that doesn't settle a conflict or git won't continue, it fails
+// and leaves the rebase stopped.
+func resolveRebaseConflicts(dir, branch, side string) error {
+	for {
+		for _, f := range unmergedFiles(dir) {
+			if err := gitIn(dir, "checkout", "--"+side, "--", f).Run(); err != nil {
+				// the file was deleted on that side
+				gitIn(dir, "rm", "--quiet", "--", f).Run()
+				continue
+			}
+			gitIn(dir, "add", "--", f).Run()
+		}
+		if left := unmergedFiles(dir); len(left) > 0 {
+			return errs.Conflict("could not resolve %s in '%s' by taking %s", quoteList(left), branch, side)
+		}
+		recordResolutions(dir)
+		out, err := gitRerereIn(dir, "rebase", "--continue").CombinedOutput()
+		if err != nil {
+			if !strings.Contains(string(out), "CONFLICT") {
+				return errs.Conflict("rebase --continue of '%s' failed after taking %s: %v\n%s", branch, side, err, string(out))
+			}
+			// the next commit conflicts too; recorded resolutions go first
+			done, rErr := reuseResolutions(dir, branch, string(out))
+			if rErr != nil {
+				return rErr
+			}
+			if done {
+				return nil
+			}
+			continue
+		}
+		if rebaseInProgress(dir) {
+			return errs.Conflict("rebase of '%s' stopped after taking %s:\n%s", branch, side, string(out))
+		}
+		return nil
 	}
 }
 
-// handleRebaseConflictManually prompts user to manually fix conflicts, then continue or abort
-func handleRebaseConflictManually() error {
-	fmt.Println("Rebase conflict detected. Please resolve conflicts in your editor.")
+// rebaseInProgress reports whether a rebase is stopped in the worktree at dir (the current
+// one when empty).
+func rebaseInProgress(dir string) bool {
+	for _, name := range []string{"rebase-merge", "rebase-apply"} {
+		out, err := gitIn(dir, "rev-parse", "--path-format=absolute", "--git-path", name).Output()
+		if err != nil {
+			continue
+		}
+		if _, err := os.Stat(strings.TrimSpace(string(out))); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// unfinishedRebase adds to err how to finish or abort the rebase left stopped in dir.
+func unfinishedRebase(dir string, err error) error {
+	cmd := "git"
+	if dir != "" {
+		cmd = "git -C " + dir
+	}
+	return fmt.Errorf("%w\nThe rebase is left stopped; resolve the conflicts and run `%s rebase --continue`, or `%s rebase --abort`", err, cmd, cmd)
+}
+
+// handleRebaseConflictManually prompts user to manually fix conflicts, then continue or abort.
+// The resolutions are recorded, so the same conflicts in other layers resolve themselves.
+func handleRebaseConflictManually(dir, branch string) error {
+	if dir != "" {
+		fmt.Printf("Rebase conflict detected in %s. Please resolve conflicts in your editor.\n", dir)
+	} else {
+		fmt.Println("Rebase conflict detected. Please resolve conflicts in your editor.")
+	}
 	for {
 		fmt.Print("Type 'continue' when conflicts are resolved, or 'abort' to cancel rebase: ")
 		scanner := bufio.NewScanner(os.Stdin)
 		if !scanner.Scan() {
-			return fmt.Errorf("no input; rebase cannot proceed")
+			return errs.Conflict("no input; rebase cannot proceed")
 		}
 		ans := scanner.Text()
 		switch ans {
 		case "continue":
-			cmd := exec.Command("git", "rebase", "--continue")
+			recordResolutions(dir)
+			cmd := gitRerereIn(dir, "rebase", "--continue")
 			out, err := cmd.CombinedOutput()
 			if err != nil {
 				if strings.Contains(string(out), "CONFLICT") {
+					done, rErr := reuseResolutions(dir, branch, string(out))
+					if rErr != nil {
+						return rErr
+					}
+					if done {
+						return nil
+					}
 					fmt.Println("Still conflicts remain. Please resolve and type 'continue' again.")
 					continue
 				} else {
@@ -198,8 +443,8 @@ func handleRebaseConflictManually() error {
 			// success
 			return nil
 		case "abort":
-			exec.Command("git", "rebase", "--abort").Run()
-			return fmt.Errorf("rebase aborted by user")
+			gitRerereIn(dir, "rebase", "--abort").Run()
+			return errs.Conflict("rebase aborted by user")
 		default:
 			fmt.Println("Unknown input. Type 'continue' or 'abort'.")
 		}
@@ -208,26 +453,42 @@ func handleRebaseConflictManually() error {
 
 // ensureCleanWorkingTree checks for uncommitted changes
 func ensureCleanWorkingTree() error {
-	cmd := exec.Command("git", "status", "--porcelain")
+	return ensureCleanWorkingTreeIn("")
+}
+
+// ensureCleanWorkingTreeIn checks the worktree at dir (the current one when empty)
+func ensureCleanWorkingTreeIn(dir string) error {
+	cmd := gitIn(dir, "status", "--porcelain")
 	out, err := cmd.CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("failed to check git status: %v\n%s", err, string(out))
 	}
 	status := strings.TrimSpace(string(out))
 	if status != "" {
-		return fmt.Errorf("working tree not clean; commit or stash changes first:\n%s", status)
+		return errs.DirtyTree("working tree not clean; commit or stash changes first:\n%s", status)
 	}
 	return nil
 }
 
 // PullBranch merges remote changes into the current branch
 func PullBranch() error {
-	cmd := exec.Command("git", "pull", "--rebase")
+	cmd := gitRerereIn("", "pull", "--rebase")
 	out, err := cmd.CombinedOutput()
 	if err != nil {
 		if strings.Contains(string(out), "CONFLICT") {
+			branch := headBranch()
+			done, rErr := reuseResolutions("", branch, string(out))
+			if rErr != nil {
+				return rErr
+			}
+			if done {
+				return nil
+			}
 			// handle similarly to handleRebaseConflict
-			if e := handleRebaseConflict(); e != nil {
+			if e := handleRebaseConflict("", branch); e != nil {
+				if rebaseInProgress("") {
+					return unfinishedRebase("", e)
+				}
 				return e
 			}
 			return nil
@@ -247,19 +508,29 @@ func FetchAll() error {
 	return nil
 }
 
+// SyncWithRemote brings branch up to date with origin. Where branch is checked out it is
+// pulled (with --rebase in the current worktree); elsewhere it is only fast-forwarded, so
+// nothing gets checked out.
 func SyncWithRemote(branch string) error {
-	// checkout branch
-	if err := checkoutBranch(branch); err != nil {
-		return err
-	}
-	// fetch + pull
 	if err := FetchAll(); err != nil {
 		return err
 	}
-	if err := PullBranch(); err != nil {
-		return err
+	dir := WorktreeFor(branch)
+	if dir != "" && isCurrentWorktree(dir) {
+		return PullBranch()
 	}
-	return nil
+	remote := "refs/remotes/origin/" + branch
+	if !RefExists(remote) || IsAncestor(remote, "refs/heads/"+branch) {
+		return nil
+	}
+	if dir != "" {
+		out, err := gitIn(dir, "merge", "--ff-only", remote).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("fast-forward %s in %s failed: %v\n%s", branch, dir, err, string(out))
+		}
+		return nil
+	}
+	return FastForwardBranch(branch, remote)
 }
 
 // Stash/unstash might be used if we want to preserve user changes during certain operations
@@ -0,0 +1,187 @@
+package git
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Read-only queries used to describe branches. None of these touch the working tree.
+
+// Commit is a single commit as reported by `git log`.
+type Commit struct {
+	Hash    string
+	Subject string
+	Author  string
+	Body    string
+}
+
+// CommitsBetween lists the commits reachable from head but not from base, newest first.
+func CommitsBetween(base, head string) ([]Commit, error) {
+	cmd := exec.Command("git", "log", "--format=%H%x1f%s%x1f%an%x1f%b%x1e", base+".."+head)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("git log %s..%s failed: %v\n%s", base, head, err, string(out))
+	}
+	commits := []Commit{}
+	for _, record := range strings.Split(string(out), "\x1e") {
+		record = strings.TrimLeft(record, "\n")
+		if record == "" {
+			continue
+		}
+		parts := strings.SplitN(record, "\x1f", 4)
+		if len(parts) != 4 {
+			continue
+		}
+		commits = append(commits, Commit{
+			Hash:    parts[0],
+			Subject: parts[1],
+			Author:  parts[2],
+			Body:    strings.TrimSpace(parts[3]),
+		})
+	}
+	return commits, nil
+}
+
+// AheadBehind reports how many commits head has that base lacks (ahead) and vice versa (behind).
+func AheadBehind(base, head string) (int, int, error) {
+	cmd := exec.Command("git", "rev-list", "--left-right", "--count", base+"..."+head)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return 0, 0, fmt.Errorf("git rev-list %s...%s failed: %v\n%s", base, head, err, string(out))
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("unexpected rev-list output: %q", string(out))
+	}
+	behind, _ := strconv.Atoi(fields[0])
+	ahead, _ := strconv.Atoi(fields[1])
+	return ahead, behind, nil
+}
+
+// RefExists returns true if ref resolves to a commit.
+func RefExists(ref string) bool {
+	return exec.Command("git", "rev-parse", "--verify", "--quiet", ref+"^{commit}").Run() == nil
+}
+
+// RevParse resolves ref to a full commit hash.
+func RevParse(ref string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--verify", ref+"^{commit}")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("cannot resolve '%s': %v\n%s", ref, err, string(out))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// MergeBase returns the best common ancestor of a and b.
+func MergeBase(a, b string) (string, error) {
+	cmd := exec.Command("git", "merge-base", a, b)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git merge-base %s %s failed: %v\n%s", a, b, err, string(out))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// DiffStat returns `git diff --stat` for the changes head introduces on top of base.
+func DiffStat(base, head string) (string, error) {
+	cmd := exec.Command("git", "diff", "--stat", base+"..."+head)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git diff --stat %s...%s failed: %v\n%s", base, head, err, string(out))
+	}
+	return strings.TrimRight(string(out), "\n"), nil
+}
+
+// FileChange is one file's line counts in a diff. Binary files count as zero lines.
+type FileChange struct {
+	Path    string
+	Added   int
+	Deleted int
+}
+
+// DiffNumstat lists the files head changes on top of base, with added and deleted lines.
+func DiffNumstat(base, head string) ([]FileChange, error) {
+	cmd := exec.Command("git", "diff", "--numstat", "--no-renames", base+"..."+head)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("git diff --numstat %s...%s failed: %v\n%s", base, head, err, string(out))
+	}
+	changes := []FileChange{}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		// binary files report "-" for both counts
+		added, _ := strconv.Atoi(fields[0])
+		deleted, _ := strconv.Atoi(fields[1])
+		changes = append(changes, FileChange{Path: fields[2], Added: added, Deleted: deleted})
+	}
+	return changes, nil
+}
+
+// MergeCommits lists the merge commits reachable from head but not from base.
+func MergeCommits(base, head string) ([]string, error) {
+	cmd := exec.Command("git", "rev-list", "--merges", base+".."+head)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("git rev-list --merges %s..%s failed: %v\n%s", base, head, err, string(out))
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// RefSnapshot returns a string that changes whenever a local or remote branch, or HEAD, moves.
+func RefSnapshot() (string, error) {
+	cmd := exec.Command("git", "for-each-ref", "--format=%(refname) %(objectname)", "refs/heads", "refs/remotes")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git for-each-ref failed: %v\n%s", err, string(out))
+	}
+	head, _ := exec.Command("git", "rev-parse", "--symbolic-full-name", "HEAD").Output()
+	return string(out) + string(head), nil
+}
+
+// RemoteDefaultBranch returns the branch origin/HEAD points at (e.g. "main"), or "" if it isn't set.
+// Run `git remote set-head origin --auto` to populate it in older clones.
+func RemoteDefaultBranch() string {
+	out, err := exec.Command("git", "symbolic-ref", "--quiet", "--short", "refs/remotes/origin/HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.TrimSpace(string(out)), "origin/")
+}
+
+// RemoteURL returns the fetch URL of the named remote.
+func RemoteURL(name string) (string, error) {
+	out, err := exec.Command("git", "remote", "get-url", name).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git remote get-url %s failed: %v\n%s", name, err, string(out))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// IsAncestor reports whether ancestor is reachable from descendant.
+func IsAncestor(ancestor, descendant string) bool {
+	return exec.Command("git", "merge-base", "--is-ancestor", ancestor, descendant).Run() == nil
+}
+
+// TopLevel returns the absolute path of the working tree's root.
+func TopLevel() (string, error) {
+	out, err := exec.Command("git", "rev-parse", "--show-toplevel").CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse --show-toplevel failed: %v\n%s", err, string(out))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// CommonDir returns the absolute path of the repository's .git directory, shared by all worktrees.
+func CommonDir() (string, error) {
+	out, err := exec.Command("git", "rev-parse", "--path-format=absolute", "--git-common-dir").CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse --git-common-dir failed: %v\n%s", err, string(out))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,74 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// MergeTree merges head into base in memory, without touching the index or the working
+// tree, and returns the files that would conflict. It needs git 2.38 or newer.
+func MergeTree(base, head string) ([]string, error) {
+	_, files, err := MergeTreeWrite(base, head)
+	return files, err
+}
+
+// MergeTreeWrite is MergeTree that also returns the merged tree. The tree is written to
+// the object database but no ref points to it; with conflicts it holds conflict markers.
+func MergeTreeWrite(base, head string) (string, []string, error) {
+	out, err := exec.Command("git", "merge-tree", "--write-tree", "--name-only", "--no-messages", base, head).Output()
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		// exit code 1 means the merge has conflicts; they follow the tree id, one per line
+		seen := map[string]bool{}
+		files := []string{}
+		for _, f := range lines[1:] {
+			if f != "" && !seen[f] {
+				seen[f] = true
+				files = append(files, f)
+			}
+		}
+		return lines[0], files, nil
+	}
+	if err != nil {
+		msg := ""
+		if errors.As(err, &exitErr) {
+			msg = strings.TrimSpace(string(exitErr.Stderr))
+		}
+		return "", nil, fmt.Errorf("git merge-tree %s %s failed (git 2.38 or newer is required): %v\n%s", base, head, err, msg)
+	}
+	return lines[0], nil, nil
+}
+
+// CommitTree records tree as a commit with the given parents and returns its hash. No ref
+// is updated, so the commit only lives until the next gc unless something points to it.
+func CommitTree(tree, message string, parents ...string) (string, error) {
+	args := []string{"commit-tree", tree, "-m", message}
+	for _, p := range parents {
+		args = append(args, "-p", p)
+	}
+	cmd := exec.Command("git", args...)
+	// don't depend on user.name/user.email, which CI clones often lack
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=strata", "GIT_AUTHOR_EMAIL=strata@localhost",
+		"GIT_COMMITTER_NAME=strata", "GIT_COMMITTER_EMAIL=strata@localhost")
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git commit-tree %s failed: %v", tree, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// ReplayCount returns how many commits `git rebase upstream` would replay from branch:
+// those upstream lacks, minus merges and patches upstream already has.
+func ReplayCount(upstream, branch string) (int, error) {
+	out, err := exec.Command("git", "rev-list", "--count", "--right-only", "--cherry-pick", "--no-merges", upstream+"..."+branch).CombinedOutput()
+	if err != nil {
+		return 0, fmt.Errorf("git rev-list %s...%s failed: %v\n%s", upstream, branch, err, string(out))
+	}
+	return strconv.Atoi(strings.TrimSpace(string(out)))
+}
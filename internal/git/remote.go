@@ -0,0 +1,71 @@
+package git
+
+import (
+	"fmt"
+	"net/url"
+	"os/exec"
+	"strings"
+)
+
+// Remote identifies a repository on a hosting service. Owner may contain slashes (GitLab subgroups).
+type Remote struct {
+	Host  string
+	Owner string
+	Repo  string
+}
+
+// RemoteBranches lists the branches on origin, asking the remote rather than reading
+// remote-tracking refs.
+func RemoteBranches() ([]string, error) {
+	out, err := exec.Command("git", "ls-remote", "--heads", "origin").CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("git ls-remote --heads origin failed: %v\n%s", err, string(out))
+	}
+	branches := []string{}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 {
+			branches = append(branches, strings.TrimPrefix(fields[1], "refs/heads/"))
+		}
+	}
+	return branches, nil
+}
+
+// OriginRemote parses the origin remote of the current repository.
+func OriginRemote() (Remote, error) {
+	raw, err := RemoteURL("origin")
+	if err != nil {
+		return Remote{}, err
+	}
+	r, ok := ParseRemote(raw)
+	if !ok {
+		return Remote{}, fmt.Errorf("cannot determine owner/repo from origin URL '%s'", raw)
+	}
+	return r, nil
+}
+
+// ParseRemote understands https://host/owner/repo(.git), ssh://git@host[:port]/owner/repo
+// and the scp-like git@host:owner/repo forms.
+func ParseRemote(raw string) (Remote, bool) {
+	raw = strings.TrimSpace(raw)
+	var host, path string
+	if strings.Contains(raw, "://") {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return Remote{}, false
+		}
+		host, path = u.Hostname(), u.Path
+	} else if at := strings.Index(raw, "@"); at >= 0 && strings.Contains(raw[at:], ":") {
+		rest := raw[at+1:]
+		colon := strings.Index(rest, ":")
+		host, path = rest[:colon], rest[colon+1:]
+	} else {
+		return Remote{}, false
+	}
+
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	slash := strings.LastIndex(path, "/")
+	if host == "" || slash <= 0 || slash == len(path)-1 {
+		return Remote{}, false
+	}
+	return Remote{Host: host, Owner: path[:slash], Repo: path[slash+1:]}, true
+}
@@ -0,0 +1,290 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strata/internal/config"
+	"strata/internal/logs"
+	"strings"
+	"time"
+)
+
+// RerereConfigKey turns off the reuse of recorded conflict resolutions when set to "false".
+// Strata enables git rerere for the rebases and merges it runs, without changing the
+// repository's git configuration.
+const RerereConfigKey = "rerere"
+
+// rerereIndexFile maps the resolutions in rr-cache to the file and layer they were recorded
+// for, which git doesn't keep. It sits next to the other clone-local state in .git/strata.
+const rerereIndexFile = "rerere_index"
+
+// hexPattern matches the names of the rr-cache entries.
+var hexPattern = regexp.MustCompile(`^[0-9a-f]{40,64}$`)
+
+// reusedPattern matches what rerere prints for a conflict it resolved from a recording.
+var reusedPattern = regexp.MustCompile(`(?m)^(?:Staged|Resolved) '(.+)' using previous resolution\.$`)
+
+// Resolution is a conflict resolution recorded by git rerere.
+type Resolution struct {
+	// ID names the conflict in rr-cache; it is a hash of the conflicting hunks.
+	ID string
+	// Path and Branch are the file and layer the conflict was first seen in, when Strata saw it.
+	Path   string
+	Branch string
+	// Resolved is false while the conflict is recorded but its resolution isn't yet.
+	Resolved bool
+	// RecordedAt is when the conflict was first seen.
+	RecordedAt time.Time
+	// LastUsed is when the resolution was recorded or last replayed; git touches it on
+	// every reuse, and `git gc` forgets resolutions unused for 60 days.
+	LastUsed time.Time
+}
+
+// RerereEnabled reports whether Strata's rebases and merges reuse recorded resolutions.
+func RerereEnabled() bool {
+	return config.GetConfigValue(RerereConfigKey) != "false"
+}
+
+// gitRerereIn is gitIn for commands that may stop on conflicts. With rerere on, git records
+// how each conflict is resolved and resolves (and stages) the same conflict the same way
+// next time. The recordings live in the common git dir, so every worktree shares them.
+// Commit messages are taken as git proposes them, since nobody could type into an editor.
+func gitRerereIn(dir string, args ...string) *exec.Cmd {
+	if RerereEnabled() {
+		args = append([]string{"-c", "rerere.enabled=true", "-c", "rerere.autoUpdate=true"}, args...)
+	}
+	cmd := gitIn(dir, args...)
+	cmd.Env = append(os.Environ(), "GIT_EDITOR=true")
+	return cmd
+}
+
+// reuseResolutions follows up on a rebase of branch in dir (the current worktree when
+// empty) that stopped on conflicts, with out being what git printed. As long as rerere
+// resolved every conflict of the stopped commit from an earlier recording, it continues
+// the rebase. It reports whether the rebase finished; if not, it stopped on conflicts
+// that need a hand.
+func reuseResolutions(dir, branch, out string) (bool, error) {
+	for {
+		if len(reportReused(dir, branch, out)) == 0 || len(unmergedFiles(dir)) > 0 {
+			return false, nil
+		}
+		o, err := gitRerereIn(dir, "rebase", "--continue").CombinedOutput()
+		if err == nil {
+			return true, nil
+		}
+		if !strings.Contains(string(o), "CONFLICT") {
+			return false, fmt.Errorf("rebase --continue failed: %v\n%s", err, string(o))
+		}
+		out = string(o)
+	}
+}
+
+// reportReused tells the user which conflicts of branch git rerere resolved from earlier
+// recordings, as seen in the git output out, notes the conflicts in the index and returns
+// the resolved files.
+func reportReused(dir, branch, out string) []string {
+	if !RerereEnabled() {
+		return nil
+	}
+	paths := []string{}
+	for _, m := range reusedPattern.FindAllStringSubmatch(out, -1) {
+		paths = append(paths, m[1])
+	}
+	noteConflicts(dir, branch)
+	if len(paths) > 0 {
+		fmt.Printf("Reused the recorded resolution of %s in '%s'.\n", quoteList(paths), branch)
+		logs.Info("Resolved %s in '%s' with recorded resolutions", strings.Join(paths, ", "), branch)
+	}
+	return paths
+}
+
+// recordResolutions has rerere record how the conflicts in dir were resolved. `rebase
+// --continue` does that too, except when the resolution empties the commit and the commit
+// is dropped, which is how the old commits of a rewritten parent usually end up.
+func recordResolutions(dir string) {
+	if RerereEnabled() {
+		gitRerereIn(dir, "rerere").Run()
+	}
+}
+
+// unmergedFiles lists the files of the worktree at dir that still have conflicts.
+func unmergedFiles(dir string) []string {
+	out, err := gitIn(dir, "diff", "--name-only", "--diff-filter=U").Output()
+	if err != nil {
+		return nil
+	}
+	return strings.Fields(string(out))
+}
+
+func quoteList(paths []string) string {
+	quoted := make([]string, len(paths))
+	for i, p := range paths {
+		quoted[i] = "'" + p + "'"
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// noteConflicts records in the index which file and layer the conflicts rerere is tracking
+// in dir belong to. Git lists them in MERGE_RR while a rebase or merge is stopped, until
+// they are resolved. Failures only cost the listing its file names.
+func noteConflicts(dir, branch string) {
+	out, err := gitIn(dir, "rev-parse", "--path-format=absolute", "--git-path", "MERGE_RR").Output()
+	if err != nil {
+		return
+	}
+	data, err := os.ReadFile(strings.TrimSpace(string(out)))
+	if err != nil || len(data) == 0 {
+		return
+	}
+	index := loadRerereIndex()
+	for _, entry := range bytes.Split(data, []byte{0}) {
+		id, path, ok := strings.Cut(string(entry), "\t")
+		if !ok {
+			continue
+		}
+		// newer gits add a variant number for conflicts that were resolved in several ways
+		id, _, _ = strings.Cut(id, ".")
+		if _, seen := index[id]; !seen {
+			index[id] = Resolution{ID: id, Path: path, Branch: branch}
+		}
+	}
+	if err := saveRerereIndex(index); err != nil {
+		logs.Warn("%v", err)
+	}
+}
+
+func rerereCacheDir() (string, error) {
+	dir, err := CommonDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "rr-cache"), nil
+}
+
+func rerereIndexPath() (string, error) {
+	dir, err := CommonDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "strata", rerereIndexFile), nil
+}
+
+// loadRerereIndex reads the index, one "id<TAB>branch<TAB>path" line per conflict, keyed
+// by ID.
+func loadRerereIndex() map[string]Resolution {
+	index := map[string]Resolution{}
+	p, err := rerereIndexPath()
+	if err != nil {
+		return index
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return index
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		index[fields[0]] = Resolution{ID: fields[0], Branch: fields[1], Path: fields[2]}
+	}
+	return index
+}
+
+func saveRerereIndex(index map[string]Resolution) error {
+	p, err := rerereIndexPath()
+	if err != nil {
+		return err
+	}
+	ids := make([]string, 0, len(index))
+	for id := range index {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	cache, err := rerereCacheDir()
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	for _, id := range ids {
+		// drop what `git rerere gc` or ForgetResolutions removed from the cache
+		if _, err := os.Stat(filepath.Join(cache, id)); err != nil {
+			continue
+		}
+		r := index[id]
+		fmt.Fprintf(&b, "%s\t%s\t%s\n", id, r.Branch, r.Path)
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %v", filepath.Dir(p), err)
+	}
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %v", tmp, err)
+	}
+	return os.Rename(tmp, p)
+}
+
+// Resolutions lists the conflict resolutions git rerere has recorded for the repository,
+// newest first. Recordings made outside Strata have no Path or Branch.
+func Resolutions() ([]Resolution, error) {
+	cache, err := rerereCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(cache)
+	if os.IsNotExist(err) {
+		return []Resolution{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", cache, err)
+	}
+	index := loadRerereIndex()
+	list := []Resolution{}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		pre, err := os.Stat(filepath.Join(cache, e.Name(), "preimage"))
+		if err != nil || !hexPattern.MatchString(e.Name()) {
+			continue
+		}
+		r, ok := index[e.Name()]
+		if !ok {
+			r = Resolution{ID: e.Name()}
+		}
+		r.RecordedAt = pre.ModTime()
+		// the postimage is written once the conflict is resolved and touched whenever it is reused
+		if post, err := os.Stat(filepath.Join(cache, e.Name(), "postimage")); err == nil {
+			r.Resolved = true
+			r.LastUsed = post.ModTime()
+		}
+		list = append(list, r)
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].RecordedAt.After(list[j].RecordedAt) })
+	return list, nil
+}
+
+// ForgetResolutions deletes the recorded resolutions with the given IDs, so their
+// conflicts stop being resolved automatically and are recorded afresh next time.
+func ForgetResolutions(ids []string) error {
+	cache, err := rerereCacheDir()
+	if err != nil {
+		return err
+	}
+	index := loadRerereIndex()
+	for _, id := range ids {
+		if id == "" || strings.ContainsAny(id, `/\.`) {
+			return fmt.Errorf("invalid resolution id '%s'", id)
+		}
+		if err := os.RemoveAll(filepath.Join(cache, id)); err != nil {
+			return fmt.Errorf("failed to forget resolution %s: %v", id, err)
+		}
+		delete(index, id)
+	}
+	return saveRerereIndex(index)
+}
@@ -0,0 +1,131 @@
+package git
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// AddWorktree checks out ref, detached, in a new worktree at path.
+func AddWorktree(path, ref string) error {
+	out, err := exec.Command("git", "worktree", "add", "--detach", path, ref).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git worktree add %s failed: %v\n%s", path, err, string(out))
+	}
+	return nil
+}
+
+// RemoveWorktree deletes the worktree at path, discarding any changes made in it.
+func RemoveWorktree(path string) error {
+	out, err := exec.Command("git", "worktree", "remove", "--force", path).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git worktree remove %s failed: %v\n%s", path, err, string(out))
+	}
+	return nil
+}
+
+// ResetWorktree switches the worktree at path to ref, detached, and removes everything the
+// previous checkout left behind, ignored files such as build output included, so nothing
+// built for one commit leaks into the next.
+func ResetWorktree(path, ref string) error {
+	out, err := exec.Command("git", "-C", path, "checkout", "--quiet", "--force", "--detach", ref).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git checkout %s in %s failed: %v\n%s", ref, path, err, string(out))
+	}
+	out, err = exec.Command("git", "-C", path, "clean", "-fdxq").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git clean in %s failed: %v\n%s", path, err, string(out))
+	}
+	return nil
+}
+
+// Worktree is one entry of `git worktree list`.
+type Worktree struct {
+	Path string
+	// Branch is the checked out branch, empty when HEAD is detached.
+	Branch string
+	Head   string
+	Bare   bool
+	// Prunable is set when the worktree's directory is gone.
+	Prunable bool
+}
+
+// Worktrees lists the repository's worktrees, the main one first.
+func Worktrees() ([]Worktree, error) {
+	out, err := exec.Command("git", "worktree", "list", "--porcelain").Output()
+	if err != nil {
+		return nil, fmt.Errorf("git worktree list failed: %v", err)
+	}
+	var list []Worktree
+	for _, line := range strings.Split(string(out), "\n") {
+		key, value, _ := strings.Cut(line, " ")
+		if key == "worktree" {
+			list = append(list, Worktree{Path: value})
+			continue
+		}
+		if len(list) == 0 {
+			continue
+		}
+		wt := &list[len(list)-1]
+		switch key {
+		case "HEAD":
+			wt.Head = value
+		case "branch":
+			wt.Branch = strings.TrimPrefix(value, "refs/heads/")
+		case "bare":
+			wt.Bare = true
+		case "prunable":
+			wt.Prunable = true
+		}
+	}
+	return list, nil
+}
+
+// MainWorktree returns the path of the repository's main worktree.
+func MainWorktree() (string, error) {
+	list, err := Worktrees()
+	if err != nil {
+		return "", err
+	}
+	if len(list) == 0 || list[0].Bare {
+		return "", fmt.Errorf("repository has no main worktree")
+	}
+	return list[0].Path, nil
+}
+
+// WorktreeFor returns the path of the worktree that has branch checked out, or "".
+func WorktreeFor(branch string) string {
+	list, err := Worktrees()
+	if err != nil {
+		return ""
+	}
+	for _, wt := range list {
+		if wt.Branch == branch {
+			return wt.Path
+		}
+	}
+	return ""
+}
+
+// PruneWorktrees drops the administrative files of worktrees whose directory is gone.
+func PruneWorktrees() error {
+	out, err := exec.Command("git", "worktree", "prune").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git worktree prune failed: %v\n%s", err, string(out))
+	}
+	return nil
+}
+
+// CheckoutWorktree checks out branch in a new worktree at path.
+func CheckoutWorktree(path, branch string) error {
+	out, err := exec.Command("git", "worktree", "add", path, branch).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git worktree add %s %s failed: %v\n%s", path, branch, err, string(out))
+	}
+	return nil
+}
+
+// WorktreeClean reports whether the worktree at path has no uncommitted changes.
+func WorktreeClean(path string) bool {
+	return ensureCleanWorkingTreeIn(path) == nil
+}
@@ -0,0 +1,184 @@
+package github
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strata/internal/config"
+	"strata/internal/errs"
+	"strata/internal/git"
+	"strata/internal/logs"
+	"strata/internal/restapi"
+	"strings"
+)
+
+// Config keys for the native client. Everything is optional for github.com repos
+// where `gh` is logged in.
+const (
+	TokenConfigKey  = "github_token"
+	APIURLConfigKey = "github_api_url" // e.g. https://ghe.example.com/api/v3
+)
+
+// ErrAlreadyExists is returned when creating a PR for a head branch that already has one.
+var ErrAlreadyExists = errors.New("a pull request already exists for this branch")
+
+// Client talks to the GitHub REST and GraphQL APIs directly, without the gh CLI.
+// BaseURL and GraphQLURL can point at GitHub Enterprise or a local fake server.
+type Client struct {
+	BaseURL    string
+	GraphQLURL string
+	Token      string
+	Owner      string
+	Repo       string
+
+	api *restapi.Client
+}
+
+// NewClient builds a client for explicit endpoints.
+func NewClient(baseURL, graphqlURL, token, owner, repo string) *Client {
+	headers := map[string]string{
+		"Accept":               "application/vnd.github+json",
+		"X-GitHub-Api-Version": "2022-11-28",
+	}
+	if token != "" {
+		headers["Authorization"] = "Bearer " + token
+	}
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		GraphQLURL: graphqlURL,
+		Token:      token,
+		Owner:      owner,
+		Repo:       repo,
+		api:        restapi.New("GitHub", headers, apiError),
+	}
+}
+
+// NewClientFromRepo builds a client for the current repository's origin remote.
+// The API URL comes from github_api_url or is derived from the remote host, and the
+// token from github_token, $GITHUB_TOKEN/$GH_TOKEN or `gh auth token`.
+func NewClientFromRepo() (*Client, error) {
+	remote, err := git.OriginRemote()
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL, graphqlURL := endpointsFor(remote.Host)
+	if custom := config.GetConfigValue(APIURLConfigKey); custom != "" {
+		baseURL = strings.TrimRight(custom, "/")
+		graphqlURL = strings.TrimSuffix(baseURL, "/v3") + "/graphql"
+	}
+
+	token := resolveToken(remote.Host)
+	if token == "" {
+		return nil, errs.Auth("no GitHub token found; set %s, $GITHUB_TOKEN, or run `gh auth login`", TokenConfigKey)
+	}
+	return NewClient(baseURL, graphqlURL, token, remote.Owner, remote.Repo), nil
+}
+
+// endpointsFor maps a git host to its REST and GraphQL endpoints.
+func endpointsFor(host string) (string, string) {
+	if host == "github.com" {
+		return "https://api.github.com", "https://api.github.com/graphql"
+	}
+	return fmt.Sprintf("https://%s/api/v3", host), fmt.Sprintf("https://%s/api/graphql", host)
+}
+
+func resolveToken(host string) string {
+	if t := config.GetConfigValue(TokenConfigKey); t != "" {
+		return t
+	}
+	for _, env := range []string{"GITHUB_TOKEN", "GH_TOKEN"} {
+		if t := os.Getenv(env); t != "" {
+			return t
+		}
+	}
+	out, err := exec.Command("gh", "auth", "token", "--hostname", host).Output()
+	if err != nil {
+		logs.Debug("gh auth token failed: %v", err)
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// APIError is a non-2xx response from GitHub.
+type APIError struct {
+	Status  int
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("GitHub API error %d: %s", e.Status, e.Message)
+}
+
+// do sends a REST request relative to BaseURL and decodes the JSON response into out (if non-nil).
+func (c *Client) do(method, path string, body interface{}, out interface{}) error {
+	return c.send(method, c.BaseURL+path, body, out)
+}
+
+// graphql runs a GraphQL query and decodes its "data" field into out.
+func (c *Client) graphql(query string, variables map[string]interface{}, out interface{}) error {
+	var resp struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	payload := map[string]interface{}{"query": query, "variables": variables}
+	var err error
+	if strings.HasPrefix(strings.TrimSpace(query), "mutation") {
+		err = c.send(http.MethodPost, c.GraphQLURL, payload, &resp)
+	} else {
+		err = c.api.Query(c.GraphQLURL, payload, &resp)
+	}
+	if err != nil {
+		return err
+	}
+	if len(resp.Errors) > 0 {
+		msgs := []string{}
+		for _, e := range resp.Errors {
+			msgs = append(msgs, e.Message)
+		}
+		return fmt.Errorf("GitHub GraphQL error: %s", strings.Join(msgs, "; "))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(resp.Data, out)
+}
+
+// send performs the request, retrying server errors and waiting out short rate limits.
+func (c *Client) send(method, url string, body interface{}, out interface{}) error {
+	return c.api.Send(method, url, body, out)
+}
+
+// apiError turns a failed response into an Auth error or an APIError.
+func apiError(status int, data []byte) error {
+	msg := apiMessage(data)
+	if status == http.StatusUnauthorized {
+		return errs.Auth("GitHub rejected the token: %s", msg)
+	}
+	return &APIError{Status: status, Message: msg}
+}
+
+// apiMessage extracts GitHub's error message, including validation details.
+func apiMessage(data []byte) string {
+	var body struct {
+		Message string `json:"message"`
+		Errors  []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(data, &body); err != nil || body.Message == "" {
+		return strings.TrimSpace(string(data))
+	}
+	msg := body.Message
+	for _, e := range body.Errors {
+		if e.Message != "" {
+			msg += ": " + e.Message
+		}
+	}
+	return msg
+}
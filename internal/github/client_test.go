@@ -0,0 +1,200 @@
+package github
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeGitHub serves the responses in order, one per request, and counts the requests.
+func fakeGitHub(t *testing.T, responses ...func(w http.ResponseWriter)) (*Client, *atomic.Int32) {
+	t.Helper()
+	calls := &atomic.Int32{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1))
+		if n > len(responses) {
+			t.Errorf("unexpected request %d: %s %s", n, r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		responses[n-1](w)
+	}))
+	t.Cleanup(srv.Close)
+	return NewClient(srv.URL, srv.URL+"/graphql", "token", "owner", "repo"), calls
+}
+
+// recordSleeps makes c note the waits it asks for instead of sleeping.
+func recordSleeps(c *Client) *[]time.Duration {
+	waits := []time.Duration{}
+	c.api.Sleep = func(d time.Duration) { waits = append(waits, d) }
+	return &waits
+}
+
+func reply(status int, body string, headers ...string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		for i := 0; i+1 < len(headers); i += 2 {
+			w.Header().Set(headers[i], headers[i+1])
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}
+}
+
+const openPullJSON = `{"data": {"repository": {"b0": {"nodes": [{"number": 7, "state": "OPEN",
+	"headRefName": "feature", "baseRefName": "main"}]}}}}`
+
+// lookUpFeature asks for the open PR of branch feature, which the fake serves as #7.
+func lookUpFeature(c *Client) (PullRequest, error) {
+	prs, err := c.OpenPullRequests([]string{"feature"})
+	return prs["feature"], err
+}
+
+func TestSendRetriesServerErrors(t *testing.T) {
+	c, calls := fakeGitHub(t,
+		reply(http.StatusBadGateway, `{"message": "bad gateway"}`),
+		reply(http.StatusInternalServerError, `{"message": "oops"}`),
+		reply(http.StatusOK, openPullJSON),
+	)
+	waits := recordSleeps(c)
+
+	pr, err := lookUpFeature(c)
+	if err != nil {
+		t.Fatalf("OpenPullRequests: %v", err)
+	}
+	if pr.Number != 7 || pr.HeadRefName != "feature" {
+		t.Errorf("got %+v, want PR #7 for feature", pr)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("made %d requests, want 3", calls.Load())
+	}
+	if want := []time.Duration{time.Second, 2 * time.Second}; !equalWaits(*waits, want) {
+		t.Errorf("backed off %v, want %v", *waits, want)
+	}
+}
+
+func TestSendGivesUpAfterMaxRetries(t *testing.T) {
+	fail := reply(http.StatusServiceUnavailable, `{"message": "unavailable"}`)
+	c, calls := fakeGitHub(t, fail, fail, fail, fail)
+	recordSleeps(c)
+
+	_, err := lookUpFeature(c)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusServiceUnavailable {
+		t.Fatalf("got %v, want a 503 APIError", err)
+	}
+	if int(calls.Load()) != c.api.MaxRetries+1 {
+		t.Errorf("made %d requests, want %d", calls.Load(), c.api.MaxRetries+1)
+	}
+}
+
+func TestSendWaitsOutRateLimits(t *testing.T) {
+	c, calls := fakeGitHub(t,
+		reply(http.StatusTooManyRequests, `{"message": "secondary rate limit"}`, "Retry-After", "3"),
+		reply(http.StatusForbidden, `{"message": "API rate limit exceeded"}`,
+			"X-RateLimit-Remaining", "0", "X-RateLimit-Reset", "0"),
+		reply(http.StatusOK, openPullJSON),
+	)
+	waits := recordSleeps(c)
+
+	if _, err := lookUpFeature(c); err != nil {
+		t.Fatalf("OpenPullRequests: %v", err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("made %d requests, want 3", calls.Load())
+	}
+	// a reset time already past still waits a second
+	if want := []time.Duration{3 * time.Second, time.Second}; !equalWaits(*waits, want) {
+		t.Errorf("waited %v, want %v", *waits, want)
+	}
+}
+
+func TestSendRefusesLongRateLimitWaits(t *testing.T) {
+	reset := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	c, calls := fakeGitHub(t,
+		reply(http.StatusForbidden, `{"message": "API rate limit exceeded"}`,
+			"X-RateLimit-Remaining", "0", "X-RateLimit-Reset", reset),
+	)
+	waits := recordSleeps(c)
+
+	if _, err := lookUpFeature(c); err == nil {
+		t.Fatal("want an error when the rate limit resets in an hour")
+	}
+	if calls.Load() != 1 || len(*waits) != 0 {
+		t.Errorf("made %d requests and waited %v, want 1 request and no wait", calls.Load(), *waits)
+	}
+}
+
+// hangUp drops the connection without answering, as a timed-out request sees it.
+func hangUp(w http.ResponseWriter) {
+	conn, _, err := w.(http.Hijacker).Hijack()
+	if err == nil {
+		conn.Close()
+	}
+}
+
+func TestSendRetriesDroppedConnections(t *testing.T) {
+	c, calls := fakeGitHub(t, hangUp, reply(http.StatusOK, openPullJSON))
+	recordSleeps(c)
+
+	if _, err := lookUpFeature(c); err != nil {
+		t.Fatalf("OpenPullRequests: %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("made %d requests, want 2", calls.Load())
+	}
+}
+
+func TestSendDoesNotRepeatPostsThatMayHaveLanded(t *testing.T) {
+	c, calls := fakeGitHub(t, hangUp)
+	recordSleeps(c)
+
+	if _, err := c.CreatePullRequest("main", "feature", "Feature", "", false); err == nil {
+		t.Fatal("want an error when the connection drops")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("made %d requests, want 1", calls.Load())
+	}
+}
+
+func TestCreatePullRequestAlreadyExists(t *testing.T) {
+	c, _ := fakeGitHub(t,
+		reply(http.StatusUnprocessableEntity, `{"message": "Validation Failed",
+			"errors": [{"message": "A pull request already exists for owner:feature."}]}`),
+	)
+	recordSleeps(c)
+
+	_, err := c.CreatePullRequest("main", "feature", "Feature", "", false)
+	if !errors.Is(err, ErrAlreadyExists) {
+		t.Fatalf("got %v, want ErrAlreadyExists", err)
+	}
+}
+
+func TestCreatePullRequestValidationError(t *testing.T) {
+	c, _ := fakeGitHub(t,
+		reply(http.StatusUnprocessableEntity, `{"message": "Validation Failed",
+			"errors": [{"message": "No commits between main and feature"}]}`),
+	)
+	recordSleeps(c)
+
+	_, err := c.CreatePullRequest("main", "feature", "Feature", "", false)
+	var apiErr *APIError
+	if errors.Is(err, ErrAlreadyExists) || !errors.As(err, &apiErr) {
+		t.Fatalf("got %v, want a plain APIError", err)
+	}
+}
+
+func equalWaits(got, want []time.Duration) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,365 @@
+package github
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// PullRequest is the subset of a GitHub pull request that Strata works with.
+type PullRequest struct {
+	Number      int    `json:"number"`
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Body        string `json:"body"`
+	State       string `json:"state"` // OPEN, CLOSED or MERGED
+	IsDraft     bool   `json:"isDraft"`
+	HeadRefName string `json:"headRefName"`
+	BaseRefName string `json:"baseRefName"`
+}
+
+// Comment is an issue comment on a pull request.
+type Comment struct {
+	ID   int64  `json:"id"`
+	Body string `json:"body"`
+}
+
+// prBatchSize bounds the number of aliased lookups per GraphQL query.
+const prBatchSize = 50
+
+const prFields = "number url title body state isDraft headRefName baseRefName"
+
+// Pull request states, as GraphQL reports them.
+const (
+	StateOpen   = "OPEN"
+	StateClosed = "CLOSED"
+	StateMerged = "MERGED"
+)
+
+// OpenPullRequests returns the open PR of each given head branch, keyed by branch, using one
+// GraphQL query per batch of branches instead of one request per branch.
+func (c *Client) OpenPullRequests(branches []string) (map[string]PullRequest, error) {
+	return c.pullRequestsByHead(branches, StateOpen)
+}
+
+// LatestPullRequests returns the PR of each given head branch in any state: its open PR,
+// otherwise the most recent merged or closed one.
+func (c *Client) LatestPullRequests(branches []string) (map[string]PullRequest, error) {
+	return c.pullRequestsByHead(branches, StateOpen, StateMerged, StateClosed)
+}
+
+func (c *Client) pullRequestsByHead(branches []string, states ...string) (map[string]PullRequest, error) {
+	result := map[string]PullRequest{}
+	for _, q := range HeadQueries(branches, states...) {
+		vars := map[string]interface{}{"owner": c.Owner, "repo": c.Repo}
+		for k, v := range q.Vars {
+			vars[k] = v
+		}
+		var data json.RawMessage
+		if err := c.graphql(q.Query, vars, &data); err != nil {
+			return nil, err
+		}
+		if err := q.Collect(data, result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// HeadQuery looks up the pull requests of a batch of head branches in one GraphQL query.
+// The query takes $owner and $repo besides Vars. The gh CLI backend runs it through
+// `gh api graphql`.
+type HeadQuery struct {
+	Branches []string
+	Query    string
+	Vars     map[string]string
+}
+
+// HeadQueries splits the lookup of the newest PRs in states of each branch into queries of
+// at most prBatchSize branches.
+func HeadQueries(branches []string, states ...string) []HeadQuery {
+	queries := []HeadQuery{}
+	for start := 0; start < len(branches); start += prBatchSize {
+		end := min(start+prBatchSize, len(branches))
+		q := HeadQuery{Branches: branches[start:end], Vars: map[string]string{}}
+		params := []string{"$owner: String!", "$repo: String!"}
+		fields := []string{}
+		for i, br := range q.Branches {
+			params = append(params, fmt.Sprintf("$h%d: String!", i))
+			fields = append(fields, fmt.Sprintf(
+				"b%d: pullRequests(headRefName: $h%d, states: [%s], first: 5, orderBy: {field: CREATED_AT, direction: DESC}) { nodes { %s } }",
+				i, i, strings.Join(states, ", "), prFields))
+			q.Vars[fmt.Sprintf("h%d", i)] = br
+		}
+		q.Query = fmt.Sprintf("query(%s) { repository(owner: $owner, name: $repo) { %s } }",
+			strings.Join(params, ", "), strings.Join(fields, " "))
+		queries = append(queries, q)
+	}
+	return queries
+}
+
+// Collect adds each branch's PR from the query's data to result: the open one if there is
+// one, otherwise the newest.
+func (q HeadQuery) Collect(data []byte, result map[string]PullRequest) error {
+	var parsed struct {
+		Repository map[string]struct {
+			Nodes []PullRequest `json:"nodes"`
+		} `json:"repository"`
+	}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return fmt.Errorf("failed to parse pull requests: %v", err)
+	}
+	for i, br := range q.Branches {
+		conn, ok := parsed.Repository[fmt.Sprintf("b%d", i)]
+		if !ok || len(conn.Nodes) == 0 {
+			continue
+		}
+		pr := conn.Nodes[0]
+		for _, n := range conn.Nodes {
+			if n.State == StateOpen {
+				pr = n
+				break
+			}
+		}
+		result[br] = pr
+	}
+	return nil
+}
+
+// restPull is the REST representation of a pull request.
+type restPull struct {
+	Number  int    `json:"number"`
+	HTMLURL string `json:"html_url"`
+	Title   string `json:"title"`
+	Body    string `json:"body"`
+	State   string `json:"state"`
+	Draft   bool   `json:"draft"`
+	Merged  bool   `json:"merged"`
+	Head    struct {
+		Ref string `json:"ref"`
+	} `json:"head"`
+	Base struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+}
+
+func (r restPull) toPullRequest() PullRequest {
+	state := strings.ToUpper(r.State)
+	if r.Merged {
+		state = StateMerged
+	}
+	return PullRequest{
+		Number:      r.Number,
+		URL:         r.HTMLURL,
+		Title:       r.Title,
+		Body:        r.Body,
+		State:       state,
+		IsDraft:     r.Draft,
+		HeadRefName: r.Head.Ref,
+		BaseRefName: r.Base.Ref,
+	}
+}
+
+func (c *Client) repoPath(format string, args ...interface{}) string {
+	return fmt.Sprintf("/repos/%s/%s", c.Owner, c.Repo) + fmt.Sprintf(format, args...)
+}
+
+// CreatePullRequest opens a PR. It returns ErrAlreadyExists if head already has an open PR.
+func (c *Client) CreatePullRequest(base, head, title, body string, draft bool) (PullRequest, error) {
+	req := map[string]interface{}{"base": base, "head": head, "title": title, "body": body, "draft": draft}
+	var pr restPull
+	if err := c.do(http.MethodPost, c.repoPath("/pulls"), req, &pr); err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.Status == http.StatusUnprocessableEntity &&
+			strings.Contains(apiErr.Message, "already exists") {
+			return PullRequest{}, ErrAlreadyExists
+		}
+		return PullRequest{}, err
+	}
+	return pr.toPullRequest(), nil
+}
+
+// UpdatePullRequest patches the given fields (e.g. "body", "title", "base") of a PR.
+func (c *Client) UpdatePullRequest(number int, fields map[string]interface{}) error {
+	return c.do(http.MethodPatch, c.repoPath("/pulls/%d", number), fields, nil)
+}
+
+// RequestReviewers asks users, or teams written as "org/team", to review a PR.
+func (c *Client) RequestReviewers(number int, reviewers []string) error {
+	users, teams := []string{}, []string{}
+	for _, r := range reviewers {
+		if slash := strings.Index(r, "/"); slash >= 0 {
+			teams = append(teams, r[slash+1:])
+		} else {
+			users = append(users, r)
+		}
+	}
+	req := map[string]interface{}{"reviewers": users, "team_reviewers": teams}
+	return c.do(http.MethodPost, c.repoPath("/pulls/%d/requested_reviewers", number), req, nil)
+}
+
+// AddLabels adds labels to a PR.
+func (c *Client) AddLabels(number int, labels []string) error {
+	return c.do(http.MethodPost, c.repoPath("/issues/%d/labels", number), map[string]interface{}{"labels": labels}, nil)
+}
+
+// AddAssignees assigns users to a PR.
+func (c *Client) AddAssignees(number int, assignees []string) error {
+	return c.do(http.MethodPost, c.repoPath("/issues/%d/assignees", number), map[string]interface{}{"assignees": assignees}, nil)
+}
+
+// SetMilestone attaches the open milestone with the given title to a PR.
+func (c *Client) SetMilestone(number int, title string) error {
+	var milestones []struct {
+		Number int    `json:"number"`
+		Title  string `json:"title"`
+	}
+	if err := c.do(http.MethodGet, c.repoPath("/milestones?state=open&per_page=100"), nil, &milestones); err != nil {
+		return err
+	}
+	for _, m := range milestones {
+		if m.Title == title {
+			return c.do(http.MethodPatch, c.repoPath("/issues/%d", number), map[string]interface{}{"milestone": m.Number}, nil)
+		}
+	}
+	return fmt.Errorf("milestone '%s' not found", title)
+}
+
+// Comments lists all issue comments on a PR.
+func (c *Client) Comments(number int) ([]Comment, error) {
+	all := []Comment{}
+	for page := 1; ; page++ {
+		var batch []Comment
+		if err := c.do(http.MethodGet, c.repoPath("/issues/%d/comments?per_page=100&page=%d", number, page), nil, &batch); err != nil {
+			return nil, err
+		}
+		all = append(all, batch...)
+		if len(batch) < 100 {
+			return all, nil
+		}
+	}
+}
+
+// CreateComment posts a new comment on a PR.
+func (c *Client) CreateComment(number int, body string) error {
+	return c.do(http.MethodPost, c.repoPath("/issues/%d/comments", number), map[string]interface{}{"body": body}, nil)
+}
+
+// UpdateComment replaces the body of an existing comment.
+func (c *Client) UpdateComment(id int64, body string) error {
+	return c.do(http.MethodPatch, c.repoPath("/issues/comments/%d", id), map[string]interface{}{"body": body}, nil)
+}
+
+// Check is one check run or commit status on a PR's head commit.
+type Check struct {
+	Name     string
+	Result   string // SUCCESS, FAILURE, PENDING, ...
+	Required bool
+}
+
+// PullRequestStatus summarises review and CI state of a PR.
+type PullRequestStatus struct {
+	ReviewDecision    string // APPROVED, CHANGES_REQUESTED, REVIEW_REQUIRED or ""
+	Mergeable         string // MERGEABLE, CONFLICTING or UNKNOWN
+	Checks            []Check
+	UnresolvedThreads int
+}
+
+// StatusQuery fetches everything PullRequestStatus reports. It takes $owner, $repo and $number,
+// so it can also be run through `gh api graphql`.
+const StatusQuery = `query($owner: String!, $repo: String!, $number: Int!) {
+  repository(owner: $owner, name: $repo) {
+    pullRequest(number: $number) {
+      reviewDecision
+      mergeable
+      reviewThreads(first: 100) { nodes { isResolved } }
+      commits(last: 1) { nodes { commit { statusCheckRollup { contexts(first: 100) { nodes {
+        ... on CheckRun { name status conclusion isRequired(pullRequestNumber: $number) }
+        ... on StatusContext { context state isRequired(pullRequestNumber: $number) }
+      } } } } } }
+    }
+  }
+}`
+
+// PullRequestStatus fetches the review decision, threads and status check rollup of a PR.
+func (c *Client) PullRequestStatus(number int) (PullRequestStatus, error) {
+	var data json.RawMessage
+	vars := map[string]interface{}{"owner": c.Owner, "repo": c.Repo, "number": number}
+	if err := c.graphql(StatusQuery, vars, &data); err != nil {
+		return PullRequestStatus{}, err
+	}
+	return DecodeStatus(data)
+}
+
+// DecodeStatus parses the "data" object returned for StatusQuery.
+func DecodeStatus(data []byte) (PullRequestStatus, error) {
+	var resp struct {
+		Repository struct {
+			PullRequest struct {
+				ReviewDecision string `json:"reviewDecision"`
+				Mergeable      string `json:"mergeable"`
+				ReviewThreads  struct {
+					Nodes []struct {
+						IsResolved bool `json:"isResolved"`
+					} `json:"nodes"`
+				} `json:"reviewThreads"`
+				Commits struct {
+					Nodes []struct {
+						Commit struct {
+							StatusCheckRollup *struct {
+								Contexts struct {
+									Nodes []struct {
+										Name       string `json:"name"`
+										Context    string `json:"context"`
+										Status     string `json:"status"`
+										Conclusion string `json:"conclusion"`
+										State      string `json:"state"`
+										IsRequired bool   `json:"isRequired"`
+									} `json:"nodes"`
+								} `json:"contexts"`
+							} `json:"statusCheckRollup"`
+						} `json:"commit"`
+					} `json:"nodes"`
+				} `json:"commits"`
+			} `json:"pullRequest"`
+		} `json:"repository"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return PullRequestStatus{}, fmt.Errorf("failed to parse PR status: %v", err)
+	}
+
+	pr := resp.Repository.PullRequest
+	status := PullRequestStatus{ReviewDecision: pr.ReviewDecision, Mergeable: pr.Mergeable, Checks: []Check{}}
+	for _, t := range pr.ReviewThreads.Nodes {
+		if !t.IsResolved {
+			status.UnresolvedThreads++
+		}
+	}
+	for _, n := range pr.Commits.Nodes {
+		if n.Commit.StatusCheckRollup == nil {
+			continue
+		}
+		for _, ctx := range n.Commit.StatusCheckRollup.Contexts.Nodes {
+			// check runs report Status/Conclusion, legacy commit statuses report State
+			check := Check{Name: ctx.Name, Result: ctx.Conclusion, Required: ctx.IsRequired}
+			if check.Name == "" {
+				check.Name = ctx.Context
+			}
+			if check.Result == "" {
+				check.Result = ctx.State
+			}
+			if check.Result == "" {
+				check.Result = "PENDING"
+			}
+			status.Checks = append(status.Checks, check)
+		}
+	}
+	return status, nil
+}
+
+// MergePullRequest merges a PR with the given method: merge, squash or rebase.
+func (c *Client) MergePullRequest(number int, method string) error {
+	return c.do(http.MethodPut, c.repoPath("/pulls/%d/merge", number), map[string]interface{}{"merge_method": method}, nil)
+}
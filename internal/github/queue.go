@@ -0,0 +1,95 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// MergeQueueStatus is a PR's standing in its base branch's merge queue.
+type MergeQueueStatus struct {
+	ID    string // node id, which EnqueueMutation takes
+	State string // OPEN, MERGED or CLOSED
+	// Entry is the queue entry state (QUEUED, AWAITING_CHECKS, MERGEABLE, UNMERGEABLE,
+	// LOCKED); empty when the PR is not in a queue.
+	Entry    string
+	Position int
+	// LastRemoval is why the PR was last removed from the queue, e.g. "failed checks".
+	LastRemoval   string
+	LastRemovedAt time.Time
+}
+
+// QueueQuery fetches what MergeQueueStatus reports. It takes $owner, $repo and $number.
+const QueueQuery = `query($owner: String!, $repo: String!, $number: Int!) {
+  repository(owner: $owner, name: $repo) {
+    pullRequest(number: $number) {
+      id
+      state
+      mergeQueueEntry { state position }
+      timelineItems(last: 1, itemTypes: [REMOVED_FROM_MERGE_QUEUE_EVENT]) {
+        nodes { ... on RemovedFromMergeQueueEvent { reason createdAt } }
+      }
+    }
+  }
+}`
+
+// EnqueueMutation adds the PR with node id $id to its merge queue. The queue's own
+// settings decide the merge method.
+const EnqueueMutation = `mutation($id: ID!) {
+  enqueuePullRequest(input: {pullRequestId: $id}) { mergeQueueEntry { position } }
+}`
+
+// MergeQueueStatus fetches a PR's merge queue entry.
+func (c *Client) MergeQueueStatus(number int) (MergeQueueStatus, error) {
+	var data json.RawMessage
+	vars := map[string]interface{}{"owner": c.Owner, "repo": c.Repo, "number": number}
+	if err := c.graphql(QueueQuery, vars, &data); err != nil {
+		return MergeQueueStatus{}, err
+	}
+	return DecodeMergeQueue(data)
+}
+
+// EnqueuePullRequest adds a PR to its base branch's merge queue.
+func (c *Client) EnqueuePullRequest(number int) error {
+	st, err := c.MergeQueueStatus(number)
+	if err != nil {
+		return err
+	}
+	return c.graphql(EnqueueMutation, map[string]interface{}{"id": st.ID}, nil)
+}
+
+// DecodeMergeQueue parses the "data" object returned for QueueQuery.
+func DecodeMergeQueue(data []byte) (MergeQueueStatus, error) {
+	var resp struct {
+		Repository struct {
+			PullRequest struct {
+				ID              string `json:"id"`
+				State           string `json:"state"`
+				MergeQueueEntry *struct {
+					State    string `json:"state"`
+					Position int    `json:"position"`
+				} `json:"mergeQueueEntry"`
+				TimelineItems struct {
+					Nodes []struct {
+						Reason    string    `json:"reason"`
+						CreatedAt time.Time `json:"createdAt"`
+					} `json:"nodes"`
+				} `json:"timelineItems"`
+			} `json:"pullRequest"`
+		} `json:"repository"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return MergeQueueStatus{}, fmt.Errorf("failed to parse merge queue status: %v", err)
+	}
+
+	pr := resp.Repository.PullRequest
+	st := MergeQueueStatus{ID: pr.ID, State: pr.State}
+	if pr.MergeQueueEntry != nil {
+		st.Entry, st.Position = pr.MergeQueueEntry.State, pr.MergeQueueEntry.Position
+	}
+	if n := len(pr.TimelineItems.Nodes); n > 0 {
+		st.LastRemoval = pr.TimelineItems.Nodes[n-1].Reason
+		st.LastRemovedAt = pr.TimelineItems.Nodes[n-1].CreatedAt
+	}
+	return st, nil
+}
@@ -0,0 +1,156 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"strata/internal/logs"
+	"time"
+)
+
+// ReviewThread is an inline review conversation on a PR.
+type ReviewThread struct {
+	ID         string // node id, which ReplyMutation and ResolveMutation take
+	Path       string
+	Line       int
+	IsResolved bool
+	IsOutdated bool
+	Comments   []ReviewComment
+}
+
+type ReviewComment struct {
+	Author    string
+	Body      string
+	CreatedAt time.Time
+}
+
+// ThreadsQuery fetches a page of the review threads of a PR. It takes $owner, $repo and
+// $number, and $after, the end cursor of the previous page, for the pages after the first.
+const ThreadsQuery = `query($owner: String!, $repo: String!, $number: Int!, $after: String) {
+  repository(owner: $owner, name: $repo) {
+    pullRequest(number: $number) {
+      reviewThreads(first: 100, after: $after) {
+        nodes {
+          id path line originalLine isResolved isOutdated
+          comments(first: 100) { nodes { author { login } body createdAt } pageInfo { hasNextPage } }
+        }
+        pageInfo { hasNextPage endCursor }
+      }
+    }
+  }
+}`
+
+// ReplyMutation answers the thread with node id $thread.
+const ReplyMutation = `mutation($thread: ID!, $body: String!) {
+  addPullRequestReviewThreadReply(input: {pullRequestReviewThreadId: $thread, body: $body}) { comment { id } }
+}`
+
+// ResolveMutation marks the thread with node id $thread as resolved.
+const ResolveMutation = `mutation($thread: ID!) {
+  resolveReviewThread(input: {threadId: $thread}) { thread { id } }
+}`
+
+// ReviewThreads fetches the review threads of a PR.
+func (c *Client) ReviewThreads(number int) ([]ReviewThread, error) {
+	return CollectThreads(func(after string) ([]byte, error) {
+		vars := map[string]interface{}{"owner": c.Owner, "repo": c.Repo, "number": number}
+		if after != "" {
+			vars["after"] = after
+		}
+		var data json.RawMessage
+		err := c.graphql(ThreadsQuery, vars, &data)
+		return data, err
+	})
+}
+
+// CollectThreads pages through the answers to ThreadsQuery. fetch returns the "data"
+// object of the page after the cursor after, or of the first page when after is empty.
+func CollectThreads(fetch func(after string) ([]byte, error)) ([]ReviewThread, error) {
+	threads := []ReviewThread{}
+	for after := ""; ; {
+		data, err := fetch(after)
+		if err != nil {
+			return nil, err
+		}
+		page, next, err := DecodeThreads(data)
+		if err != nil {
+			return nil, err
+		}
+		threads = append(threads, page...)
+		if next == "" {
+			return threads, nil
+		}
+		after = next
+	}
+}
+
+// ReplyToThread posts body as a reply in a review thread.
+func (c *Client) ReplyToThread(threadID, body string) error {
+	return c.graphql(ReplyMutation, map[string]interface{}{"thread": threadID, "body": body}, nil)
+}
+
+// ResolveThread marks a review thread as resolved.
+func (c *Client) ResolveThread(threadID string) error {
+	return c.graphql(ResolveMutation, map[string]interface{}{"thread": threadID}, nil)
+}
+
+// DecodeThreads parses a page of the "data" object returned for ThreadsQuery. next is the
+// cursor of the following page, empty on the last one.
+func DecodeThreads(data []byte) (threads []ReviewThread, next string, err error) {
+	var resp struct {
+		Repository struct {
+			PullRequest struct {
+				ReviewThreads struct {
+					Nodes []struct {
+						ID           string `json:"id"`
+						Path         string `json:"path"`
+						Line         int    `json:"line"`
+						OriginalLine int    `json:"originalLine"`
+						IsResolved   bool   `json:"isResolved"`
+						IsOutdated   bool   `json:"isOutdated"`
+						Comments     struct {
+							Nodes []struct {
+								Author struct {
+									Login string `json:"login"`
+								} `json:"author"`
+								Body      string    `json:"body"`
+								CreatedAt time.Time `json:"createdAt"`
+							} `json:"nodes"`
+							PageInfo pageInfo `json:"pageInfo"`
+						} `json:"comments"`
+					} `json:"nodes"`
+					PageInfo pageInfo `json:"pageInfo"`
+				} `json:"reviewThreads"`
+			} `json:"pullRequest"`
+		} `json:"repository"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, "", fmt.Errorf("failed to parse review threads: %v", err)
+	}
+
+	conn := resp.Repository.PullRequest.ReviewThreads
+	threads = []ReviewThread{}
+	for _, n := range conn.Nodes {
+		t := ReviewThread{ID: n.ID, Path: n.Path, Line: n.Line, IsResolved: n.IsResolved, IsOutdated: n.IsOutdated}
+		if t.Line == 0 {
+			// outdated threads no longer map onto the current diff
+			t.Line = n.OriginalLine
+		}
+		for _, c := range n.Comments.Nodes {
+			t.Comments = append(t.Comments, ReviewComment{Author: c.Author.Login, Body: c.Body, CreatedAt: c.CreatedAt})
+		}
+		if n.Comments.PageInfo.HasNextPage {
+			logs.Warn("The review thread on %s:%d has more comments than the %d shown", t.Path, t.Line, len(t.Comments))
+		}
+		threads = append(threads, t)
+	}
+	if conn.PageInfo.HasNextPage {
+		next = conn.PageInfo.EndCursor
+	}
+	return threads, next, nil
+}
+
+// pageInfo tells whether a GraphQL connection has more pages and where the next one starts.
+type pageInfo struct {
+	HasNextPage bool   `json:"hasNextPage"`
+	EndCursor   string `json:"endCursor"`
+}
@@ -11,10 +11,11 @@ import (
 )
 
 var (
-	loggerDebug *log.Logger
-	loggerInfo  *log.Logger
-	loggerWarn  *log.Logger
-	loggerError *log.Logger
+	// Discard until InitLogger runs, so errors raised before the logger is set up (e.g. bad flags) don't panic.
+	loggerDebug = log.New(io.Discard, "", 0)
+	loggerInfo  = log.New(io.Discard, "", 0)
+	loggerWarn  = log.New(io.Discard, "", 0)
+	loggerError = log.New(io.Discard, "", 0)
 
 	logLevel = "INFO"
 	verbose  = false
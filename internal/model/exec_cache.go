@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// ExecCache remembers how `strata exec` commands went on each commit, so layers that
+// haven't changed aren't run again.
+type ExecCache struct {
+	// Results maps a commit SHA to the outcome of every command run on it.
+	Results map[string]map[string]ExecResult `yaml:"results"`
+}
+
+// ExecResult is the outcome of one command on one commit.
+type ExecResult struct {
+	Passed   bool          `yaml:"passed"`
+	ExitCode int           `yaml:"exit_code"`
+	Duration time.Duration `yaml:"duration"`
+	RanAt    time.Time     `yaml:"ran_at"`
+}
@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+// LandRun is the progress of a `strata land --queue` run, kept on disk so an
+// interrupted or ejected run can be resumed.
+type LandRun struct {
+	Target string `yaml:"target"`
+	Method string `yaml:"method,omitempty"`
+	// Remaining lists the layers still to land, bottom first.
+	Remaining []string `yaml:"remaining"`
+	Landed    []string `yaml:"landed,omitempty"`
+
+	// Number is the PR of Remaining[0] once it has been enqueued, 0 before that.
+	Number int `yaml:"number,omitempty"`
+	// EnqueuedAt is when Number was last enqueued; removals from the queue before then
+	// are from earlier attempts.
+	EnqueuedAt time.Time `yaml:"enqueued_at,omitempty"`
+	// Ejected records why the queue dropped that PR; it is cleared when re-enqueued.
+	Ejected string `yaml:"ejected,omitempty"`
+	// Restack is set once Number has merged, until the layers above it are restacked.
+	Restack *LandRestack `yaml:"restack,omitempty"`
+
+	StartedAt time.Time `yaml:"started_at"`
+	UpdatedAt time.Time `yaml:"updated_at"`
+}
+
+// LandRestack is the restack of the layers above a landed one. It is saved before anything
+// moves, so a run that stops halfway finishes it on resume instead of skipping it.
+type LandRestack struct {
+	Branch string `yaml:"branch"`
+	Trunk  string `yaml:"trunk"`
+	// Children are the landed layer's children, which move onto the trunk.
+	Children []string `yaml:"children,omitempty"`
+	// OldTips are where the landed layer and the layers above it pointed before the merge,
+	// so only their own commits are replayed.
+	OldTips map[string]string `yaml:"old_tips,omitempty"`
+	// Pushed lists the layers already restacked and force-pushed.
+	Pushed []string `yaml:"pushed,omitempty"`
+}
@@ -0,0 +1,55 @@
+package model
+
+// Severities of a policy rule. An "error" fails `strata ci check`, a "warning" is only
+// reported and "off" disables the rule.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityOff     = "off"
+)
+
+// Policy is the org's merge rules, read from .strata/policy.yaml. Rules left out are not checked.
+type Policy struct {
+	MaxCommits          *LimitRule          `yaml:"max_commits,omitempty"`
+	MaxDiffLines        *LimitRule          `yaml:"max_diff_lines,omitempty"`
+	LinearHistory       *PolicyRule         `yaml:"linear_history,omitempty"`
+	ConventionalCommits *ConventionalRule   `yaml:"conventional_commits,omitempty"`
+	ForbiddenPaths      *ForbiddenPathsRule `yaml:"forbidden_paths,omitempty"`
+	MaxStackDepth       *LimitRule          `yaml:"max_stack_depth,omitempty"`
+	TicketReference     *TicketRule         `yaml:"ticket_reference,omitempty"`
+}
+
+// PolicyRule holds what every rule has; an empty Severity means "error".
+type PolicyRule struct {
+	Severity string `yaml:"severity,omitempty"`
+}
+
+// LimitRule caps a count per layer (or, for max_stack_depth, per stack).
+type LimitRule struct {
+	PolicyRule `yaml:",inline"`
+	Max        int `yaml:"max"`
+}
+
+// ConventionalRule requires "type(scope)!: subject" commit subjects. Types defaults to the
+// Conventional Commits set (feat, fix, docs, ...).
+type ConventionalRule struct {
+	PolicyRule `yaml:",inline"`
+	Types      []string `yaml:"types,omitempty"`
+}
+
+// ForbiddenPathsRule rejects layers touching files that match glob patterns ("**" spans
+// directories). Paths applies to every layer; Layers maps branch globs to extra patterns.
+type ForbiddenPathsRule struct {
+	PolicyRule `yaml:",inline"`
+	Paths      []string            `yaml:"paths,omitempty"`
+	Layers     map[string][]string `yaml:"layers,omitempty"`
+}
+
+// TicketRule requires a ticket reference matching Pattern, e.g. "[A-Z]+-[0-9]+". In is
+// "commits" (default: every commit message), "any" (at least one commit message) or
+// "branch" (the branch name).
+type TicketRule struct {
+	PolicyRule `yaml:",inline"`
+	Pattern    string `yaml:"pattern"`
+	In         string `yaml:"in,omitempty"`
+}
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// PRCache is the last known PR metadata per branch, so that read-only commands can show
+// PR state without asking the forge.
+type PRCache struct {
+	Branches map[string]CachedPR `yaml:"branches"`
+}
+
+// CachedPR is what the forge said about a branch's PR at FetchedAt. Number is 0 when the
+// branch had no open PR, which is worth remembering too.
+type CachedPR struct {
+	Number int    `yaml:"number,omitempty"`
+	URL    string `yaml:"url,omitempty"`
+	State  string `yaml:"state,omitempty"`
+	Base   string `yaml:"base,omitempty"`
+	// Review is one of the forge.Review* decisions; it is only filled in by full refreshes.
+	Review string `yaml:"review,omitempty"`
+
+	FetchedAt time.Time `yaml:"fetched_at"`
+}
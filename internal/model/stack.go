@@ -1,15 +1,110 @@
 package model
 
-import "time"
+import (
+	"sort"
+	"time"
+)
 
 type StackNode struct {
 	BranchName   string   `yaml:"branch_name"`
 	ParentBranch string   `yaml:"parent_branch,omitempty"`
 	Children     []string `yaml:"children,omitempty"`
 
+	// Stack names the Stack this layer belongs to; empty for unassigned branches.
+	Stack string `yaml:"stack,omitempty"`
+
 	CreatedBy string    `yaml:"created_by,omitempty"` // GH username or fallback
 	CreatedAt time.Time `yaml:"created_at,omitempty"`
 	UpdatedAt time.Time `yaml:"updated_at,omitempty"`
 }
 
 type StackTree map[string]*StackNode
+
+// Stack is a named group of layers, e.g. "auth-refactor", with its own metadata.
+type Stack struct {
+	Name        string    `yaml:"name"`
+	Description string    `yaml:"description,omitempty"`
+	Owner       string    `yaml:"owner,omitempty"`
+	Trunk       string    `yaml:"trunk,omitempty"`
+	Labels      []string  `yaml:"labels,omitempty"`
+	CreatedAt   time.Time `yaml:"created_at,omitempty"`
+	Archived    bool      `yaml:"archived,omitempty"`
+}
+
+// Stacks maps stack names to their metadata.
+type Stacks map[string]*Stack
+
+// Roots returns the branches whose parent is empty or not tracked in the stack, sorted by name.
+func (st StackTree) Roots() []string {
+	roots := []string{}
+	for br, node := range st {
+		if node.ParentBranch == "" || st[node.ParentBranch] == nil {
+			roots = append(roots, br)
+		}
+	}
+	sort.Strings(roots)
+	return roots
+}
+
+// Topological returns every branch in the stack ordered parents-first.
+// Siblings keep the order they were added in, roots are sorted by name.
+func (st StackTree) Topological() []string {
+	order := []string{}
+	visited := map[string]bool{}
+	var walk func(br string)
+	walk = func(br string) {
+		node := st[br]
+		if node == nil || visited[br] {
+			return
+		}
+		visited[br] = true
+		order = append(order, br)
+		for _, c := range node.Children {
+			walk(c)
+		}
+	}
+	for _, r := range st.Roots() {
+		walk(r)
+	}
+	// Anything left over is part of a cycle or has a dangling child reference; append it so nothing is lost.
+	rest := []string{}
+	for br := range st {
+		if !visited[br] {
+			rest = append(rest, br)
+		}
+	}
+	sort.Strings(rest)
+	return append(order, rest...)
+}
+
+// Filter returns the layers that belong to the named stack. Parents outside the stack
+// (usually the trunk) are not included, so they show up as roots of the result.
+func (st StackTree) Filter(stack string) StackTree {
+	out := StackTree{}
+	for br, node := range st {
+		if node.Stack == stack {
+			out[br] = node
+		}
+	}
+	return out
+}
+
+// Descendants returns branch and every layer stacked on top of it, parents first.
+func (st StackTree) Descendants(branch string) []string {
+	out := []string{}
+	visited := map[string]bool{}
+	var walk func(br string)
+	walk = func(br string) {
+		node := st[br]
+		if node == nil || visited[br] {
+			return
+		}
+		visited[br] = true
+		out = append(out, br)
+		for _, c := range node.Children {
+			walk(c)
+		}
+	}
+	walk(branch)
+	return out
+}
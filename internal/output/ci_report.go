@@ -0,0 +1,324 @@
+package output
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Renderings of the ci_check document for CI systems: JUnit and SARIF reports, GitHub
+// Actions annotations and step summaries, and GitLab Code Quality reports. Unlike the
+// documents in schema.go they follow external formats.
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Skipped  int          `xml:"skipped,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the report as a JUnit XML test suite with one test case per rule.
+// Warnings pass, with the finding in the test's output, since JUnit has no warning state.
+func WriteJUnit(w io.Writer, r CICheckDoc) error {
+	suite := junitSuite{Name: "strata ci check " + r.Branch}
+	for _, rule := range r.Rules {
+		tc := junitCase{Name: rule.Name, ClassName: "strata." + r.Branch}
+		detail := strings.Join(append(append([]string{}, rule.Files...), rule.Details...), "\n")
+		switch {
+		case rule.Blocking():
+			tc.Failure = &junitMessage{Message: rule.Message, Type: rule.Severity, Text: detail}
+			suite.Failures++
+		case rule.Status == RuleFailed:
+			tc.SystemOut = strings.TrimSpace("warning: " + rule.Message + "\n" + detail)
+		case rule.Status == RuleSkipped:
+			tc.Skipped = &junitMessage{Message: rule.Message}
+			suite.Skipped++
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+	suite.Tests = len(suite.Cases)
+	doc := junitSuites{Name: "strata", Tests: suite.Tests, Failures: suite.Failures, Skipped: suite.Skipped, Suites: []junitSuite{suite}}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("failed to encode JUnit report: %v", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+	DefaultConfig    sarifConfig  `json:"defaultConfiguration"`
+}
+
+type sarifConfig struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysical `json:"physicalLocation"`
+}
+
+type sarifPhysical struct {
+	ArtifactLocation sarifArtifact `json:"artifactLocation"`
+}
+
+type sarifArtifact struct {
+	URI string `json:"uri"`
+}
+
+// sarifRepoRoot is the location of results about the layer as a whole.
+const sarifRepoRoot = "."
+
+// sarifRuleText describes each rule of the ci_check document for SARIF viewers.
+var sarifRuleText = map[string]string{
+	"parent_landed":        "The layer sits directly on a trunk",
+	"contains_parent":      "The layer contains its target's current tip",
+	"merges_cleanly":       "The layer merges into its target without conflicts",
+	"pr_base":              "The layer's PR targets the right base",
+	"max_commits":          "Layers stay below the policy's commit count",
+	"max_diff_lines":       "Layers stay below the policy's diff size",
+	"linear_history":       "Layers contain no merge commits",
+	"conventional_commits": "Commit subjects follow Conventional Commits",
+	"forbidden_paths":      "Layers don't touch paths the policy forbids",
+	"max_stack_depth":      "Stacks stay below the policy's depth",
+	"ticket_reference":     "Commits or branches reference a ticket",
+}
+
+// WriteSARIF writes the report's failed rules as SARIF 2.1.0 results. Failures about files
+// (conflicts, forbidden paths) get one result per file so they can be annotated in place;
+// other policy failures point at the policy file, and the merge checks at the repository.
+func WriteSARIF(w io.Writer, r CICheckDoc) error {
+	run := sarifRun{Tool: sarifTool{Driver: sarifDriver{Name: "strata", Rules: []sarifRule{}}}, Results: []sarifResult{}}
+	seen := map[string]bool{}
+	for _, rule := range r.Rules {
+		level := "error"
+		if rule.Severity == "warning" {
+			level = "warning"
+		}
+		if !seen[rule.Name] {
+			seen[rule.Name] = true
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+				ID:               rule.Name,
+				ShortDescription: sarifMessage{Text: sarifRuleText[rule.Name]},
+				DefaultConfig:    sarifConfig{Level: level},
+			})
+		}
+		if rule.Status != RuleFailed {
+			continue
+		}
+		msg := fmt.Sprintf("%s: %s", r.Branch, rule.Message)
+		if len(rule.Details) > 0 {
+			msg += "\n" + strings.Join(rule.Details, "\n")
+		}
+		files := rule.Files
+		if len(files) == 0 {
+			// code scanning needs a location: the policy for its rules, the repository otherwise
+			if rule.Policy && r.Policy != "" {
+				files = []string{r.Policy}
+			} else {
+				files = []string{sarifRepoRoot}
+			}
+		}
+		for _, f := range files {
+			run.Results = append(run.Results, sarifResult{
+				RuleID:    rule.Name,
+				Level:     level,
+				Message:   sarifMessage{Text: msg},
+				Locations: []sarifLocation{{PhysicalLocation: sarifPhysical{ArtifactLocation: sarifArtifact{URI: f}}}},
+			})
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{Schema: sarifSchema, Version: "2.1.0", Runs: []sarifRun{run}})
+}
+
+// WriteGitHubAnnotations writes failed rules as GitHub Actions workflow commands, which
+// Gitea and Forgejo Actions understand too. Failures about files are annotated on each file.
+func WriteGitHubAnnotations(w io.Writer, r CICheckDoc) error {
+	for _, rule := range r.Rules {
+		if rule.Status != RuleFailed {
+			continue
+		}
+		level := "error"
+		if !rule.Blocking() {
+			level = "warning"
+		}
+		msg := rule.Message
+		if len(rule.Details) > 0 {
+			msg += "\n" + strings.Join(rule.Details, "\n")
+		}
+		title := fmt.Sprintf("strata %s (%s)", rule.Name, r.Branch)
+		files := rule.Files
+		if len(files) == 0 {
+			files = []string{""}
+		}
+		for _, f := range files {
+			props := "title=" + escapeWorkflowProperty(title)
+			if f != "" {
+				props = "file=" + escapeWorkflowProperty(f) + "," + props
+			}
+			if _, err := fmt.Fprintf(w, "::%s %s::%s\n", level, props, escapeWorkflowData(msg)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// escapeWorkflowData and escapeWorkflowProperty follow the escaping of the Actions toolkit.
+func escapeWorkflowData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func escapeWorkflowProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+// WriteStepSummary writes a Markdown summary of the report for $GITHUB_STEP_SUMMARY, with
+// the stack drawn by graph (Mermaid) when it is not empty.
+func WriteStepSummary(w io.Writer, r CICheckDoc, graph string) error {
+	var b strings.Builder
+	verdict := "✅ can be merged"
+	for _, rule := range r.Rules {
+		if rule.Blocking() {
+			verdict = "❌ blocked"
+		}
+	}
+	b.WriteString(fmt.Sprintf("### Strata: `%s` → `%s` %s\n\n", r.Branch, r.Target, verdict))
+	b.WriteString("| Result | Rule | Details |\n|---|---|---|\n")
+	for _, rule := range r.Rules {
+		result := map[string]string{RulePassed: "✅ pass", RuleFailed: "❌ fail", RuleSkipped: "⏭️ skip"}[rule.Status]
+		if rule.Status == RuleFailed && !rule.Blocking() {
+			result = "⚠️ warn"
+		}
+		details := rule.Message
+		for _, extra := range append(append([]string{}, rule.Files...), rule.Details...) {
+			details += "<br>`" + extra + "`"
+		}
+		b.WriteString(fmt.Sprintf("| %s | `%s` | %s |\n", result, rule.Name, strings.ReplaceAll(details, "|", "\\|")))
+	}
+	if graph != "" {
+		b.WriteString("\n```mermaid\n" + strings.TrimRight(graph, "\n") + "\n```\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+type codeQualityIssue struct {
+	Description string              `json:"description"`
+	CheckName   string              `json:"check_name"`
+	Fingerprint string              `json:"fingerprint"`
+	Severity    string              `json:"severity"`
+	Location    codeQualityLocation `json:"location"`
+}
+
+type codeQualityLocation struct {
+	Path  string           `json:"path"`
+	Lines codeQualityLines `json:"lines"`
+}
+
+type codeQualityLines struct {
+	Begin int `json:"begin"`
+}
+
+// WriteCodeQuality writes failed rules as a GitLab Code Quality report, shown on the merge
+// request. GitLab needs a path for every issue; rules not about files point at policyPath.
+func WriteCodeQuality(w io.Writer, r CICheckDoc, policyPath string) error {
+	issues := []codeQualityIssue{}
+	for _, rule := range r.Rules {
+		if rule.Status != RuleFailed {
+			continue
+		}
+		severity := "major"
+		if !rule.Blocking() {
+			severity = "minor"
+		}
+		files := rule.Files
+		if len(files) == 0 {
+			files = []string{policyPath}
+		}
+		for _, f := range files {
+			sum := sha1.Sum([]byte(r.Branch + "\x00" + rule.Name + "\x00" + f))
+			issues = append(issues, codeQualityIssue{
+				Description: fmt.Sprintf("%s: %s", r.Branch, rule.Message),
+				CheckName:   "strata-" + rule.Name,
+				Fingerprint: hex.EncodeToString(sum[:]),
+				Severity:    severity,
+				Location:    codeQualityLocation{Path: f, Lines: codeQualityLines{Begin: 1}},
+			})
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(issues)
+}
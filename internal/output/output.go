@@ -0,0 +1,121 @@
+package output
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strata/internal/errs"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaVersion is bumped whenever a field is removed or changes meaning.
+// Adding fields is backwards compatible and does not require a bump.
+const SchemaVersion = 1
+
+type Format string
+
+const (
+	Text Format = "text"
+	JSON Format = "json"
+	YAML Format = "yaml"
+)
+
+var current = Text
+
+// ParseFormat validates the value of the global --output flag.
+func ParseFormat(s string) (Format, error) {
+	switch Format(s) {
+	case Text, JSON, YAML:
+		return Format(s), nil
+	default:
+		return Text, fmt.Errorf("unknown output format '%s' (expected text, json or yaml)", s)
+	}
+}
+
+func SetFormat(f Format) {
+	current = f
+}
+
+func CurrentFormat() Format {
+	return current
+}
+
+// Structured is true when commands should emit documents instead of human text.
+func Structured() bool {
+	return current != Text
+}
+
+// Envelope wraps every structured document so consumers can dispatch on kind and version.
+type Envelope struct {
+	SchemaVersion int          `json:"schema_version" yaml:"schema_version"`
+	Kind          string       `json:"kind" yaml:"kind"`
+	Data          interface{}  `json:"data,omitempty" yaml:"data,omitempty"`
+	Error         *ErrorDetail `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+type ErrorDetail struct {
+	Code     string `json:"code" yaml:"code"`
+	ExitCode int    `json:"exit_code" yaml:"exit_code"`
+	Message  string `json:"message" yaml:"message"`
+}
+
+// Print writes data as a structured document of the given kind to stdout.
+func Print(kind string, data interface{}) error {
+	return write(os.Stdout, Envelope{SchemaVersion: SchemaVersion, Kind: kind, Data: data})
+}
+
+// PrintError reports err as an "error" document on stdout, or as plain text on stderr.
+// Errors already reported by the command (see Reported) are not printed again.
+func PrintError(err error) {
+	var r *reportedError
+	if errors.As(err, &r) {
+		return
+	}
+	if !Structured() {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return
+	}
+	detail := &ErrorDetail{
+		Code:     errs.CodeOf(err),
+		ExitCode: errs.ExitCode(err),
+		Message:  err.Error(),
+	}
+	if e := write(os.Stdout, Envelope{SchemaVersion: SchemaVersion, Kind: "error", Error: detail}); e != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+	}
+}
+
+type reportedError struct {
+	err error
+}
+
+func (r *reportedError) Error() string { return r.err.Error() }
+func (r *reportedError) Unwrap() error { return r.err }
+
+// Reported marks err as already written to the output (e.g. as a failed verdict),
+// so only its exit code is used.
+func Reported(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &reportedError{err: err}
+}
+
+func write(w io.Writer, env Envelope) error {
+	switch current {
+	case YAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(env); err != nil {
+			return err
+		}
+		return enc.Close()
+	default:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(env)
+	}
+}
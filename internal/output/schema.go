@@ -0,0 +1,252 @@
+package output
+
+import "time"
+
+// Document kinds. Consumers should ignore kinds they don't know.
+const (
+	KindStack       = "stack"
+	KindStackStatus = "stack_status"
+	KindCICheck     = "ci_check"
+	KindCIAffected  = "ci_affected_layers"
+	KindPRCreate    = "pr_create"
+	KindPRSync      = "pr_sync"
+	KindPRStatus    = "pr_status"
+	KindPRComments  = "pr_comments"
+	KindExec        = "exec"
+	KindUpdatePlan  = "update_plan"
+	KindConflicts   = "conflicts"
+)
+
+// StackDoc is the "stack" document emitted by `strata view`.
+type StackDoc struct {
+	Current  string      `json:"current,omitempty" yaml:"current,omitempty"`
+	Roots    []string    `json:"roots" yaml:"roots"`
+	Branches []BranchDoc `json:"branches" yaml:"branches"`
+}
+
+type BranchDoc struct {
+	Name      string     `json:"name" yaml:"name"`
+	Parent    string     `json:"parent,omitempty" yaml:"parent,omitempty"`
+	Children  []string   `json:"children" yaml:"children"`
+	Stack     string     `json:"stack,omitempty" yaml:"stack,omitempty"`
+	CreatedBy string     `json:"created_by,omitempty" yaml:"created_by,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty" yaml:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty" yaml:"updated_at,omitempty"`
+}
+
+// StatusDoc is the "stack_status" document emitted by `strata log`.
+type StatusDoc struct {
+	Branches []BranchStatusDoc `json:"branches" yaml:"branches"`
+}
+
+type BranchStatusDoc struct {
+	Name         string      `json:"name" yaml:"name"`
+	Parent       string      `json:"parent,omitempty" yaml:"parent,omitempty"`
+	Current      bool        `json:"current" yaml:"current"`
+	CreatedBy    string      `json:"created_by,omitempty" yaml:"created_by,omitempty"`
+	Commits      []CommitDoc `json:"commits" yaml:"commits"`
+	AheadParent  int         `json:"ahead_parent" yaml:"ahead_parent"`
+	BehindParent int         `json:"behind_parent" yaml:"behind_parent"`
+	NeedsRestack bool        `json:"needs_restack" yaml:"needs_restack"`
+	Remote       *RemoteDoc  `json:"remote,omitempty" yaml:"remote,omitempty"`
+	PR           *PRDoc      `json:"pr,omitempty" yaml:"pr,omitempty"`
+}
+
+type CommitDoc struct {
+	Hash    string `json:"hash" yaml:"hash"`
+	Subject string `json:"subject" yaml:"subject"`
+	Author  string `json:"author" yaml:"author"`
+}
+
+type RemoteDoc struct {
+	Ahead  int `json:"ahead" yaml:"ahead"`
+	Behind int `json:"behind" yaml:"behind"`
+}
+
+type PRDoc struct {
+	Number int    `json:"number" yaml:"number"`
+	State  string `json:"state" yaml:"state"`
+	URL    string `json:"url" yaml:"url"`
+	Review string `json:"review,omitempty" yaml:"review,omitempty"`
+}
+
+// CICheckDoc is the "ci_check" verdict emitted by `strata ci check`.
+type CICheckDoc struct {
+	Branch  string `json:"branch" yaml:"branch"`
+	Target  string `json:"target,omitempty" yaml:"target,omitempty"`
+	Passed  bool   `json:"passed" yaml:"passed"`
+	Code    string `json:"code,omitempty" yaml:"code,omitempty"`
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+	// Policy is the policy file checked, relative to the repository root.
+	Policy string      `json:"policy,omitempty" yaml:"policy,omitempty"`
+	Rules  []CIRuleDoc `json:"rules" yaml:"rules"`
+}
+
+// CIAffectedDoc is the "ci_affected_layers" list emitted by `strata ci affected-layers`.
+type CIAffectedDoc struct {
+	Branch string   `json:"branch" yaml:"branch"`
+	Layers []string `json:"layers" yaml:"layers"`
+}
+
+// CIRuleDoc is one rule of a ci_check verdict; Status is "pass", "fail" or "skip" and
+// Severity "error" or "warning".
+type CIRuleDoc struct {
+	Name     string   `json:"name" yaml:"name"`
+	Severity string   `json:"severity" yaml:"severity"`
+	Status   string   `json:"status" yaml:"status"`
+	Message  string   `json:"message" yaml:"message"`
+	Files    []string `json:"files,omitempty" yaml:"files,omitempty"`
+	Details  []string `json:"details,omitempty" yaml:"details,omitempty"`
+	// Policy marks the rules of the policy file.
+	Policy bool `json:"policy,omitempty" yaml:"policy,omitempty"`
+}
+
+// The statuses of a CIRuleDoc.
+const (
+	RulePassed  = "pass"
+	RuleFailed  = "fail"
+	RuleSkipped = "skip"
+)
+
+// Blocking reports whether the rule failed at error severity.
+func (r CIRuleDoc) Blocking() bool {
+	return r.Status == RuleFailed && r.Severity != "warning"
+}
+
+// ExecDoc is the "exec" matrix emitted by `strata exec`; Status is "pass", "fail" or "skip".
+type ExecDoc struct {
+	Command string         `json:"command" yaml:"command"`
+	Passed  bool           `json:"passed" yaml:"passed"`
+	Code    string         `json:"code,omitempty" yaml:"code,omitempty"`
+	Message string         `json:"message,omitempty" yaml:"message,omitempty"`
+	Layers  []ExecLayerDoc `json:"layers" yaml:"layers"`
+}
+
+type ExecLayerDoc struct {
+	Branch     string `json:"branch" yaml:"branch"`
+	Commit     string `json:"commit,omitempty" yaml:"commit,omitempty"`
+	Status     string `json:"status" yaml:"status"`
+	ExitCode   int    `json:"exit_code" yaml:"exit_code"`
+	DurationMS int64  `json:"duration_ms" yaml:"duration_ms"`
+	Cached     bool   `json:"cached" yaml:"cached"`
+	Log        string `json:"log,omitempty" yaml:"log,omitempty"`
+	Reason     string `json:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
+// UpdatePlanDoc is the "update_plan" dry run emitted by `strata update --plan`. Action is
+// "up_to_date", "fast_forward", "rebase", "conflict", "blocked" or "missing".
+type UpdatePlanDoc struct {
+	Layers      []PlannedLayerDoc `json:"layers" yaml:"layers"`
+	Rebases     int               `json:"rebases" yaml:"rebases"`
+	Conflicts   int               `json:"conflicts" yaml:"conflicts"`
+	CommitCount int               `json:"commit_count" yaml:"commit_count"`
+}
+
+type PlannedLayerDoc struct {
+	Branch  string   `json:"branch" yaml:"branch"`
+	Parent  string   `json:"parent,omitempty" yaml:"parent,omitempty"`
+	Action  string   `json:"action" yaml:"action"`
+	Commits int      `json:"commits" yaml:"commits"`
+	Files   []string `json:"files,omitempty" yaml:"files,omitempty"`
+	Note    string   `json:"note,omitempty" yaml:"note,omitempty"`
+}
+
+// ConflictsDoc is the "conflicts" document emitted by `strata conflicts list`.
+type ConflictsDoc struct {
+	Resolutions []ResolutionDoc `json:"resolutions" yaml:"resolutions"`
+}
+
+type ResolutionDoc struct {
+	ID         string     `json:"id" yaml:"id"`
+	Path       string     `json:"path,omitempty" yaml:"path,omitempty"`
+	Branch     string     `json:"branch,omitempty" yaml:"branch,omitempty"`
+	Resolved   bool       `json:"resolved" yaml:"resolved"`
+	RecordedAt time.Time  `json:"recorded_at" yaml:"recorded_at"`
+	LastUsed   *time.Time `json:"last_used,omitempty" yaml:"last_used,omitempty"`
+}
+
+// PRCreateDoc is the "pr_create" report emitted by `strata pr create`. Action is
+// "created", "updated", "skipped" or "failed".
+type PRCreateDoc struct {
+	Passed  bool          `json:"passed" yaml:"passed"`
+	Code    string        `json:"code,omitempty" yaml:"code,omitempty"`
+	Message string        `json:"message,omitempty" yaml:"message,omitempty"`
+	Layers  []PRResultDoc `json:"layers" yaml:"layers"`
+}
+
+type PRResultDoc struct {
+	Branch string `json:"branch" yaml:"branch"`
+	Base   string `json:"base,omitempty" yaml:"base,omitempty"`
+	Action string `json:"action" yaml:"action"`
+	Number int    `json:"number,omitempty" yaml:"number,omitempty"`
+	URL    string `json:"url,omitempty" yaml:"url,omitempty"`
+	Reason string `json:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
+// PRSyncDoc is the "pr_sync" report emitted by `strata pr sync`.
+type PRSyncDoc struct {
+	DryRun  bool            `json:"dry_run" yaml:"dry_run"`
+	Changes []BaseChangeDoc `json:"changes" yaml:"changes"`
+}
+
+type BaseChangeDoc struct {
+	Branch  string `json:"branch" yaml:"branch"`
+	Number  int    `json:"number" yaml:"number"`
+	URL     string `json:"url" yaml:"url"`
+	From    string `json:"from" yaml:"from"`
+	To      string `json:"to" yaml:"to"`
+	Applied bool   `json:"applied" yaml:"applied"`
+	Error   string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// PRStatusDoc is the "pr_status" overview emitted by `strata pr status`.
+type PRStatusDoc struct {
+	Blocked bool             `json:"blocked" yaml:"blocked"`
+	Layers  []LayerStatusDoc `json:"layers" yaml:"layers"`
+}
+
+type LayerStatusDoc struct {
+	Branch            string   `json:"branch" yaml:"branch"`
+	Parent            string   `json:"parent" yaml:"parent"`
+	Bottom            bool     `json:"bottom" yaml:"bottom"`
+	PR                *PRDoc   `json:"pr,omitempty" yaml:"pr,omitempty"`
+	Review            string   `json:"review,omitempty" yaml:"review,omitempty"`
+	Mergeable         string   `json:"mergeable,omitempty" yaml:"mergeable,omitempty"`
+	ChecksPassed      int      `json:"checks_passed" yaml:"checks_passed"`
+	ChecksFailed      int      `json:"checks_failed" yaml:"checks_failed"`
+	ChecksPending     int      `json:"checks_pending" yaml:"checks_pending"`
+	UnresolvedThreads int      `json:"unresolved_threads" yaml:"unresolved_threads"`
+	Base              string   `json:"base,omitempty" yaml:"base,omitempty"`
+	ExpectedBase      string   `json:"expected_base" yaml:"expected_base"`
+	BehindBase        int      `json:"behind_base" yaml:"behind_base"`
+	BaseStale         bool     `json:"base_stale" yaml:"base_stale"`
+	Blockers          []string `json:"blockers" yaml:"blockers"`
+}
+
+// PRCommentsDoc is the "pr_comments" document emitted by `strata pr comments`.
+type PRCommentsDoc struct {
+	Layers []LayerThreadsDoc `json:"layers" yaml:"layers"`
+}
+
+type LayerThreadsDoc struct {
+	Branch  string      `json:"branch" yaml:"branch"`
+	PR      PRDoc       `json:"pr" yaml:"pr"`
+	Threads []ThreadDoc `json:"threads" yaml:"threads"`
+}
+
+type ThreadDoc struct {
+	// Ref is what `strata pr comments jump|reply|resolve` take.
+	Ref      string             `json:"ref" yaml:"ref"`
+	ID       string             `json:"id" yaml:"id"`
+	Path     string             `json:"path,omitempty" yaml:"path,omitempty"`
+	Line     int                `json:"line,omitempty" yaml:"line,omitempty"`
+	Resolved bool               `json:"resolved" yaml:"resolved"`
+	Outdated bool               `json:"outdated" yaml:"outdated"`
+	Comments []ThreadCommentDoc `json:"comments" yaml:"comments"`
+}
+
+type ThreadCommentDoc struct {
+	Author    string     `json:"author" yaml:"author"`
+	Body      string     `json:"body" yaml:"body"`
+	CreatedAt *time.Time `json:"created_at,omitempty" yaml:"created_at,omitempty"`
+}
@@ -0,0 +1,160 @@
+package restapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strata/internal/logs"
+	"strconv"
+	"time"
+)
+
+// MaxRateLimitWait is the longest we sleep for a rate limit window to reset before giving up.
+const MaxRateLimitWait = 60 * time.Second
+
+// Client sends JSON requests, retrying failed requests and server errors with backoff and
+// waiting out short rate limits. The GitHub client and the GitLab and Gitea forges share it.
+type Client struct {
+	Name       string            // API name used in messages, e.g. "GitHub"
+	Headers    map[string]string // sent with every request, authentication included
+	MaxRetries int
+	HTTP       *http.Client
+	// Error turns a response that failed for good into the caller's error.
+	Error func(status int, body []byte) error
+	// Sleep waits between attempts; tests replace it to run without delays.
+	Sleep func(time.Duration)
+}
+
+// New builds a client with the usual retries and timeout.
+func New(name string, headers map[string]string, errorFor func(status int, body []byte) error) *Client {
+	return &Client{
+		Name:       name,
+		Headers:    headers,
+		MaxRetries: 3,
+		HTTP:       &http.Client{Timeout: 30 * time.Second},
+		Error:      errorFor,
+		Sleep:      time.Sleep,
+	}
+}
+
+// Send performs the request and decodes the JSON response into out (if non-nil).
+func (c *Client) Send(method, url string, body interface{}, out interface{}) error {
+	return c.do(method, url, body, out, method != http.MethodPost)
+}
+
+// Query POSTs a request that only reads, such as a GraphQL query, so it is retried like
+// a GET.
+func (c *Client) Query(url string, body interface{}, out interface{}) error {
+	return c.do(http.MethodPost, url, body, out, true)
+}
+
+// do sends the request. A request that isn't idempotent may have been carried out before
+// the connection broke, and repeating it would post a second comment or reply, so it is
+// only sent again when it never reached the server.
+func (c *Client) do(method, url string, body interface{}, out interface{}, idempotent bool) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("failed to encode request: %v", err)
+		}
+	}
+
+	backoff := time.Second
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(method, url, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("User-Agent", "strata")
+		for k, v := range c.Headers {
+			if v != "" {
+				req.Header.Set(k, v)
+			}
+		}
+		if payload != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		resp, err := c.HTTP.Do(req)
+		if err != nil {
+			if attempt < c.MaxRetries && (idempotent || notSent(err)) {
+				logs.Warn("%s request %s %s failed, retrying in %v: %v", c.Name, method, url, backoff, err)
+				c.Sleep(backoff)
+				backoff *= 2
+				continue
+			}
+			return fmt.Errorf("%s request %s %s failed: %v", c.Name, method, url, err)
+		}
+		data, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			if out == nil || len(data) == 0 {
+				return nil
+			}
+			if err := json.Unmarshal(data, out); err != nil {
+				return fmt.Errorf("failed to decode %s response: %v", c.Name, err)
+			}
+			return nil
+		}
+
+		if wait, limited := rateLimitWait(resp); limited && attempt < c.MaxRetries {
+			if wait == 0 {
+				wait = backoff
+				backoff *= 2
+			}
+			if wait > MaxRateLimitWait {
+				return fmt.Errorf("%s rate limit exceeded; resets in %v", c.Name, wait.Round(time.Second))
+			}
+			logs.Warn("%s rate limit hit, waiting %v", c.Name, wait.Round(time.Second))
+			c.Sleep(wait)
+			continue
+		}
+		if resp.StatusCode >= 500 && attempt < c.MaxRetries {
+			logs.Warn("%s returned %d for %s %s, retrying in %v", c.Name, resp.StatusCode, method, url, backoff)
+			c.Sleep(backoff)
+			backoff *= 2
+			continue
+		}
+		return c.Error(resp.StatusCode, data)
+	}
+}
+
+// notSent reports whether the transport error err means the request never left, because
+// no connection could be made.
+func notSent(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// rateLimitWait reports whether resp is a rate limit and how long to wait. GitHub answers
+// 403 or 429 with X-RateLimit-* headers, GitLab 429 with RateLimit-* ones.
+func rateLimitWait(resp *http.Response) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	if ra := resp.Header.Get("Retry-After"); ra != "" {
+		if secs, err := strconv.Atoi(ra); err == nil {
+			return time.Duration(secs) * time.Second, true
+		}
+	}
+	for _, prefix := range []string{"X-RateLimit-", "RateLimit-"} {
+		if resp.Header.Get(prefix+"Remaining") != "0" {
+			continue
+		}
+		if reset, err := strconv.ParseInt(resp.Header.Get(prefix+"Reset"), 10, 64); err == nil {
+			wait := time.Until(time.Unix(reset, 0))
+			if wait < time.Second {
+				wait = time.Second
+			}
+			return wait, true
+		}
+		return time.Minute, true
+	}
+	return 0, resp.StatusCode == http.StatusTooManyRequests
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strata/internal/errs"
+	"strata/internal/git"
+	"strata/internal/logs"
+	"strata/internal/model"
+	"strata/internal/utils"
+	"strconv"
+	"strings"
+)
+
+// CI providers recognised from the job environment.
+const (
+	CIGitHub = "github"
+	CIGitLab = "gitlab"
+	CIGitea  = "gitea"
+)
+
+// ciStackName is the in-memory named stack holding a stack rebuilt from the forge; it
+// carries the trunk so IsTrunk and TrunkFor work without any local metadata.
+const ciStackName = "ci"
+
+// CIContext is what the CI job environment says about the change being built.
+type CIContext struct {
+	Provider string // one of the CI* constants, or "" outside a known CI
+	// Branch is the PR's head branch, or the pushed branch for push builds.
+	Branch string
+	// Base and Number describe the PR; they are empty for push builds.
+	Base   string
+	Number int
+}
+
+// DetectCIContext reads the standard variables of GitHub Actions, Gitea/Forgejo Actions and
+// GitLab CI. STRATA_CI_BRANCH overrides the branch anywhere.
+func DetectCIContext() CIContext {
+	ctx := CIContext{}
+	switch {
+	case os.Getenv("GITLAB_CI") == "true":
+		ctx.Provider = CIGitLab
+		ctx.Branch = os.Getenv("CI_MERGE_REQUEST_SOURCE_BRANCH_NAME")
+		ctx.Base = os.Getenv("CI_MERGE_REQUEST_TARGET_BRANCH_NAME")
+		ctx.Number, _ = strconv.Atoi(os.Getenv("CI_MERGE_REQUEST_IID"))
+		if ctx.Branch == "" {
+			ctx.Branch = os.Getenv("CI_COMMIT_BRANCH")
+		}
+	case os.Getenv("GITHUB_ACTIONS") == "true":
+		ctx.Provider = CIGitHub
+		if os.Getenv("GITEA_ACTIONS") == "true" || os.Getenv("FORGEJO_ACTIONS") == "true" {
+			ctx.Provider = CIGitea
+		}
+		// GITHUB_HEAD_REF and GITHUB_BASE_REF are only set for pull_request events
+		ctx.Branch = os.Getenv("GITHUB_HEAD_REF")
+		ctx.Base = os.Getenv("GITHUB_BASE_REF")
+		if ref := os.Getenv("GITHUB_REF"); strings.HasPrefix(ref, "refs/pull/") {
+			ctx.Number, _ = strconv.Atoi(strings.Split(strings.TrimPrefix(ref, "refs/pull/"), "/")[0])
+		}
+		if ctx.Branch == "" && os.Getenv("GITHUB_REF_TYPE") == "branch" {
+			ctx.Branch = os.Getenv("GITHUB_REF_NAME")
+		}
+	}
+	if br := os.Getenv("STRATA_CI_BRANCH"); br != "" {
+		ctx.Branch = br
+	}
+	return ctx
+}
+
+// PrepareCI resolves the branch a `strata ci` command works on (branch, else the CI
+// context's, else the checked out one) and makes sure the stack knows it. When the clone
+// has no stack file, as in CI, the stack is rebuilt from the forge and the layers' branches
+// are fetched from origin. The rebuilt stack only lives in memory.
+func (c *CIService) PrepareCI(branch string) (string, error) {
+	if branch == "" {
+		branch = DetectCIContext().Branch
+	}
+	if branch == "" {
+		branch = utils.CurrentBranch()
+	}
+	if branch == "" || branch == "HEAD" {
+		return "", fmt.Errorf("no branch given and none found in the CI environment; pass one or set STRATA_CI_BRANCH")
+	}
+
+	s := GetStackService()
+	if _, ok := s.stack[branch]; ok {
+		return branch, nil
+	}
+	tree, trunk, err := c.RebuildStack(branch)
+	if err != nil {
+		return branch, err
+	}
+	s.stack = tree
+	s.stacks[ciStackName] = &model.Stack{Name: ciStackName, Trunk: trunk}
+
+	branches := []string{trunk}
+	for br := range tree {
+		if br != trunk {
+			branches = append(branches, br)
+		}
+	}
+	if err := git.FetchBranches(branches); err != nil {
+		logs.Warn("Could not fetch the stack's branches: %v", err)
+	}
+	return branch, nil
+}
+
+// RebuildStack reconstructs the stack containing branch from the forge's open PRs. A PR's
+// parent is the one recorded in its Strata section when that layer still has an open PR,
+// and its base otherwise. The stack runs from the trunk (the bottom PR's base) through
+// every PR stacked on top of it; branch must have an open PR. The PRs are looked up by
+// head for every branch on origin, so however many PRs the repository has open, none of
+// the stack's are missed.
+func (c *CIService) RebuildStack(branch string) (model.StackTree, string, error) {
+	fg, err := GetPRService().forge()
+	if err != nil {
+		return nil, "", err
+	}
+	heads, err := git.RemoteBranches()
+	if err != nil {
+		return nil, "", err
+	}
+	changes, err := fg.OpenChanges(heads)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list open PRs: %w", err)
+	}
+	if _, ok := changes[branch]; !ok {
+		return nil, "", errs.NotInStack("branch '%s' has no stack file entry and no open PR to rebuild its stack from", branch)
+	}
+
+	parentOf := map[string]string{}
+	children := map[string][]string{}
+	for head, cr := range changes {
+		parent := cr.Base
+		if hint := strataParent(cr.Body); hint != "" && hint != head {
+			if _, open := changes[hint]; open {
+				parent = hint
+			}
+		}
+		parentOf[head] = parent
+		children[parent] = append(children[parent], head)
+	}
+
+	// walk down to the bottom PR; its base is the trunk
+	root := branch
+	for seen := map[string]bool{root: true}; ; {
+		parent := parentOf[root]
+		if _, open := changes[parent]; !open || seen[parent] {
+			break
+		}
+		seen[parent] = true
+		root = parent
+	}
+	trunk := parentOf[root]
+
+	tree := model.StackTree{trunk: {BranchName: trunk, Children: []string{root}, Stack: ciStackName}}
+	queue := []string{root}
+	for len(queue) > 0 {
+		br := queue[0]
+		queue = queue[1:]
+		if tree[br] != nil {
+			continue
+		}
+		kids := append([]string{}, children[br]...)
+		sort.Strings(kids)
+		tree[br] = &model.StackNode{BranchName: br, ParentBranch: parentOf[br], Children: kids, Stack: ciStackName}
+		queue = append(queue, kids...)
+	}
+	logs.Info("Rebuilt stack of '%s' from %d open PRs: %d layers on '%s'", branch, len(changes), len(tree)-1, trunk)
+	return tree, trunk, nil
+}
+
+// AffectedLayers returns branch and every layer stacked on it, parents first: the layers
+// whose contents change when branch does.
+func (c *CIService) AffectedLayers(branch string) []string {
+	stack := GetStackService().stack
+	affected := map[string]bool{branch: true}
+	for _, d := range stack.Descendants(branch) {
+		affected[d] = true
+	}
+	layers := []string{}
+	for _, br := range stack.Topological() {
+		if affected[br] {
+			layers = append(layers, br)
+		}
+	}
+	return layers
+}
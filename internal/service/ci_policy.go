@@ -0,0 +1,327 @@
+package service
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strata/internal/git"
+	"strata/internal/model"
+	"strata/internal/store"
+	"strings"
+)
+
+// Names of the policy rules, as they appear in the policy file and in reports.
+const (
+	RuleMaxCommits          = "max_commits"
+	RuleMaxDiffLines        = "max_diff_lines"
+	RuleLinearHistory       = "linear_history"
+	RuleConventionalCommits = "conventional_commits"
+	RuleForbiddenPaths      = "forbidden_paths"
+	RuleMaxStackDepth       = "max_stack_depth"
+	RuleTicketReference     = "ticket_reference"
+)
+
+// defaultCommitTypes are the Conventional Commits types accepted when the policy lists none.
+var defaultCommitTypes = []string{"feat", "fix", "docs", "style", "refactor", "perf", "test", "build", "ci", "chore", "revert"}
+
+// loadPolicy reads the policy at policyPath, or at store.PolicyFileName when policyPath is
+// empty, relative paths being taken from the top of the repository; only an explicitly
+// named file has to exist. The policy is validated up front so a
+// broken rule fails the check instead of passing it.
+func loadPolicy(policyPath string) (*model.Policy, error) {
+	explicit := policyPath != ""
+	if !explicit {
+		policyPath = store.PolicyFileName
+	}
+	policy, err := store.LoadPolicy(policyFile(policyPath))
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		if explicit {
+			return nil, fmt.Errorf("policy file '%s' not found", policyPath)
+		}
+		return nil, nil
+	}
+
+	rules := map[string]*model.PolicyRule{}
+	limits := map[string]*model.LimitRule{}
+	if r := policy.MaxCommits; r != nil {
+		rules[RuleMaxCommits] = &r.PolicyRule
+		limits[RuleMaxCommits] = r
+	}
+	if r := policy.MaxDiffLines; r != nil {
+		rules[RuleMaxDiffLines] = &r.PolicyRule
+		limits[RuleMaxDiffLines] = r
+	}
+	if r := policy.LinearHistory; r != nil {
+		rules[RuleLinearHistory] = r
+	}
+	if r := policy.ConventionalCommits; r != nil {
+		rules[RuleConventionalCommits] = &r.PolicyRule
+	}
+	if r := policy.ForbiddenPaths; r != nil {
+		rules[RuleForbiddenPaths] = &r.PolicyRule
+	}
+	if r := policy.MaxStackDepth; r != nil {
+		rules[RuleMaxStackDepth] = &r.PolicyRule
+		limits[RuleMaxStackDepth] = r
+	}
+	if r := policy.TicketReference; r != nil {
+		rules[RuleTicketReference] = &r.PolicyRule
+		if _, err := regexp.Compile(r.Pattern); err != nil || r.Pattern == "" {
+			return nil, fmt.Errorf("%s: invalid %s pattern '%s'", policyPath, RuleTicketReference, r.Pattern)
+		}
+		switch r.In {
+		case "", "commits", "any", "branch":
+		default:
+			return nil, fmt.Errorf("%s: %s.in must be commits, any or branch, not '%s'", policyPath, RuleTicketReference, r.In)
+		}
+	}
+	for name, r := range rules {
+		switch r.Severity {
+		case "":
+			r.Severity = model.SeverityError
+		case model.SeverityError, model.SeverityWarning, model.SeverityOff:
+		default:
+			return nil, fmt.Errorf("%s: %s.severity must be error, warning or off, not '%s'", policyPath, name, r.Severity)
+		}
+	}
+	// a missing or zero max would fail every layer, or none with a negative one
+	for name, r := range limits {
+		if r.Severity != model.SeverityOff && r.Max <= 0 {
+			return nil, fmt.Errorf("%s: %s.max must be a positive number, not %d", policyPath, name, r.Max)
+		}
+	}
+	return policy, nil
+}
+
+// policyFile resolves a relative policyPath against the top of the repository, so the
+// policy is found from any subdirectory instead of silently going unchecked.
+func policyFile(policyPath string) string {
+	if filepath.IsAbs(policyPath) {
+		return policyPath
+	}
+	root, err := git.TopLevel()
+	if err != nil {
+		return policyPath
+	}
+	return filepath.Join(root, policyPath)
+}
+
+// policyLocation names the policy file loadPolicy read for policyPath relative to the top of
+// the repository, where reports such as SARIF expect it.
+func policyLocation(policyPath string) string {
+	if policyPath == "" {
+		policyPath = store.PolicyFileName
+	}
+	root, err := git.TopLevel()
+	if err != nil {
+		return filepath.ToSlash(policyPath)
+	}
+	rel, err := filepath.Rel(root, policyFile(policyPath))
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(policyPath)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// layerChanges is what a policy needs to know about a layer, gathered once.
+type layerChanges struct {
+	commits []git.Commit
+	files   []git.FileChange
+	merges  []string
+}
+
+// checkPolicy adds one rule to the report per enabled policy rule. head and base are the
+// refs of the branch and its target; the layer is everything head has on top of base.
+func (c *CIService) checkPolicy(report *CIReport, policy *model.Policy, head, base string) error {
+	var lc layerChanges
+	var err error
+	if lc.commits, err = git.CommitsBetween(base, head); err != nil {
+		return err
+	}
+	if lc.files, err = git.DiffNumstat(base, head); err != nil {
+		return err
+	}
+	if lc.merges, err = git.MergeCommits(base, head); err != nil {
+		return err
+	}
+
+	if r := policy.MaxCommits; enabled(r) {
+		if n := len(lc.commits); n > r.Max {
+			report.check(RuleMaxCommits, r.Severity, false, "'%s' has %s; the policy allows %d", report.Branch, pluralize(n, "commit"), r.Max)
+		} else {
+			report.check(RuleMaxCommits, r.Severity, true, "%s (max %d)", pluralize(n, "commit"), r.Max)
+		}
+	}
+
+	if r := policy.MaxDiffLines; enabled(r) {
+		lines := 0
+		for _, f := range lc.files {
+			lines += f.Added + f.Deleted
+		}
+		if lines > r.Max {
+			report.check(RuleMaxDiffLines, r.Severity, false, "'%s' changes %s; the policy allows %d", report.Branch, pluralize(lines, "line"), r.Max)
+		} else {
+			report.check(RuleMaxDiffLines, r.Severity, true, "%s changed (max %d)", pluralize(lines, "line"), r.Max)
+		}
+	}
+
+	if r := policy.LinearHistory; r != nil && r.Severity != model.SeverityOff {
+		if len(lc.merges) > 0 {
+			report.check(RuleLinearHistory, r.Severity, false, "'%s' contains %s; rebase instead of merging", report.Branch, pluralize(len(lc.merges), "merge commit"))
+		} else {
+			report.check(RuleLinearHistory, r.Severity, true, "no merge commits")
+		}
+	}
+
+	if r := policy.ConventionalCommits; r != nil && r.Severity != model.SeverityOff {
+		types := r.Types
+		if len(types) == 0 {
+			types = defaultCommitTypes
+		}
+		quoted := make([]string, len(types))
+		for i, t := range types {
+			quoted[i] = regexp.QuoteMeta(t)
+		}
+		re := regexp.MustCompile(`^(` + strings.Join(quoted, "|") + `)(\([^)]+\))?!?: \S`)
+		bad := []string{}
+		for _, cm := range lc.commits {
+			if !re.MatchString(cm.Subject) {
+				bad = append(bad, shortHash(cm.Hash)+" "+cm.Subject)
+			}
+		}
+		if len(bad) > 0 {
+			rule := report.check(RuleConventionalCommits, r.Severity, false, "%s not in the form type(scope): subject", pluralize(len(bad), "commit subject"))
+			rule.Details = bad
+		} else {
+			report.check(RuleConventionalCommits, r.Severity, true, "all subjects follow Conventional Commits")
+		}
+	}
+
+	if r := policy.ForbiddenPaths; r != nil && r.Severity != model.SeverityOff {
+		patterns := append([]string{}, r.Paths...)
+		for layerGlob, extra := range r.Layers {
+			if ok, _ := path.Match(layerGlob, report.Branch); ok {
+				patterns = append(patterns, extra...)
+			}
+		}
+		hits := []string{}
+		for _, f := range lc.files {
+			for _, p := range patterns {
+				if matchPathGlob(p, f.Path) {
+					hits = append(hits, f.Path)
+					break
+				}
+			}
+		}
+		if len(hits) > 0 {
+			rule := report.check(RuleForbiddenPaths, r.Severity, false, "'%s' touches %s it may not change", report.Branch, pluralize(len(hits), "file"))
+			rule.Files = hits
+		} else {
+			report.check(RuleForbiddenPaths, r.Severity, true, "no forbidden paths touched")
+		}
+	}
+
+	if r := policy.MaxStackDepth; enabled(r) {
+		depth := GetStackService().layerDepth(report.Branch)
+		if depth > r.Max {
+			report.check(RuleMaxStackDepth, r.Severity, false, "'%s' is layer %d of its stack; the policy allows %d", report.Branch, depth, r.Max)
+		} else {
+			report.check(RuleMaxStackDepth, r.Severity, true, "layer %d (max %d)", depth, r.Max)
+		}
+	}
+
+	if r := policy.TicketReference; r != nil && r.Severity != model.SeverityOff {
+		c.checkTicketReference(report, r, lc.commits)
+	}
+	return nil
+}
+
+func (c *CIService) checkTicketReference(report *CIReport, r *model.TicketRule, commits []git.Commit) {
+	re := regexp.MustCompile(r.Pattern)
+	switch r.In {
+	case "branch":
+		if re.MatchString(report.Branch) {
+			report.check(RuleTicketReference, r.Severity, true, "branch name references %s", re.FindString(report.Branch))
+		} else {
+			report.check(RuleTicketReference, r.Severity, false, "branch name '%s' has no ticket reference matching %s", report.Branch, r.Pattern)
+		}
+	case "any":
+		for _, cm := range commits {
+			if ref := re.FindString(cm.Subject + "\n" + cm.Body); ref != "" {
+				report.check(RuleTicketReference, r.Severity, true, "%s references %s", shortHash(cm.Hash), ref)
+				return
+			}
+		}
+		report.check(RuleTicketReference, r.Severity, false, "no commit of '%s' references a ticket matching %s", report.Branch, r.Pattern)
+	default:
+		missing := []string{}
+		for _, cm := range commits {
+			if !re.MatchString(cm.Subject + "\n" + cm.Body) {
+				missing = append(missing, shortHash(cm.Hash)+" "+cm.Subject)
+			}
+		}
+		if len(missing) > 0 {
+			rule := report.check(RuleTicketReference, r.Severity, false, "%s without a ticket reference matching %s", pluralize(len(missing), "commit"), r.Pattern)
+			rule.Details = missing
+		} else {
+			report.check(RuleTicketReference, r.Severity, true, "every commit references a ticket")
+		}
+	}
+}
+
+func enabled(r *model.LimitRule) bool {
+	return r != nil && r.Severity != model.SeverityOff
+}
+
+func shortHash(h string) string {
+	if len(h) > 7 {
+		return h[:7]
+	}
+	return h
+}
+
+// layerDepth counts the layers from the trunk up to and including branch.
+func (s *StackService) layerDepth(branch string) int {
+	depth := 0
+	visited := map[string]bool{}
+	for cur := branch; cur != "" && !visited[cur] && !s.IsTrunk(cur); {
+		visited[cur] = true
+		node := s.stack[cur]
+		if node == nil {
+			break
+		}
+		depth++
+		cur = node.ParentBranch
+	}
+	return depth
+}
+
+// matchPathGlob matches a slash-separated path against a glob where "*" and "?" stay within
+// one directory and "**" spans any number of them, e.g. "vendor/**" or "**/*.pem".
+func matchPathGlob(pattern, p string) bool {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch ch := pattern[i]; {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			b.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case ch == '*':
+			b.WriteString("[^/]*")
+		case ch == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	b.WriteString("$")
+	re, err := regexp.Compile(b.String())
+	return err == nil && re.MatchString(p)
+}
@@ -2,7 +2,12 @@ package service
 
 import (
 	"fmt"
+	"strata/internal/errs"
+	"strata/internal/git"
 	"strata/internal/logs"
+	"strata/internal/model"
+	"strata/internal/ui"
+	"strings"
 )
 
 type CIService struct{}
@@ -16,29 +21,246 @@ func GetCIService() *CIService {
 	return ciSvc
 }
 
-// CheckMergeFeasibility ensures the parent is merged, no conflicts remain etc.
-// This is a simplistic exampl. Real logic might check if parent is fully merged, or if there's a PR conflict, etc.
-func (c *CIService) CheckMergeFeasibility(branch string) error {
+// Outcomes of a single CI rule.
+const (
+	RulePassed  = "pass"
+	RuleFailed  = "fail"
+	RuleSkipped = "skip"
+)
+
+// Names of the rules `strata ci check` runs, in order.
+const (
+	RuleParentLanded   = "parent_landed"
+	RuleContainsParent = "contains_parent"
+	RuleMergesCleanly  = "merges_cleanly"
+	RulePRBase         = "pr_base"
+)
+
+// CIRule is the outcome of one merge-feasibility or policy rule.
+type CIRule struct {
+	Name string
+	// Severity is model.SeverityError or model.SeverityWarning; failed warnings don't fail the check.
+	Severity string
+	Status   string
+	Message  string
+	// Files lists the files a failure is about, e.g. conflicting or forbidden paths.
+	Files []string
+	// Details lists the offending commits of commit-level policy rules.
+	Details []string
+	// Policy is set for the rules of the policy file, as opposed to the merge checks.
+	Policy bool
+}
+
+// CIReport is the per-rule verdict on whether a branch can be merged into its target.
+type CIReport struct {
+	Branch string
+	// Target is where the branch merges: its parent, or the trunk once the parents have landed.
+	Target string
+	Rules  []CIRule
+	// Policy is the policy file that was checked, relative to the top of the repository
+	// when it is inside; empty when there is none.
+	Policy string
+}
+
+// Passed reports whether no error-level rule failed.
+func (r CIReport) Passed() bool {
+	for _, rule := range r.Rules {
+		if rule.Blocking() {
+			return false
+		}
+	}
+	return true
+}
+
+// Blocking reports whether the rule failed at error severity.
+func (r CIRule) Blocking() bool {
+	return r.Status == RuleFailed && r.Severity != model.SeverityWarning
+}
+
+func (r *CIReport) add(name, status, format string, args ...interface{}) *CIRule {
+	r.Rules = append(r.Rules, CIRule{Name: name, Severity: model.SeverityError, Status: status, Message: fmt.Sprintf(format, args...)})
+	return &r.Rules[len(r.Rules)-1]
+}
+
+// check adds a policy rule that passed when ok.
+func (r *CIReport) check(name, severity string, ok bool, format string, args ...interface{}) *CIRule {
+	status := RuleFailed
+	if ok {
+		status = RulePassed
+	}
+	rule := r.add(name, status, format, args...)
+	rule.Severity = severity
+	rule.Policy = true
+	return rule
+}
+
+// CheckMergeFeasibility checks that branch can be merged into its target: its parents have
+// landed, it contains the target's current tip, it merges without conflicts (tested with
+// `git merge-tree`, so nothing is checked out) and its PR targets the right base. Every rule
+// runs even after one fails; the error sums up the failures and is errs.Conflict when the
+// merge itself conflicts.
+//
+// The org's rules from the policy file (policyPath, or store.PolicyFileName when empty) are
+// checked on the layer as well; their failures only fail the check at error severity.
+func (c *CIService) CheckMergeFeasibility(branch, policyPath string) (CIReport, error) {
 	s := GetStackService()
-	st := s.GetStack()
-	node, ok := st[branch]
+	report := CIReport{Branch: branch}
+	node, ok := s.GetStack()[branch]
 	if !ok {
-		return fmt.Errorf("branch '%s' not found in stack", branch)
+		return report, errs.NotInStack("branch '%s' not found in stack", branch)
+	}
+	policy, err := loadPolicy(policyPath)
+	if err != nil {
+		return report, err
+	}
+	if policy != nil {
+		report.Policy = policyLocation(policyPath)
+	}
+	// whether the parents have landed is only known from their PRs
+	prMap, landed, lookupErr := GetPRService().stackPRs(model.StackTree{branch: node})
+	report.Target = s.ExpectedBase(branch, landed)
+
+	switch {
+	case s.IsTrunk(report.Target):
+		report.add(RuleParentLanded, RulePassed, "'%s' sits directly on trunk '%s'", branch, report.Target)
+	case lookupErr != nil:
+		report.add(RuleParentLanded, RuleFailed, "could not check whether parent branch '%s' has merged: %s",
+			report.Target, strings.SplitN(lookupErr.Error(), "\n", 2)[0])
+	default:
+		report.add(RuleParentLanded, RuleFailed, "parent branch '%s' not yet merged, so '%s' cannot be merged in the stack", report.Target, branch)
 	}
 
-	// If parent is not in the stack (like 'main'), we assume it's always feasible
-	if node.ParentBranch == "" {
-		logs.Info("'%s' has no parent in the stack. Possibly top-level. Marking feasible.", branch)
-		return nil
+	head, headOK := ciRef(branch)
+	target, targetOK := ciRef(report.Target)
+	switch {
+	case !headOK:
+		report.add(RuleContainsParent, RuleFailed, "'%s' exists neither locally nor on origin", branch)
+		report.add(RuleMergesCleanly, RuleSkipped, "nothing to merge")
+	case !targetOK:
+		report.add(RuleContainsParent, RuleSkipped, "'%s' exists neither locally nor on origin", report.Target)
+		report.add(RuleMergesCleanly, RuleSkipped, "nothing to merge into")
+	default:
+		c.checkContainsTarget(&report, head, target)
+		c.checkMergesCleanly(&report, head, target)
 	}
 
-	// Check if parent is in the stack. If the parent's node is still open (not merged), we can't merge child yet.
-	parentNode, pOk := st[node.ParentBranch]
-	if pOk {
-		// if parent is still in the stack, it means it hasn't fully merged.
-		return fmt.Errorf("parent branch '%s' not yet merged, so '%s' cannot be merged in the stack", parentNode.BranchName, branch)
+	c.checkPRBase(&report, prMap, lookupErr)
+
+	if policy != nil && headOK && targetOK {
+		if err := c.checkPolicy(&report, policy, head, target); err != nil {
+			return report, err
+		}
 	}
 
-	// We might also check for rebase conflicts, etc. byt that's more advanced.
-	return nil
+	if report.Passed() {
+		return report, nil
+	}
+	failed := []string{}
+	conflict := false
+	for _, rule := range report.Rules {
+		if rule.Blocking() {
+			failed = append(failed, rule.Message)
+			conflict = conflict || rule.Name == RuleMergesCleanly
+		}
+	}
+	if conflict {
+		return report, errs.Conflict("%s", strings.Join(failed, "; "))
+	}
+	return report, fmt.Errorf("%s", strings.Join(failed, "; "))
+}
+
+// ciRef resolves a branch to the local ref, or to origin's copy in a CI checkout that only
+// has remote-tracking branches.
+func ciRef(branch string) (string, bool) {
+	for _, ref := range []string{"refs/heads/" + branch, "refs/remotes/origin/" + branch} {
+		if git.RefExists(ref) {
+			return ref, true
+		}
+	}
+	return "", false
+}
+
+func (c *CIService) checkContainsTarget(report *CIReport, head, target string) {
+	if git.IsAncestor(target, head) {
+		report.add(RuleContainsParent, RulePassed, "'%s' contains the tip of '%s'", report.Branch, report.Target)
+		return
+	}
+	_, behind, err := git.AheadBehind(target, head)
+	if err != nil {
+		report.add(RuleContainsParent, RuleFailed, "'%s' does not contain the tip of '%s'", report.Branch, report.Target)
+		return
+	}
+	report.add(RuleContainsParent, RuleFailed, "'%s' is %s behind '%s'; run `strata update`",
+		report.Branch, pluralize(behind, "commit"), report.Target)
+}
+
+func (c *CIService) checkMergesCleanly(report *CIReport, head, target string) {
+	files, err := git.MergeTree(target, head)
+	if err != nil {
+		logs.Warn("Merge test of '%s' into '%s' failed: %v", report.Branch, report.Target, err)
+		report.add(RuleMergesCleanly, RuleFailed, "could not test the merge: %v", err)
+		return
+	}
+	if len(files) == 0 {
+		report.add(RuleMergesCleanly, RulePassed, "'%s' merges into '%s' without conflicts", report.Branch, report.Target)
+		return
+	}
+	rule := report.add(RuleMergesCleanly, RuleFailed, "merging '%s' into '%s' conflicts in %s",
+		report.Branch, report.Target, pluralize(len(files), "file"))
+	rule.Files = files
+}
+
+// checkPRBase compares the PR's base with the target. It is skipped when the branch has no
+// open PR or the forge couldn't be reached (lookupErr), since neither says anything about
+// the merge.
+func (c *CIService) checkPRBase(report *CIReport, prMap map[string]branchPRInfo, lookupErr error) {
+	if lookupErr != nil {
+		report.add(RulePRBase, RuleSkipped, "could not look up the PR: %s", strings.SplitN(lookupErr.Error(), "\n", 2)[0])
+		return
+	}
+	info, ok := prMap[report.Branch]
+	if !ok {
+		report.add(RulePRBase, RuleSkipped, "'%s' has no open PR", report.Branch)
+		return
+	}
+	if info.Base != report.Target {
+		report.add(RulePRBase, RuleFailed, "PR #%d targets '%s' instead of '%s'; run `strata pr sync`", info.Number, info.Base, report.Target)
+		return
+	}
+	report.add(RulePRBase, RulePassed, "PR #%d targets '%s'", info.Number, info.Base)
+}
+
+// RenderCIReport lists each rule's outcome, with conflicting files under the merge rule.
+func RenderCIReport(r CIReport) string {
+	rows := [][]string{}
+	for _, rule := range r.Rules {
+		label := strings.ToUpper(rule.Status)
+		if rule.Status == RuleFailed && !rule.Blocking() {
+			label = "WARN"
+		}
+		rows = append(rows, []string{label, rule.Name, rule.Message})
+	}
+	lines := alignRows(rows)
+	var b strings.Builder
+	for i, rule := range r.Rules {
+		line := lines[i]
+		switch {
+		case rule.Status == RulePassed:
+			line = ui.Colorize(line, ui.FgGreen)
+		case rule.Blocking():
+			line = ui.Colorize(line, ui.FgRed)
+		case rule.Status == RuleFailed:
+			line = ui.Colorize(line, ui.FgYellow)
+		default:
+			line = ui.Colorize(line, ui.Dim)
+		}
+		b.WriteString(line + "\n")
+		for _, f := range rule.Files {
+			b.WriteString("      " + f + "\n")
+		}
+		for _, d := range rule.Details {
+			b.WriteString("      " + d + "\n")
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
 }
@@ -53,8 +53,16 @@ func (c *CollabService) HasServerOrShare() bool {
 }
 
 // GenerateShareCode copies the local stack into ephemeral memory for others to pull.
-func (c *CollabService) GenerateShareCode() (string, error) {
+// When stackName is set only that stack's layers are shared.
+func (c *CollabService) GenerateShareCode(stackName string) (string, error) {
 	localStack := GetStackService().GetStack()
+	if stackName != "" {
+		layers, err := GetStackService().StackLayers(stackName)
+		if err != nil {
+			return "", err
+		}
+		localStack = layers
+	}
 	code := utils.RandomShareCode()
 
 	c.shareMux.Lock()
@@ -221,6 +229,9 @@ func storeAndRefresh(svc *StackService, updated model.StackTree) error {
 	if err := store.SaveStack(updated); err != nil {
 		return err
 	}
+	if err := svc.registerMissingStacks(); err != nil {
+		return err
+	}
 	// Reload in case the file changes externally
 	return svc.ReloadStack()
 }
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"fmt"
+	"strata/internal/git"
+	"strata/internal/logs"
+	"strata/internal/ui"
+	"strings"
+)
+
+// shortResolutionID is how much of a resolution ID listings show; ForgetResolutions
+// accepts any prefix at least this long.
+const shortResolutionID = 8
+
+// ShortResolutionID is the abbreviated ID listings show for r.
+func ShortResolutionID(r git.Resolution) string {
+	return r.ID[:shortResolutionID]
+}
+
+// ConflictResolutions lists the conflict resolutions git rerere has recorded, newest first.
+// Rebases and merges run by Strata record them and reuse them for the same conflict in
+// other layers and worktrees.
+func ConflictResolutions() ([]git.Resolution, error) {
+	return git.Resolutions()
+}
+
+// ForgetResolutions deletes the recorded resolutions that targets name, each by an ID
+// prefix, the file or the layer it was recorded for, or every one with all set. A wrong
+// resolution is then no longer replayed, and the conflict is recorded afresh next time.
+func ForgetResolutions(targets []string, all bool) ([]git.Resolution, error) {
+	if !all && len(targets) == 0 {
+		return nil, fmt.Errorf("name the resolutions to forget by id, file or layer, or pass --all")
+	}
+	list, err := git.Resolutions()
+	if err != nil {
+		return nil, err
+	}
+	forget := []git.Resolution{}
+	if all {
+		forget = list
+	} else {
+		picked := map[string]bool{}
+		for _, t := range targets {
+			matched := false
+			for _, r := range list {
+				if resolutionMatches(r, t) {
+					matched = true
+					if !picked[r.ID] {
+						picked[r.ID] = true
+						forget = append(forget, r)
+					}
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("no recorded resolution matches '%s'; see `strata conflicts list`", t)
+			}
+		}
+	}
+
+	ids := make([]string, len(forget))
+	for i, r := range forget {
+		ids[i] = r.ID
+	}
+	if err := git.ForgetResolutions(ids); err != nil {
+		return nil, err
+	}
+	logs.Info("Forgot %s", pluralize(len(ids), "recorded resolution"))
+	return forget, nil
+}
+
+func resolutionMatches(r git.Resolution, target string) bool {
+	if len(target) >= shortResolutionID && strings.HasPrefix(r.ID, target) {
+		return true
+	}
+	return target == r.Path || target == r.Branch
+}
+
+// RenderResolutions shows the recorded resolutions as a table.
+func RenderResolutions(list []git.Resolution) string {
+	if len(list) == 0 {
+		return "No conflict resolutions recorded yet."
+	}
+	rows := [][]string{{"ID", "FILE", "LAYER", "CONFLICTED", "LAST USED"}}
+	for _, r := range list {
+		file, layer := r.Path, r.Branch
+		if file == "" {
+			file = "?"
+		}
+		if layer == "" {
+			layer = "-"
+		}
+		used := "not resolved yet"
+		if r.Resolved {
+			used = r.LastUsed.Format("2006-01-02 15:04")
+		}
+		rows = append(rows, []string{ShortResolutionID(r), file, layer, r.RecordedAt.Format("2006-01-02 15:04"), used})
+	}
+	lines := alignRows(rows)
+
+	var b strings.Builder
+	b.WriteString(ui.Colorize(lines[0], ui.Bold))
+	for i, r := range list {
+		line := lines[i+1]
+		if !r.Resolved {
+			line = ui.Colorize(line, ui.Dim)
+		}
+		b.WriteString("\n" + line)
+	}
+	return b.String()
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strata/internal/model"
+	"strings"
+)
+
+// Graph export formats supported by `strata view --format`.
+const (
+	GraphTree    = "tree"
+	GraphMermaid = "mermaid"
+	GraphDOT     = "dot"
+	GraphSVG     = "svg"
+)
+
+// diagramStyleKey selects how PR bodies render the stack: "tree" (default) or "mermaid".
+const diagramStyleKey = "pr_diagram_style"
+
+// ExportStackGraph renders the stack DAG as Mermaid, Graphviz DOT or SVG.
+// PR state, merged and closed included, comes from the PR cache; nodes without a PR are
+// drawn neutral.
+func (p *PRService) ExportStackGraph(stack model.StackTree, current, format string, withPRs bool) (string, error) {
+	prMap := map[string]branchPRInfo{}
+	if withPRs {
+		prMap = p.cachedPRMap(stack)
+	}
+
+	switch format {
+	case GraphMermaid:
+		return renderMermaid(stack, current, prMap, true), nil
+	case GraphDOT:
+		return renderDOT(stack, current, prMap), nil
+	case GraphSVG:
+		return renderSVG(renderDOT(stack, current, prMap))
+	default:
+		return "", fmt.Errorf("unknown graph format '%s' (expected tree, mermaid, dot or svg)", format)
+	}
+}
+
+// graphNodeIDs assigns stable, syntax-safe identifiers; branch names may contain '/', '-' or '.'.
+func graphNodeIDs(stack model.StackTree) ([]string, map[string]string) {
+	order := stack.Topological()
+	ids := make(map[string]string, len(order))
+	for i, br := range order {
+		ids[br] = fmt.Sprintf("n%d", i)
+	}
+	return order, ids
+}
+
+// graphNodeClass buckets a branch into one of the styles shared by every renderer.
+func graphNodeClass(info branchPRInfo) string {
+	switch info.State {
+	case "MERGED":
+		return "merged"
+	case "CLOSED":
+		return "closed"
+	case "DRAFT":
+		return "draft"
+	case "OPEN":
+		return "open"
+	default:
+		return "nopr"
+	}
+}
+
+func graphNodeLabel(br string, info branchPRInfo) string {
+	if info.Number != 0 {
+		return fmt.Sprintf("%s #%d", br, info.Number)
+	}
+	return br
+}
+
+// renderMermaid emits a top-down flowchart. Links are only emitted for terminal use,
+// GitHub ignores click handlers in rendered Markdown.
+func renderMermaid(stack model.StackTree, current string, prMap map[string]branchPRInfo, withLinks bool) string {
+	order, ids := graphNodeIDs(stack)
+	var b strings.Builder
+	b.WriteString("flowchart TD\n")
+	for _, br := range order {
+		info := prMap[br]
+		label := strings.ReplaceAll(graphNodeLabel(br, info), `"`, "#quot;")
+		b.WriteString(fmt.Sprintf("    %s[\"%s\"]:::%s\n", ids[br], label, graphNodeClass(info)))
+	}
+	for _, br := range order {
+		if p := stack[br].ParentBranch; p != "" && ids[p] != "" {
+			b.WriteString(fmt.Sprintf("    %s --> %s\n", ids[p], ids[br]))
+		}
+	}
+	if withLinks {
+		for _, br := range order {
+			if url := prMap[br].URL; url != "" {
+				b.WriteString(fmt.Sprintf("    click %s href \"%s\" _blank\n", ids[br], url))
+			}
+		}
+	}
+	b.WriteString("    classDef open fill:#2da44e,stroke:#1a7f37,color:#fff\n")
+	b.WriteString("    classDef draft fill:#bf8700,stroke:#9a6700,color:#fff\n")
+	b.WriteString("    classDef merged fill:#8250df,stroke:#6639ba,color:#fff,stroke-dasharray:5 5\n")
+	b.WriteString("    classDef closed fill:#cf222e,stroke:#a40e26,color:#fff,stroke-dasharray:5 5\n")
+	b.WriteString("    classDef nopr fill:#f6f8fa,stroke:#8c959f,color:#24292f\n")
+	if id, ok := ids[current]; ok {
+		b.WriteString(fmt.Sprintf("    style %s stroke:#0969da,stroke-width:4px\n", id))
+	}
+	return b.String()
+}
+
+func renderDOT(stack model.StackTree, current string, prMap map[string]branchPRInfo) string {
+	styles := map[string]string{
+		"open":   `fillcolor="#2da44e", fontcolor="white"`,
+		"draft":  `fillcolor="#bf8700", fontcolor="white"`,
+		"merged": `fillcolor="#8250df", fontcolor="white", style="filled,dashed"`,
+		"closed": `fillcolor="#cf222e", fontcolor="white", style="filled,dashed"`,
+		"nopr":   `fillcolor="#f6f8fa", fontcolor="#24292f"`,
+	}
+
+	order, ids := graphNodeIDs(stack)
+	var b strings.Builder
+	b.WriteString("digraph strata {\n")
+	b.WriteString("    rankdir=TB;\n")
+	b.WriteString("    node [shape=box, style=filled, fontname=\"Helvetica\"];\n")
+	for _, br := range order {
+		info := prMap[br]
+		attrs := fmt.Sprintf(`label="%s", %s`, dotEscape(graphNodeLabel(br, info)), styles[graphNodeClass(info)])
+		if info.URL != "" {
+			attrs += fmt.Sprintf(`, URL="%s"`, dotEscape(info.URL))
+		}
+		if br == current {
+			attrs += `, color="#0969da", penwidth=3`
+		}
+		b.WriteString(fmt.Sprintf("    %s [%s];\n", ids[br], attrs))
+	}
+	for _, br := range order {
+		if p := stack[br].ParentBranch; p != "" && ids[p] != "" {
+			b.WriteString(fmt.Sprintf("    %s -> %s;\n", ids[p], ids[br]))
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func dotEscape(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, `\`, `\\`), `"`, `\"`)
+}
+
+// renderSVG pipes DOT through Graphviz, which must be installed separately.
+func renderSVG(dot string) (string, error) {
+	if _, err := exec.LookPath("dot"); err != nil {
+		return "", fmt.Errorf("svg export needs Graphviz 'dot' on PATH; use --format dot and render it yourself")
+	}
+	cmd := exec.Command("dot", "-Tsvg")
+	cmd.Stdin = strings.NewReader(dot)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("graphviz failed: %v\n%s", err, stderr.String())
+	}
+	return string(out), nil
+}
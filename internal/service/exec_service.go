@@ -0,0 +1,457 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strata/internal/config"
+	"strata/internal/git"
+	"strata/internal/logs"
+	"strata/internal/model"
+	"strata/internal/store"
+	"strata/internal/ui"
+	"strings"
+	"sync"
+	"time"
+)
+
+// testCommandKey is the command `strata test-stack` runs when none is given, e.g. "make test".
+const testCommandKey = "test_command"
+
+// execCacheMaxAge bounds how long cached exec results are kept.
+const execCacheMaxAge = 30 * 24 * time.Hour
+
+type ExecService struct{}
+
+var execSvc *ExecService
+
+func GetExecService() *ExecService {
+	if execSvc == nil {
+		execSvc = &ExecService{}
+	}
+	return execSvc
+}
+
+// Outcomes of a layer in `strata exec`.
+const (
+	ExecPassed  = "pass"
+	ExecFailed  = "fail"
+	ExecSkipped = "skip"
+)
+
+// ExecOptions says what `strata exec` runs and where.
+type ExecOptions struct {
+	// Command is run through the shell when it is a single argument, directly otherwise.
+	Command []string
+	// Stack, From and Upto narrow the layers like they do for `strata pr create`.
+	Stack string
+	From  string
+	Upto  string
+	// Jobs is how many layers run at once, each in its own worktree.
+	Jobs int
+	// KeepGoing runs the remaining layers after one fails instead of skipping them.
+	KeepGoing bool
+	// NoCache reruns layers whose commit already has a result for the command.
+	NoCache bool
+	// CacheFailures caches failed results too. By default only passes are, since a failure
+	// may come from the environment (a flaky test, a missing tool) rather than the commit.
+	CacheFailures bool
+	// Report, if set, receives a line as each layer finishes.
+	Report func(msg string)
+}
+
+func (o ExecOptions) report(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	logs.Info("%s", msg)
+	if o.Report != nil {
+		o.Report(msg)
+	}
+}
+
+// ExecLayerResult is how the command went on one layer.
+type ExecLayerResult struct {
+	Branch   string
+	Commit   string
+	Status   string
+	ExitCode int
+	Duration time.Duration
+	// Cached is set when the result was taken from an earlier run on the same commit.
+	Cached bool
+	// Log is the file holding the command's output; empty when it didn't run this time.
+	Log string
+	// Reason explains a skipped layer or a command that couldn't be started.
+	Reason string
+}
+
+// ExecReport is the pass/fail matrix of `strata exec`, parents first.
+type ExecReport struct {
+	Command string
+	Layers  []ExecLayerResult
+}
+
+// Passed reports whether the command passed on every layer.
+func (r ExecReport) Passed() bool {
+	for _, l := range r.Layers {
+		if l.Status != ExecPassed {
+			return false
+		}
+	}
+	return true
+}
+
+// TestCommand is the configured test_command as an ExecOptions.Command, or nil.
+func (e *ExecService) TestCommand() []string {
+	if cmd := strings.TrimSpace(config.GetConfigValue(testCommandKey)); cmd != "" {
+		return []string{cmd}
+	}
+	return nil
+}
+
+// Exec runs the command on every selected layer, parents first, each checked out in a
+// throwaway worktree so the user's checkout is never touched. Up to opts.Jobs layers run
+// at once. After the first failure the layers not yet started are skipped, unless
+// opts.KeepGoing is set. Passes are cached per commit SHA and command, so unchanged layers
+// pass again without running; failures only with opts.CacheFailures. The command's output
+// goes to a log per layer under .git/strata/exec/.
+func (e *ExecService) Exec(opts ExecOptions) (ExecReport, error) {
+	report := ExecReport{Command: strings.Join(opts.Command, " ")}
+	if len(opts.Command) == 0 {
+		return report, fmt.Errorf("no command given; pass one after -- or set %s", testCommandKey)
+	}
+	s := GetStackService()
+	layers, err := s.layerRange(opts.Stack, opts.From, opts.Upto)
+	if err != nil {
+		return report, err
+	}
+	if len(layers) == 0 {
+		return report, fmt.Errorf("no layers to run on")
+	}
+	stateDir, err := store.StateDir()
+	if err != nil {
+		return report, err
+	}
+	logDir := filepath.Join(stateDir, "exec")
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		return report, fmt.Errorf("failed to create %s: %v", logDir, err)
+	}
+	cache, err := store.LoadExecCache()
+	if err != nil {
+		logs.Warn("Ignoring unreadable exec cache: %v", err)
+	}
+
+	report.Layers = make([]ExecLayerResult, len(layers))
+	for i, br := range layers {
+		report.Layers[i] = ExecLayerResult{Branch: br, Status: ExecSkipped}
+		if report.Layers[i].Commit, err = git.RevParse("refs/heads/" + br); err != nil {
+			report.Layers[i].Reason = "branch not found"
+		}
+	}
+
+	jobs := opts.Jobs
+	if jobs < 1 {
+		jobs = 1
+	}
+	if jobs > len(layers) {
+		jobs = len(layers)
+	}
+	pool, err := newWorktreePool(jobs)
+	if err != nil {
+		return report, err
+	}
+	defer pool.close()
+
+	var mu sync.Mutex
+	stopped := false
+	finish := func(i int, res ExecLayerResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		report.Layers[i] = res
+		if res.Status == ExecFailed && !opts.KeepGoing {
+			stopped = true
+		}
+		opts.report("%s", execProgressLine(res))
+	}
+
+	var wg sync.WaitGroup
+	for i, br := range layers {
+		res := report.Layers[i]
+		mu.Lock()
+		stop := stopped
+		mu.Unlock()
+		if stop {
+			report.Layers[i].Reason = "an earlier layer failed"
+			continue
+		}
+		if res.Commit == "" {
+			finish(i, res)
+			continue
+		}
+		if cached, ok := cache.Results[res.Commit][report.Command]; ok && !opts.NoCache && (cached.Passed || opts.CacheFailures) {
+			res.Status, res.ExitCode, res.Duration, res.Cached = ExecFailed, cached.ExitCode, cached.Duration, true
+			if cached.Passed {
+				res.Status = ExecPassed
+			}
+			finish(i, res)
+			continue
+		}
+
+		dir, err := pool.get()
+		if err != nil {
+			wg.Wait()
+			return report, err
+		}
+		// a layer may have failed while we waited for a worktree
+		mu.Lock()
+		stop = stopped
+		mu.Unlock()
+		if stop {
+			pool.put(dir)
+			report.Layers[i].Reason = "an earlier layer failed"
+			continue
+		}
+		res.Log = filepath.Join(logDir, strings.ReplaceAll(br, "/", "-")+".log")
+		wg.Add(1)
+		go func(i int, res ExecLayerResult) {
+			defer wg.Done()
+			defer pool.put(dir)
+			res = runLayer(dir, res, opts.Command, s.stack[res.Branch].ParentBranch)
+			finish(i, res)
+		}(i, res)
+	}
+	wg.Wait()
+
+	for _, res := range report.Layers {
+		if res.Cached || res.Log == "" || res.Reason != "" || (res.Status != ExecPassed && !opts.CacheFailures) {
+			continue
+		}
+		if cache.Results[res.Commit] == nil {
+			cache.Results[res.Commit] = map[string]model.ExecResult{}
+		}
+		cache.Results[res.Commit][report.Command] = model.ExecResult{
+			Passed: res.Status == ExecPassed, ExitCode: res.ExitCode, Duration: res.Duration, RanAt: time.Now(),
+		}
+	}
+	pruneExecCache(cache)
+	if err := store.SaveExecCache(cache); err != nil {
+		logs.Warn("Could not save exec results: %v", err)
+	}
+
+	if report.Passed() {
+		return report, nil
+	}
+	failed := []string{}
+	for _, res := range report.Layers {
+		if res.Status == ExecFailed {
+			failed = append(failed, res.Branch)
+		}
+	}
+	if len(failed) == 0 {
+		return report, fmt.Errorf("'%s' did not run on every layer", report.Command)
+	}
+	return report, fmt.Errorf("'%s' failed on %s: %s", report.Command, pluralize(len(failed), "layer"), strings.Join(failed, ", "))
+}
+
+// runLayer checks out res.Commit in dir and runs the command there, with the layer's name,
+// parent and commit in STRATA_LAYER, STRATA_PARENT and STRATA_COMMIT.
+func runLayer(dir string, res ExecLayerResult, command []string, parent string) ExecLayerResult {
+	res.Status = ExecFailed
+	if err := git.ResetWorktree(dir, res.Commit); err != nil {
+		res.Reason = err.Error()
+		res.Log = ""
+		return res
+	}
+	logFile, err := os.Create(res.Log)
+	if err != nil {
+		res.Reason = fmt.Sprintf("failed to create %s: %v", res.Log, err)
+		res.Log = ""
+		return res
+	}
+	defer logFile.Close()
+
+	var cmd *exec.Cmd
+	if len(command) == 1 {
+		cmd = exec.Command("sh", "-c", command[0])
+	} else {
+		cmd = exec.Command(command[0], command[1:]...)
+	}
+	cmd.Dir = dir
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.Env = append(os.Environ(), "STRATA_LAYER="+res.Branch, "STRATA_PARENT="+parent, "STRATA_COMMIT="+res.Commit)
+
+	logs.Debug("Running '%s' on '%s' (%s) in %s", strings.Join(command, " "), res.Branch, shortHash(res.Commit), dir)
+	start := time.Now()
+	err = cmd.Run()
+	res.Duration = time.Since(start).Round(10 * time.Millisecond)
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		res.Status = ExecPassed
+	case errors.As(err, &exitErr):
+		res.ExitCode = exitErr.ExitCode()
+	default:
+		// the command couldn't be started; report it like a shell would
+		res.ExitCode = 127
+		fmt.Fprintln(logFile, err)
+	}
+	return res
+}
+
+func execProgressLine(res ExecLayerResult) string {
+	switch {
+	case res.Status == ExecSkipped:
+		return fmt.Sprintf("- %s skipped: %s", res.Branch, res.Reason)
+	case res.Cached:
+		return fmt.Sprintf("%s %s (%s) %s, cached", execMark(res), res.Branch, shortHash(res.Commit), res.Status)
+	case res.Reason != "":
+		return fmt.Sprintf("%s %s (%s) %s: %s", execMark(res), res.Branch, shortHash(res.Commit), res.Status, res.Reason)
+	}
+	return fmt.Sprintf("%s %s (%s) %s in %s", execMark(res), res.Branch, shortHash(res.Commit), res.Status, res.Duration)
+}
+
+func execMark(res ExecLayerResult) string {
+	switch res.Status {
+	case ExecPassed:
+		return ui.Colorize("✓", ui.FgGreen)
+	case ExecFailed:
+		return ui.Colorize("✗", ui.FgRed)
+	}
+	return "-"
+}
+
+// pruneExecCache drops results older than execCacheMaxAge so the cache doesn't grow forever.
+func pruneExecCache(cache *model.ExecCache) {
+	for sha, results := range cache.Results {
+		for command, r := range results {
+			if time.Since(r.RanAt) > execCacheMaxAge {
+				delete(results, command)
+			}
+		}
+		if len(results) == 0 {
+			delete(cache.Results, sha)
+		}
+	}
+}
+
+// RenderExecReport lists each layer's result, followed by the end of each failed layer's log.
+func RenderExecReport(r ExecReport) string {
+	rows := [][]string{{"LAYER", "COMMIT", "RESULT", "TIME"}}
+	for _, l := range r.Layers {
+		result, took := strings.ToUpper(l.Status), ""
+		switch {
+		case l.Status == ExecSkipped:
+			took = l.Reason
+		case l.Cached:
+			took = "cached"
+		case l.Reason != "":
+			took = l.Reason
+		default:
+			took = l.Duration.String()
+		}
+		if l.Status == ExecFailed && l.ExitCode != 0 {
+			result += fmt.Sprintf(" (exit %d)", l.ExitCode)
+		}
+		rows = append(rows, []string{l.Branch, shortHash(l.Commit), result, strings.SplitN(took, "\n", 2)[0]})
+	}
+	lines := alignRows(rows)
+	var b strings.Builder
+	b.WriteString(ui.Colorize(lines[0], ui.Bold) + "\n")
+	for i, l := range r.Layers {
+		line := lines[i+1]
+		switch l.Status {
+		case ExecPassed:
+			line = ui.Colorize(line, ui.FgGreen)
+		case ExecFailed:
+			line = ui.Colorize(line, ui.FgRed)
+		default:
+			line = ui.Colorize(line, ui.Dim)
+		}
+		b.WriteString(line + "\n")
+	}
+	for _, l := range r.Layers {
+		if l.Status != ExecFailed || l.Log == "" {
+			continue
+		}
+		b.WriteString(fmt.Sprintf("\n%s %s:\n", ui.Colorize("Output of", ui.Bold), l.Branch))
+		for _, line := range logTail(l.Log, 20) {
+			b.WriteString("  " + line + "\n")
+		}
+		b.WriteString(ui.Colorize("  full log: "+l.Log, ui.Dim) + "\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func logTail(path string, n int) []string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	trimmed := strings.TrimRight(string(content), "\n")
+	if trimmed == "" {
+		return []string{"(no output)"}
+	}
+	lines := strings.Split(trimmed, "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
+
+// worktreePool hands out throwaway worktrees, created on first use under a temporary
+// directory and removed by close.
+type worktreePool struct {
+	root  string
+	max   int
+	made  []string
+	free  chan string
+	mu    sync.Mutex
+	added int
+}
+
+func newWorktreePool(max int) (*worktreePool, error) {
+	root, err := os.MkdirTemp("", "strata-exec-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a directory for worktrees: %v", err)
+	}
+	return &worktreePool{root: root, max: max, free: make(chan string, max)}, nil
+}
+
+// get returns a free worktree, creating one while fewer than max exist and waiting for
+// one to be put back otherwise.
+func (p *worktreePool) get() (string, error) {
+	select {
+	case dir := <-p.free:
+		return dir, nil
+	default:
+	}
+	p.mu.Lock()
+	if p.added < p.max {
+		p.added++
+		dir := filepath.Join(p.root, fmt.Sprintf("wt%d", p.added))
+		p.mu.Unlock()
+		if err := git.AddWorktree(dir, "HEAD"); err != nil {
+			return "", err
+		}
+		p.mu.Lock()
+		p.made = append(p.made, dir)
+		p.mu.Unlock()
+		return dir, nil
+	}
+	p.mu.Unlock()
+	return <-p.free, nil
+}
+
+func (p *worktreePool) put(dir string) {
+	p.free <- dir
+}
+
+func (p *worktreePool) close() {
+	for _, dir := range p.made {
+		if err := git.RemoveWorktree(dir); err != nil {
+			logs.Warn("%v", err)
+		}
+	}
+	os.RemoveAll(p.root)
+}
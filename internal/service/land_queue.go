@@ -0,0 +1,186 @@
+package service
+
+import (
+	"fmt"
+	"strata/internal/errs"
+	"strata/internal/forge"
+	"strata/internal/logs"
+	"strata/internal/model"
+	"strata/internal/store"
+	"strata/internal/utils"
+	"time"
+)
+
+// LandQueue drains target's chain through the forge's merge queue: it enqueues the bottom
+// PR, follows it until it merges, restacks the layers above and enqueues the next one.
+// Progress is saved to store.LandRunFileName after every step, the restack after a merge
+// included, and calling LandQueue again resumes a saved run (target must then be empty or
+// match). When the queue ejects a PR the run stops with errs.Blocked and the next call
+// re-enqueues it.
+func (l *LandService) LandQueue(target string, opts LandOptions) (*model.LandRun, error) {
+	run, err := store.LoadLandRun()
+	if err != nil {
+		return nil, err
+	}
+	if run != nil {
+		if target != "" && target != run.Target {
+			return run, fmt.Errorf("a merge queue run for '%s' is in progress; resume it or drop it with --abort", run.Target)
+		}
+		if opts.Method == "" {
+			opts.Method = run.Method
+		}
+		opts.report("Resuming merge queue run for '%s': %d landed, %d to go", run.Target, len(run.Landed), len(run.Remaining))
+	} else {
+		chain, err := l.landChain(target)
+		if err != nil {
+			return nil, err
+		}
+		run = &model.LandRun{Target: chain[len(chain)-1], Remaining: chain, StartedAt: time.Now()}
+	}
+	opts, err = l.withDefaults(opts)
+	if err != nil {
+		return run, err
+	}
+	run.Method = opts.Method
+
+	fg, err := GetPRService().forge()
+	if err != nil {
+		return run, err
+	}
+	mq, ok := fg.(forge.MergeQueue)
+	if !ok {
+		return run, fmt.Errorf("%s has no merge queue; land without --queue", fg.Name())
+	}
+	defer l.restoreCheckout(utils.CurrentBranch())
+
+	save := func() error {
+		run.UpdatedAt = time.Now()
+		return store.SaveLandRun(run)
+	}
+	if err := save(); err != nil {
+		return run, err
+	}
+
+	s := GetStackService()
+	for len(run.Remaining) > 0 {
+		br := run.Remaining[0]
+		if run.Restack == nil {
+			if s.stack[br] == nil {
+				// an earlier attempt merged and restacked it but didn't get to save that
+				logs.Warn("'%s' already left the stack; skipping it", br)
+				run.Landed = append(run.Landed, br)
+				run.Remaining, run.Number, run.EnqueuedAt, run.Ejected = run.Remaining[1:], 0, time.Time{}, ""
+				if err := save(); err != nil {
+					return run, err
+				}
+				continue
+			}
+			if err := l.mergeQueued(mq, run, br, opts, save); err != nil {
+				return run, err
+			}
+			// saved before anything moves, so a restack that stops halfway is finished on resume
+			restack, err := l.planRestack(br)
+			if err != nil {
+				return run, err
+			}
+			run.Restack = restack
+			if err := save(); err != nil {
+				return run, err
+			}
+		} else {
+			opts.report("Finishing the restack of the layers above '%s'", br)
+		}
+
+		if err := l.restack(run.Restack, opts, save); err != nil {
+			return run, fmt.Errorf("%w; fix it and run `strata land --queue` to finish restacking", err)
+		}
+		run.Landed = append(run.Landed, br)
+		run.Remaining, run.Number, run.EnqueuedAt, run.Restack = run.Remaining[1:], 0, time.Time{}, nil
+		if err := save(); err != nil {
+			return run, err
+		}
+	}
+
+	return run, store.ClearLandRun()
+}
+
+// mergeQueued enqueues br's PR unless it is queued already and follows it until it merges.
+// When the queue ejects it, the reason is saved and the run stops with errs.Blocked.
+func (l *LandService) mergeQueued(mq forge.MergeQueue, run *model.LandRun, br string, opts LandOptions, save func() error) error {
+	if run.Number == 0 || run.Ejected != "" {
+		_, info, err := l.prepare(br, opts)
+		if err != nil {
+			return err
+		}
+		if run.Ejected != "" {
+			opts.report("Re-enqueueing PR #%d (%s), ejected before: %s", info.Number, br, run.Ejected)
+		}
+		// taken before enqueueing, so no removal from this attempt can predate it
+		enqueuedAt := time.Now()
+		if err := mq.Enqueue(info.Number, opts.Method); err != nil {
+			return fmt.Errorf("failed to enqueue PR #%d: %w", info.Number, err)
+		}
+		run.Number, run.EnqueuedAt, run.Ejected = info.Number, enqueuedAt, ""
+		if err := save(); err != nil {
+			return err
+		}
+		opts.report("Enqueued PR #%d (%s)", info.Number, br)
+	}
+
+	entry, err := l.followQueue(mq, br, run.Number, run.EnqueuedAt, opts)
+	if err != nil {
+		return err
+	}
+	if entry.State == forge.QueueEjected {
+		run.Ejected = entry.Reason
+		if run.Ejected == "" {
+			run.Ejected = "no reason given"
+		}
+		if err := save(); err != nil {
+			return err
+		}
+		return errs.Blocked("PR #%d (%s) was ejected from the merge queue: %s; fix it and run `strata land --queue` to resume",
+			run.Number, br, run.Ejected)
+	}
+	return nil
+}
+
+// AbortLandQueue forgets the saved merge queue run. PRs already in the queue stay there.
+func (l *LandService) AbortLandQueue() (*model.LandRun, error) {
+	run, err := store.LoadLandRun()
+	if err != nil || run == nil {
+		return run, err
+	}
+	return run, store.ClearLandRun()
+}
+
+// followQueue polls a queued PR until it merges or is ejected, reporting position changes.
+func (l *LandService) followQueue(mq forge.MergeQueue, branch string, number int, enqueuedAt time.Time, opts LandOptions) (forge.QueueEntry, error) {
+	deadline := time.Now().Add(opts.Timeout)
+	lastPos := -1
+	for {
+		entry, err := mq.QueueState(number, enqueuedAt)
+		if err != nil {
+			return entry, fmt.Errorf("failed to get queue state of PR #%d: %w", number, err)
+		}
+		switch entry.State {
+		case forge.QueueMerged:
+			opts.report("PR #%d (%s) merged by the queue", number, branch)
+			return entry, nil
+		case forge.QueueEjected:
+			return entry, nil
+		}
+		if entry.Position != lastPos {
+			if entry.Position > 0 {
+				opts.report("PR #%d (%s) is queued at position %d", number, branch, entry.Position)
+			} else {
+				opts.report("PR #%d (%s) is in the merge queue", number, branch)
+			}
+			lastPos = entry.Position
+		}
+		if time.Now().After(deadline) {
+			return entry, fmt.Errorf("timed out waiting for PR #%d (%s) in the merge queue; run `strata land --queue` to keep following it", number, branch)
+		}
+		time.Sleep(opts.Interval)
+	}
+}
@@ -0,0 +1,365 @@
+package service
+
+import (
+	"fmt"
+	"strata/internal/config"
+	"strata/internal/errs"
+	"strata/internal/forge"
+	"strata/internal/git"
+	"strata/internal/hooks"
+	"strata/internal/logs"
+	"strata/internal/model"
+	"strata/internal/store"
+	"strata/internal/utils"
+	"strings"
+	"time"
+)
+
+// landMethodConfigKey picks how `strata land` merges PRs: merge (default), squash or rebase.
+const landMethodConfigKey = "land_method"
+
+// LandOptions tunes how `strata land` merges and waits.
+type LandOptions struct {
+	// Method is merge, squash or rebase; empty falls back to land_method.
+	Method string
+	// Timeout bounds the wait for pending checks and for each merge to show up.
+	Timeout time.Duration
+	// Interval is how often the forge is polled while waiting.
+	Interval time.Duration
+	// Report, if set, receives a line for every step so long runs can show progress.
+	Report func(msg string)
+}
+
+func (o LandOptions) report(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	logs.Info("%s", msg)
+	if o.Report != nil {
+		o.Report(msg)
+	}
+}
+
+type LandService struct{}
+
+var landSvc *LandService
+
+func GetLandService() *LandService {
+	if landSvc == nil {
+		landSvc = &LandService{}
+	}
+	return landSvc
+}
+
+// Land merges the PRs from the bottom of target's chain up to and including target
+// through the forge, one at a time. After each merge the layers stacked on the landed
+// one are rebased onto the trunk, force-pushed and their PRs retargeted, so the next
+// PR becomes the bottom of the stack. It stops with errs.Blocked on the first PR that
+// can't be merged. The branches landed so far are returned either way.
+func (l *LandService) Land(target string, opts LandOptions) ([]string, error) {
+	chain, err := l.landChain(target)
+	if err != nil {
+		return nil, err
+	}
+	opts, err = l.withDefaults(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer l.restoreCheckout(utils.CurrentBranch())
+
+	landed := []string{}
+	for _, br := range chain {
+		fg, info, err := l.prepare(br, opts)
+		if err != nil {
+			return landed, err
+		}
+		opts.report("Merging PR #%d (%s) with %s", info.Number, br, opts.Method)
+		if err := fg.Merge(info.Number, opts.Method); err != nil {
+			return landed, fmt.Errorf("failed to merge PR #%d: %w", info.Number, err)
+		}
+		if err := l.waitLanded(fg, br, info.Number, opts); err != nil {
+			return landed, err
+		}
+		if err := l.afterMerge(br, opts); err != nil {
+			return landed, err
+		}
+		landed = append(landed, br)
+	}
+	return landed, nil
+}
+
+// landChain resolves target (default: the current branch) and returns it with every layer
+// below it down to the trunk, bottom first.
+func (l *LandService) landChain(target string) ([]string, error) {
+	s := GetStackService()
+	if target == "" {
+		target = utils.CurrentBranch()
+	}
+	if _, ok := s.stack[target]; !ok {
+		return nil, errs.NotInStack("branch '%s' not in stack", target)
+	}
+	if s.IsTrunk(target) {
+		return nil, fmt.Errorf("'%s' is a trunk; pass the layer to land", target)
+	}
+
+	// walk the parents until the trunk, then reverse
+	chain := []string{}
+	visited := map[string]bool{}
+	for cur := target; cur != "" && !visited[cur] && !s.IsTrunk(cur); {
+		visited[cur] = true
+		node := s.stack[cur]
+		if node == nil {
+			break
+		}
+		chain = append([]string{cur}, chain...)
+		cur = node.ParentBranch
+	}
+	return chain, nil
+}
+
+func (l *LandService) withDefaults(opts LandOptions) (LandOptions, error) {
+	if opts.Method == "" {
+		opts.Method = config.GetConfigValue(landMethodConfigKey)
+	}
+	if opts.Method == "" {
+		opts.Method = "merge"
+	}
+	if opts.Method != "merge" && opts.Method != "squash" && opts.Method != "rebase" {
+		return opts, fmt.Errorf("unknown land method '%s' (want merge, squash or rebase)", opts.Method)
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = 15 * time.Second
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Minute
+	}
+	return opts, nil
+}
+
+func (l *LandService) restoreCheckout(original string) {
+	if utils.CurrentBranch() != original && git.RefExists("refs/heads/"+original) {
+		if err := git.CheckoutBranch(original); err != nil {
+			logs.Warn("Could not switch back to '%s': %v", original, err)
+		}
+	}
+}
+
+// prepare finds the open PR of a bottom layer, retargets it to the trunk if needed and
+// waits until it can be merged.
+func (l *LandService) prepare(branch string, opts LandOptions) (forge.Forge, branchPRInfo, error) {
+	s := GetStackService()
+	p := GetPRService()
+	fg, err := p.forge()
+	if err != nil {
+		return nil, branchPRInfo{}, err
+	}
+	trunk := s.TrunkFor(branch)
+
+	prMap, err := p.getBranchPRMap(model.StackTree{branch: s.stack[branch]})
+	if err != nil {
+		return nil, branchPRInfo{}, fmt.Errorf("failed to get PR for '%s': %w", branch, err)
+	}
+	info, ok := prMap[branch]
+	if !ok {
+		return nil, branchPRInfo{}, errs.Blocked("'%s' has no open PR to land", branch)
+	}
+	if info.Base != trunk {
+		opts.report("Retargeting PR #%d (%s) to '%s'", info.Number, branch, trunk)
+		if err := fg.SetBase(info.Number, trunk); err != nil {
+			return nil, branchPRInfo{}, fmt.Errorf("failed to retarget PR #%d: %w", info.Number, err)
+		}
+	}
+	if err := l.waitMergeable(fg, branch, info, trunk, opts); err != nil {
+		return nil, branchPRInfo{}, err
+	}
+	return fg, info, nil
+}
+
+// afterMerge moves everything stacked on a freshly merged layer onto the trunk: the
+// trunk is fast-forwarded, the layers above are rebased, force-pushed and their PRs
+// retargeted, and the landed layer leaves the stack.
+func (l *LandService) afterMerge(branch string, opts LandOptions) error {
+	r, err := l.planRestack(branch)
+	if err != nil {
+		return err
+	}
+	return l.restack(r, opts, func() error { return nil })
+}
+
+// planRestack notes where the merged branch and every layer above it sit, before anything
+// moves, to replay only their own commits later.
+func (l *LandService) planRestack(branch string) (*model.LandRestack, error) {
+	s := GetStackService()
+	r := &model.LandRestack{
+		Branch:   branch,
+		Trunk:    s.TrunkFor(branch),
+		Children: append([]string{}, s.stack[branch].Children...),
+		OldTips:  map[string]string{},
+	}
+	for _, br := range s.stack.Descendants(branch) {
+		sha, err := git.RevParse(br)
+		if err != nil {
+			return nil, err
+		}
+		r.OldTips[br] = sha
+	}
+	return r, nil
+}
+
+// restack carries out the restack r planned, calling saved after every layer it pushes.
+// It can be run again after failing partway: the layers already pushed are skipped, and
+// so are the rebases already done.
+func (l *LandService) restack(r *model.LandRestack, opts LandOptions, saved func() error) error {
+	s := GetStackService()
+	p := GetPRService()
+	fg, err := p.forge()
+	if err != nil {
+		return err
+	}
+	branch, trunk := r.Branch, r.Trunk
+
+	if err := git.FetchPrune(); err != nil {
+		return err
+	}
+	if git.RefExists("refs/heads/" + trunk) {
+		if err := git.FastForwardBranch(trunk, "origin/"+trunk); err != nil {
+			return err
+		}
+	}
+
+	if s.stack[branch] != nil {
+		s.detachNode(branch, trunk)
+		if err := store.SaveStack(s.stack); err != nil {
+			return err
+		}
+	}
+
+	pushed := map[string]bool{}
+	for _, br := range r.Pushed {
+		pushed[br] = true
+	}
+	// parents first, so every layer is replayed onto its already rewritten parent
+	for _, child := range r.Children {
+		for _, br := range s.stack.Descendants(child) {
+			if pushed[br] {
+				continue
+			}
+			parent := s.stack[br].ParentBranch
+			upstream := r.OldTips[parent]
+			onto := parent
+			if parent == trunk {
+				upstream, onto = r.OldTips[branch], "origin/"+trunk
+			}
+			// a layer already on its new base was rebased before an earlier attempt stopped
+			if !git.IsAncestor(onto, br) {
+				opts.report("Restacking '%s' onto '%s'", br, parent)
+				if err := git.RebaseOnto(br, onto, upstream); err != nil {
+					return fmt.Errorf("restack of '%s' failed: %w", br, err)
+				}
+			}
+			if err := git.PushBranch(br, true); err != nil {
+				return err
+			}
+			s.stack[br].UpdatedAt = time.Now()
+			r.Pushed = append(r.Pushed, br)
+			if err := saved(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(r.Children) > 0 {
+		scope := model.StackTree{}
+		for _, child := range r.Children {
+			scope[child] = s.stack[child]
+		}
+		childPRs, err := p.getBranchPRMap(scope)
+		if err != nil {
+			return fmt.Errorf("failed to get PR map: %w", err)
+		}
+		for _, child := range r.Children {
+			if cp, ok := childPRs[child]; ok && cp.Base != trunk {
+				opts.report("Retargeting PR #%d (%s) to '%s'", cp.Number, child, trunk)
+				if err := fg.SetBase(cp.Number, trunk); err != nil {
+					return fmt.Errorf("failed to retarget PR #%d: %w", cp.Number, err)
+				}
+			}
+		}
+	}
+
+	if utils.CurrentBranch() == branch {
+		if err := git.CheckoutBranch(trunk); err != nil {
+			return err
+		}
+	}
+	if git.RefExists("refs/heads/" + branch) {
+		if err := git.DeleteBranch(branch); err != nil {
+			logs.Warn("Landed '%s' but could not delete the local branch: %v", branch, err)
+		}
+	}
+	if err := store.SaveStack(s.stack); err != nil {
+		return err
+	}
+	opts.report("Landed '%s' on '%s'", branch, trunk)
+	hooks.RunHooks("landLayer", branch)
+	return nil
+}
+
+// waitMergeable polls the PR until nothing but pending checks blocks it, failing fast on
+// anything a wait won't fix.
+func (l *LandService) waitMergeable(fg forge.Forge, branch string, info branchPRInfo, trunk string, opts LandOptions) error {
+	deadline := time.Now().Add(opts.Timeout)
+	for {
+		fs, err := fg.Status(info.Number)
+		if err != nil {
+			return fmt.Errorf("failed to get status of PR #%d: %w", info.Number, err)
+		}
+		st := LayerPRStatus{Branch: branch, PRNumber: info.Number, Draft: info.State == "DRAFT", Base: trunk, ExpectedBase: trunk}
+		GetPRService().applyForgeStatus(&st, fs)
+
+		waiting := false
+		hard := []string{}
+		for _, b := range st.Blockers {
+			if b == "checks pending" {
+				waiting = true
+			} else {
+				hard = append(hard, b)
+			}
+		}
+		if len(hard) > 0 {
+			return errs.Blocked("cannot land '%s' (#%d): %s", branch, info.Number, strings.Join(hard, ", "))
+		}
+		if !waiting {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return errs.Blocked("timed out waiting for PR #%d (%s) to become mergeable", info.Number, branch)
+		}
+		opts.report("Waiting for PR #%d (%s): checks pending", info.Number, branch)
+		time.Sleep(opts.Interval)
+	}
+}
+
+// waitLanded polls until the PR has left the open state, and makes sure it did so by being
+// merged. A PR closed without merging stops the land before anything is rebased, pushed or
+// deleted.
+func (l *LandService) waitLanded(fg forge.Forge, branch string, number int, opts LandOptions) error {
+	deadline := time.Now().Add(opts.Timeout)
+	for {
+		latest, err := fg.LatestChanges([]string{branch})
+		if err != nil {
+			return err
+		}
+		cr, ok := latest[branch]
+		switch {
+		case !ok || cr.Number != number:
+			return errs.Blocked("PR #%d (%s) is no longer the branch's PR; not landing it", number, branch)
+		case cr.State == forge.StateMerged:
+			return nil
+		case cr.State != forge.StateOpen:
+			return errs.Blocked("PR #%d (%s) was closed without being merged", number, branch)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for PR #%d (%s) to merge", number, branch)
+		}
+		time.Sleep(opts.Interval)
+	}
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strata/internal/errs"
+	"strata/internal/hooks"
+	"strata/internal/model"
+	"strata/internal/store"
+	"strata/internal/utils"
+	"strings"
+	"time"
+)
+
+// Stacks returns the named stacks, including archived ones.
+func (s *StackService) Stacks() model.Stacks {
+	return s.stacks
+}
+
+// StackNames returns the names of all stacks, sorted. Archived stacks are only included when requested.
+func (s *StackService) StackNames(includeArchived bool) []string {
+	names := []string{}
+	for name, st := range s.stacks {
+		if st.Archived && !includeArchived {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetNamedStack returns the metadata of a stack.
+func (s *StackService) GetNamedStack(name string) (*model.Stack, error) {
+	st, ok := s.stacks[name]
+	if !ok {
+		return nil, fmt.Errorf("stack '%s' does not exist; see `strata stack list`", name)
+	}
+	return st, nil
+}
+
+// activeStack is like GetNamedStack but refuses archived stacks.
+func (s *StackService) activeStack(name string) (*model.Stack, error) {
+	st, err := s.GetNamedStack(name)
+	if err != nil {
+		return nil, err
+	}
+	if st.Archived {
+		return nil, fmt.Errorf("stack '%s' is archived", name)
+	}
+	return st, nil
+}
+
+// StackLayers returns the layers belonging to the named stack.
+func (s *StackService) StackLayers(name string) (model.StackTree, error) {
+	if _, err := s.activeStack(name); err != nil {
+		return nil, err
+	}
+	return s.stack.Filter(name), nil
+}
+
+// layerRange returns the layers of the named stack (or of every stack when stackName is
+// empty), parents first and without trunks. from keeps that layer and the ones stacked on
+// it, upto that layer and the ones below it.
+func (s *StackService) layerRange(stackName, from, upto string) ([]string, error) {
+	scope := s.stack
+	if stackName != "" {
+		var err error
+		if scope, err = s.StackLayers(stackName); err != nil {
+			return nil, err
+		}
+	}
+
+	keep := map[string]bool{}
+	for br := range scope {
+		keep[br] = true
+	}
+	if from != "" {
+		if _, ok := scope[from]; !ok {
+			return nil, errs.NotInStack("branch '%s' not in stack", from)
+		}
+		above := map[string]bool{}
+		for _, br := range s.stack.Descendants(from) {
+			above[br] = true
+		}
+		for br := range keep {
+			keep[br] = above[br]
+		}
+	}
+	if upto != "" {
+		if _, ok := scope[upto]; !ok {
+			return nil, errs.NotInStack("branch '%s' not in stack", upto)
+		}
+		below := map[string]bool{}
+		for cur := upto; cur != "" && s.stack[cur] != nil && !below[cur]; cur = s.stack[cur].ParentBranch {
+			below[cur] = true
+		}
+		for br := range keep {
+			keep[br] = keep[br] && below[br]
+		}
+	}
+
+	order := []string{}
+	for _, br := range scope.Topological() {
+		if keep[br] && scope[br].ParentBranch != "" && !s.IsTrunk(br) {
+			order = append(order, br)
+		}
+	}
+	return order, nil
+}
+
+// CreateStack registers a new named stack. If from is set, that branch and every layer
+// on top of it are moved into the new stack.
+func (s *StackService) CreateStack(name, description, trunk string, labels []string, from string) error {
+	if name == "" || strings.ContainsAny(name, " \t\n") {
+		return fmt.Errorf("invalid stack name '%s'", name)
+	}
+	if _, exists := s.stacks[name]; exists {
+		return fmt.Errorf("stack '%s' already exists", name)
+	}
+	if from != "" {
+		if _, ok := s.stack[from]; !ok {
+			return errs.NotInStack("branch '%s' not found in stack", from)
+		}
+	}
+
+	s.stacks[name] = &model.Stack{
+		Name:        name,
+		Description: description,
+		Owner:       utils.GetGithubUsername(),
+		Trunk:       trunk,
+		Labels:      labels,
+		CreatedAt:   time.Now(),
+	}
+
+	if from != "" {
+		for _, br := range s.stack.Descendants(from) {
+			s.stack[br].Stack = name
+			s.stack[br].UpdatedAt = time.Now()
+		}
+		if err := store.SaveStack(s.stack); err != nil {
+			return err
+		}
+	}
+
+	if err := store.SaveStacks(s.stacks); err != nil {
+		return err
+	}
+	hooks.RunHooks("createStack", name)
+	return nil
+}
+
+// registerMissingStacks creates bare metadata for stacks that layers reference but that
+// aren't known locally, e.g. after pulling a teammate's shared stack.
+func (s *StackService) registerMissingStacks() error {
+	added := false
+	for _, node := range s.stack {
+		if node.Stack == "" {
+			continue
+		}
+		if _, ok := s.stacks[node.Stack]; !ok {
+			s.stacks[node.Stack] = &model.Stack{Name: node.Stack, Owner: node.CreatedBy, CreatedAt: time.Now()}
+			added = true
+		}
+	}
+	if !added {
+		return nil
+	}
+	return store.SaveStacks(s.stacks)
+}
+
+// ArchiveStack hides a stack from listings and stack-scoped operations. Branches are left untouched.
+func (s *StackService) ArchiveStack(name string) error {
+	st, err := s.GetNamedStack(name)
+	if err != nil {
+		return err
+	}
+	if st.Archived {
+		return fmt.Errorf("stack '%s' is already archived", name)
+	}
+	st.Archived = true
+	if err := store.SaveStacks(s.stacks); err != nil {
+		return err
+	}
+	hooks.RunHooks("archiveStack", name)
+	return nil
+}
+
+// DescribeStack renders a stack's metadata followed by its layers.
+func (s *StackService) DescribeStack(name string) (string, error) {
+	st, err := s.GetNamedStack(name)
+	if err != nil {
+		return "", err
+	}
+	layers := s.stack.Filter(name)
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("Stack:       %s\n", st.Name))
+	if st.Archived {
+		b.WriteString("Status:      archived\n")
+	}
+	if st.Description != "" {
+		b.WriteString(fmt.Sprintf("Description: %s\n", st.Description))
+	}
+	if st.Owner != "" {
+		b.WriteString(fmt.Sprintf("Owner:       %s\n", st.Owner))
+	}
+	if st.Trunk != "" {
+		b.WriteString(fmt.Sprintf("Trunk:       %s\n", st.Trunk))
+	}
+	if len(st.Labels) > 0 {
+		b.WriteString(fmt.Sprintf("Labels:      %s\n", strings.Join(st.Labels, ", ")))
+	}
+	if !st.CreatedAt.IsZero() {
+		b.WriteString(fmt.Sprintf("Created:     %s\n", st.CreatedAt.Format("2006-01-02 15:04")))
+	}
+	b.WriteString(fmt.Sprintf("Layers:      %d\n", len(layers)))
+
+	visited := map[string]bool{}
+	for _, root := range layers.Roots() {
+		if p := layers[root].ParentBranch; p != "" {
+			b.WriteString(fmt.Sprintf("  (on %s)\n", p))
+		}
+		printNode(&b, layers, layers[root], 1, visited)
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"strata/internal/config"
+	"strata/internal/forge"
+	"strata/internal/logs"
+	"strata/internal/model"
+	"strata/internal/store"
+	"time"
+)
+
+// prCacheTTLKey sets how long cached PR metadata is trusted, e.g. "10m". Zero disables the cache.
+const prCacheTTLKey = "pr_cache_ttl"
+
+const defaultPRCacheTTL = 5 * time.Minute
+
+func prCacheTTL() time.Duration {
+	v := config.GetConfigValue(prCacheTTLKey)
+	if v == "" {
+		return defaultPRCacheTTL
+	}
+	ttl, err := time.ParseDuration(v)
+	if err != nil || ttl < 0 {
+		logs.Warn("Invalid %s '%s', using %s", prCacheTTLKey, v, defaultPRCacheTTL)
+		return defaultPRCacheTTL
+	}
+	return ttl
+}
+
+// cachedPRMap is the PR lookup of read-only commands (view, log, diagrams). It answers from
+// the cache in .git/strata/ while every branch of stack has an entry younger than the TTL,
+// and otherwise refreshes it from the forge. Merged and closed PRs are included, so layers
+// that already landed show as such. When the forge can't be reached the stale
+// entries are used, so these commands keep working offline.
+func (p *PRService) cachedPRMap(stack model.StackTree) map[string]branchPRInfo {
+	cache, err := store.LoadPRCache()
+	if err != nil {
+		logs.Warn("Ignoring unreadable PR cache: %v", err)
+	}
+	if prCacheStale(cache, stack, prCacheTTL()) {
+		if _, err := p.getLatestPRMap(stack); err != nil {
+			logs.Warn("Unable to refresh PR info, using cached data: %v", err)
+		} else if cache, err = store.LoadPRCache(); err != nil {
+			logs.Warn("Ignoring unreadable PR cache: %v", err)
+		}
+	}
+
+	prMap := map[string]branchPRInfo{}
+	for br := range stack {
+		if e, ok := cache.Branches[br]; ok && e.Number != 0 {
+			prMap[br] = branchPRInfo{URL: e.URL, State: e.State, Number: e.Number, Base: e.Base, Review: e.Review}
+		}
+	}
+	return prMap
+}
+
+// prCacheStale reports whether any branch of stack is missing from the cache or has expired.
+func prCacheStale(cache *model.PRCache, stack model.StackTree, ttl time.Duration) bool {
+	for br := range stack {
+		e, ok := cache.Branches[br]
+		if !ok || time.Since(e.FetchedAt) >= ttl {
+			return true
+		}
+	}
+	return false
+}
+
+// recordPRs writes a fresh forge lookup of branches through to the cache. With allStates,
+// the lookup covered merged and closed PRs too, and branches missing from prMap are recorded
+// as having no PR. A lookup of open PRs only can't tell, so a missing branch just loses a
+// cached open PR, to be looked up again on the next read. A known review decision is kept
+// as long as the branch still has the same PR.
+func recordPRs(branches []string, prMap map[string]branchPRInfo, allStates bool) {
+	now := time.Now()
+	err := store.UpdatePRCache(func(cache *model.PRCache) {
+		for _, br := range branches {
+			info, ok := prMap[br]
+			if !ok {
+				if allStates {
+					cache.Branches[br] = model.CachedPR{FetchedAt: now}
+				} else if old := cache.Branches[br]; old.State == forge.StateOpen || old.State == "DRAFT" {
+					delete(cache.Branches, br)
+				}
+				continue
+			}
+			review := info.Review
+			if old := cache.Branches[br]; review == "" && old.Number == info.Number {
+				review = old.Review
+			}
+			cache.Branches[br] = model.CachedPR{
+				Number: info.Number, URL: info.URL, State: info.State, Base: info.Base,
+				Review: review, FetchedAt: now,
+			}
+		}
+	})
+	if err != nil {
+		logs.Warn("Failed to update PR cache: %v", err)
+	}
+}
+
+// recordReviews stores review decisions, keyed by branch, fetched for PRs already in the cache.
+func recordReviews(reviews map[string]string) {
+	if len(reviews) == 0 {
+		return
+	}
+	err := store.UpdatePRCache(func(cache *model.PRCache) {
+		for br, review := range reviews {
+			if e, ok := cache.Branches[br]; ok {
+				e.Review = review
+				cache.Branches[br] = e
+			}
+		}
+	})
+	if err != nil {
+		logs.Warn("Failed to update PR cache: %v", err)
+	}
+}
+
+// PRCacheStale reports whether the PR cache is missing or out of date for any layer.
+func (p *PRService) PRCacheStale() bool {
+	stack, err := store.LoadStack()
+	if err != nil || len(stack) == 0 {
+		return false
+	}
+	cache, err := store.LoadPRCache()
+	if err != nil {
+		return true
+	}
+	return prCacheStale(cache, stack, prCacheTTL())
+}
+
+// RefreshPRCache fetches the latest PR of every layer, with the review decision of open
+// ones, into the cache.
+// It reads the stack from disk rather than the service's copy, since the daemon calls it
+// long after start-up.
+func (p *PRService) RefreshPRCache() error {
+	stack, err := store.LoadStack()
+	if err != nil {
+		return err
+	}
+	prMap, err := p.getLatestPRMap(stack)
+	if err != nil {
+		return err
+	}
+	fg, err := p.forge()
+	if err != nil {
+		return err
+	}
+	reviews := map[string]string{}
+	for br, info := range prMap {
+		if info.State != forge.StateOpen && info.State != "DRAFT" {
+			continue
+		}
+		st, err := fg.Status(info.Number)
+		if err != nil {
+			logs.Warn("Failed to get review status of PR #%d (%s): %v", info.Number, br, err)
+			continue
+		}
+		reviews[br] = st.Review
+	}
+	recordReviews(reviews)
+	logs.Info("Refreshed PR cache: %d of %d layers have a PR", len(prMap), len(stack))
+	return nil
+}
@@ -0,0 +1,259 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strata/internal/errs"
+	"strata/internal/forge"
+	"strata/internal/git"
+	"strata/internal/logs"
+	"strata/internal/model"
+	"strata/internal/ui"
+	"strata/internal/utils"
+	"strconv"
+	"strings"
+)
+
+// ReviewThread is a forge review thread with the ref used to address it from the CLI.
+type ReviewThread struct {
+	forge.Thread
+	// Ref is "<branch>:<n>", n counting the layer's threads in file and line order.
+	Ref string
+}
+
+// LayerThreads is the review feedback on one layer's PR.
+type LayerThreads struct {
+	Branch   string
+	PRNumber int
+	PRURL    string
+	Threads  []ReviewThread
+}
+
+// ReviewThreads fetches the review threads of the current layer's PR or, with all, of every
+// layer's PR, parents first. A non-empty stackName limits all to that named stack.
+// unresolvedOnly drops resolved threads; refs are numbered before filtering, so they don't
+// shift as threads get resolved.
+func (p *PRService) ReviewThreads(all bool, stackName string, unresolvedOnly bool) ([]LayerThreads, error) {
+	s := GetStackService()
+	stack := model.StackTree(s.GetStack())
+	var branches []string
+	switch {
+	case stackName != "":
+		layers, err := s.StackLayers(stackName)
+		if err != nil {
+			return nil, err
+		}
+		stack = layers
+		branches = stack.Topological()
+	case all:
+		branches = stack.Topological()
+	default:
+		curr := utils.CurrentBranch()
+		if _, ok := stack[curr]; !ok {
+			return nil, errs.NotInStack("branch '%s' not in stack", curr)
+		}
+		branches = []string{curr}
+		stack = model.StackTree{curr: stack[curr]}
+	}
+
+	prMap, err := p.getBranchPRMap(stack)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get PR map: %w", err)
+	}
+	fg, err := p.forge()
+	if err != nil {
+		return nil, err
+	}
+
+	layers := []LayerThreads{}
+	for _, br := range branches {
+		info, ok := prMap[br]
+		if !ok {
+			if !all && stackName == "" {
+				return nil, fmt.Errorf("no open PR for '%s'", br)
+			}
+			continue
+		}
+		threads, err := p.layerThreads(fg, br, info.Number)
+		if err != nil {
+			return nil, err
+		}
+		lt := LayerThreads{Branch: br, PRNumber: info.Number, PRURL: info.URL, Threads: []ReviewThread{}}
+		for _, t := range threads {
+			if !unresolvedOnly || !t.Resolved {
+				lt.Threads = append(lt.Threads, t)
+			}
+		}
+		layers = append(layers, lt)
+	}
+	return layers, nil
+}
+
+// layerThreads fetches and orders a PR's threads by file and line and assigns their refs.
+func (p *PRService) layerThreads(fg forge.Forge, branch string, number int) ([]ReviewThread, error) {
+	threads, err := fg.Threads(number)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get review threads of PR #%d (%s): %w", number, branch, err)
+	}
+	sort.SliceStable(threads, func(i, j int) bool {
+		if threads[i].Path != threads[j].Path {
+			return threads[i].Path < threads[j].Path
+		}
+		return threads[i].Line < threads[j].Line
+	})
+	out := make([]ReviewThread, len(threads))
+	for i, t := range threads {
+		out[i] = ReviewThread{Thread: t, Ref: fmt.Sprintf("%s:%d", branch, i+1)}
+	}
+	return out, nil
+}
+
+// findThread resolves a ref ("<branch>:<n>", or just "<n>" on the current branch) to the
+// layer's PR number and the thread.
+func (p *PRService) findThread(ref string) (int, ReviewThread, error) {
+	branch, n := utils.CurrentBranch(), ref
+	if i := strings.LastIndex(ref, ":"); i >= 0 {
+		branch, n = ref[:i], ref[i+1:]
+	}
+	idx, err := strconv.Atoi(n)
+	if err != nil || idx < 1 {
+		return 0, ReviewThread{}, fmt.Errorf("invalid thread ref '%s' (want <branch>:<n> or <n>)", ref)
+	}
+
+	fg, err := p.forge()
+	if err != nil {
+		return 0, ReviewThread{}, err
+	}
+	cr, err := p.openChange(fg, branch)
+	if err != nil {
+		return 0, ReviewThread{}, err
+	}
+	threads, err := p.layerThreads(fg, branch, cr.Number)
+	if err != nil {
+		return 0, ReviewThread{}, err
+	}
+	if idx > len(threads) {
+		return 0, ReviewThread{}, fmt.Errorf("PR #%d (%s) has %s", cr.Number, branch, pluralize(len(threads), "review thread"))
+	}
+	return cr.Number, threads[idx-1], nil
+}
+
+// ReplyToThread posts body as a reply in the thread ref points at.
+func (p *PRService) ReplyToThread(ref, body string) (ReviewThread, error) {
+	number, thread, err := p.findThread(ref)
+	if err != nil {
+		return thread, err
+	}
+	fg, err := p.forge()
+	if err != nil {
+		return thread, err
+	}
+	if err := fg.ReplyThread(number, thread.Thread, body); err != nil {
+		return thread, fmt.Errorf("failed to reply to %s: %w", ref, err)
+	}
+	logs.Info("Replied to thread %s on PR #%d", thread.Ref, number)
+	return thread, nil
+}
+
+// ResolveThread marks the thread ref points at as resolved.
+func (p *PRService) ResolveThread(ref string) (ReviewThread, error) {
+	number, thread, err := p.findThread(ref)
+	if err != nil {
+		return thread, err
+	}
+	fg, err := p.forge()
+	if err != nil {
+		return thread, err
+	}
+	if err := fg.ResolveThread(number, thread.Thread); err != nil {
+		return thread, fmt.Errorf("failed to resolve %s: %w", ref, err)
+	}
+	logs.Info("Resolved thread %s on PR #%d", thread.Ref, number)
+	return thread, nil
+}
+
+// JumpToThread opens $VISUAL/$EDITOR at the file and line the thread ref points at.
+// The file is opened as it is in the working tree, which may be another layer.
+func (p *PRService) JumpToThread(ref string) (ReviewThread, error) {
+	_, thread, err := p.findThread(ref)
+	if err != nil {
+		return thread, err
+	}
+	if thread.Path == "" {
+		return thread, fmt.Errorf("thread %s is not attached to a file", thread.Ref)
+	}
+	root, err := git.TopLevel()
+	if err != nil {
+		return thread, err
+	}
+	path := filepath.Join(root, thread.Path)
+
+	// EDITOR may carry arguments, e.g. "code --wait"
+	editor := editorCommand()
+	fields := strings.Fields(editor)
+	args := fields[1:]
+	switch filepath.Base(fields[0]) {
+	case "code", "code-insiders", "codium", "cursor":
+		args = append(args, "--goto", fmt.Sprintf("%s:%d", path, thread.Line))
+	case "subl", "zed":
+		args = append(args, fmt.Sprintf("%s:%d", path, thread.Line))
+	default:
+		// vi, vim, nvim, nano, emacs, micro, kak and friends
+		args = append(args, fmt.Sprintf("+%d", thread.Line), path)
+	}
+	cmd := exec.Command(fields[0], args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return thread, fmt.Errorf("editor '%s' failed: %v", editor, err)
+	}
+	return thread, nil
+}
+
+// RenderThreads formats review threads grouped by layer, then file and line.
+func RenderThreads(layers []LayerThreads) string {
+	if len(layers) == 0 {
+		return "No layers with pull requests."
+	}
+	var b strings.Builder
+	for i, lt := range layers {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		open := 0
+		for _, t := range lt.Threads {
+			if !t.Resolved {
+				open++
+			}
+		}
+		b.WriteString(ui.Colorize(fmt.Sprintf("%s  #%d", lt.Branch, lt.PRNumber), ui.Bold))
+		b.WriteString(fmt.Sprintf("  %s, %d unresolved  %s\n", pluralize(len(lt.Threads), "thread"), open, lt.PRURL))
+		for _, t := range lt.Threads {
+			loc := t.Path
+			if t.Line > 0 {
+				loc = fmt.Sprintf("%s:%d", t.Path, t.Line)
+			}
+			if loc == "" {
+				loc = "(general)"
+			}
+			state := ui.Colorize("unresolved", ui.FgRed)
+			if t.Resolved {
+				state = ui.Colorize("resolved", ui.FgGreen)
+			}
+			if t.Outdated {
+				state += " (outdated)"
+			}
+			b.WriteString(fmt.Sprintf("  %s  [%s] %s\n", loc, t.Ref, state))
+			for _, c := range t.Comments {
+				lines := strings.Split(strings.TrimSpace(c.Body), "\n")
+				b.WriteString(fmt.Sprintf("    %s: %s\n", ui.Colorize(c.Author, ui.FgCyan), lines[0]))
+				for _, l := range lines[1:] {
+					b.WriteString("      " + l + "\n")
+				}
+			}
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,290 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strata/internal/config"
+	"strata/internal/forge"
+	"strata/internal/git"
+	"strata/internal/logs"
+	"strings"
+)
+
+// PROptions controls how `strata pr create` opens and decorates pull requests.
+// Empty values fall back to the pr_* config defaults (see withDefaults).
+type PROptions struct {
+	All   bool
+	Stack string
+	// From and Upto narrow the layers to those at or above From and at or below Upto.
+	From string
+	Upto string
+
+	Draft     bool
+	Reviewers []string
+	Labels    []string
+	Assignees []string
+	Milestone string
+
+	// Edit opens $EDITOR on the generated title and body before a PR is created.
+	Edit bool
+}
+
+// Per-repo defaults, set with e.g. `strata config set pr_reviewers alice,bob`.
+const (
+	prDraftKey     = "pr_draft"
+	prReviewersKey = "pr_reviewers"
+	prLabelsKey    = "pr_labels"
+	prAssigneesKey = "pr_assignees"
+	prMilestoneKey = "pr_milestone"
+	prEditKey      = "pr_edit"
+
+	// prDiagramLocationKey is "body" (default) or "comment" for a single sticky PR comment.
+	prDiagramLocationKey = "pr_diagram_location"
+)
+
+// Markers delimiting the part of a PR body (or comment) that Strata regenerates.
+const (
+	strataBeginMarker = "<!-- strata:begin -->"
+	strataEndMarker   = "<!-- strata:end -->"
+	// legacyStrataIntro opened the whole body in Strata versions that predate the markers.
+	legacyStrataIntro = "This PR is part of a stacked workflow."
+	// strataParentMarker records the layer's parent so `strata ci` can rebuild the stack
+	// from PR bodies alone, even after a PR was retargeted.
+	strataParentMarker = "<!-- strata:parent=%s -->"
+)
+
+// prTemplatePaths are checked in the order GitHub itself uses.
+var prTemplatePaths = []string{
+	".github/pull_request_template.md",
+	".github/PULL_REQUEST_TEMPLATE.md",
+	"pull_request_template.md",
+	"PULL_REQUEST_TEMPLATE.md",
+	"docs/pull_request_template.md",
+	"docs/PULL_REQUEST_TEMPLATE.md",
+}
+
+func (o PROptions) withDefaults() PROptions {
+	if !o.Draft {
+		o.Draft = config.GetConfigValue(prDraftKey) == "true"
+	}
+	if !o.Edit {
+		o.Edit = config.GetConfigValue(prEditKey) == "true"
+	}
+	if len(o.Reviewers) == 0 {
+		o.Reviewers = splitConfigList(prReviewersKey)
+	}
+	if len(o.Labels) == 0 {
+		o.Labels = splitConfigList(prLabelsKey)
+	}
+	if len(o.Assignees) == 0 {
+		o.Assignees = splitConfigList(prAssigneesKey)
+	}
+	if o.Milestone == "" {
+		o.Milestone = config.GetConfigValue(prMilestoneKey)
+	}
+	return o
+}
+
+func splitConfigList(key string) []string {
+	out := []string{}
+	for _, v := range strings.Split(config.GetConfigValue(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// metadata returns the reviewer/label part of the options.
+func (o PROptions) metadata() forge.Metadata {
+	return forge.Metadata{
+		Reviewers: o.Reviewers,
+		Labels:    o.Labels,
+		Assignees: o.Assignees,
+		Milestone: o.Milestone,
+	}
+}
+
+// strataSection is the part of a PR body that Strata owns and regenerates.
+func strataSection(branch, stackDiagram string) string {
+	begin := strataBeginMarker
+	if node := GetStackService().stack[branch]; node != nil && node.ParentBranch != "" {
+		begin += "\n" + fmt.Sprintf(strataParentMarker, node.ParentBranch)
+	}
+	return fmt.Sprintf("%s\n%s\n\n%s\n%s", begin, legacyStrataIntro, strings.TrimRight(stackDiagram, "\n"), strataEndMarker)
+}
+
+// strataParent reads the parent recorded in a PR body's Strata section, or "" if there is none.
+func strataParent(body string) string {
+	begin := strings.Index(body, strataBeginMarker)
+	if begin < 0 {
+		return ""
+	}
+	prefix, _, _ := strings.Cut(strataParentMarker, "%s")
+	rest := body[begin:]
+	i := strings.Index(rest, prefix)
+	if i < 0 {
+		return ""
+	}
+	parent, _, ok := strings.Cut(rest[i+len(prefix):], " -->")
+	if !ok || strings.ContainsAny(parent, " \n") {
+		return ""
+	}
+	return parent
+}
+
+func diagramInComment() bool {
+	return config.GetConfigValue(prDiagramLocationKey) == "comment"
+}
+
+// spliceStrataSection replaces the marked Strata section of body with section. Bodies written
+// by older versions (entirely Strata-generated) are replaced wholesale; bodies without any
+// Strata content get the section appended so the author's text is never lost.
+func spliceStrataSection(body, section string) string {
+	begin := strings.Index(body, strataBeginMarker)
+	if begin >= 0 {
+		if end := strings.Index(body[begin:], strataEndMarker); end >= 0 {
+			end += begin + len(strataEndMarker)
+			return body[:begin] + section + body[end:]
+		}
+		// an unterminated section runs to the end of the body
+		return body[:begin] + section
+	}
+	if strings.HasPrefix(strings.TrimSpace(body), legacyStrataIntro) {
+		return section
+	}
+	if strings.TrimSpace(body) == "" {
+		return section
+	}
+	return strings.TrimRight(body, "\n") + "\n\n" + section
+}
+
+// buildPRContent derives a title and body from the layer's commits and the repo's PR template.
+// A single commit supplies both title and description; several commits are listed instead.
+func buildPRContent(branch, base, stackDiagram string) (string, string) {
+	commits, err := git.CommitsBetween(base, branch)
+	if err != nil {
+		logs.Warn("Could not read commits of '%s' for the PR description: %v", branch, err)
+	}
+
+	title := fmt.Sprintf("Strata PR for %s", branch)
+	description := ""
+	switch len(commits) {
+	case 0:
+	case 1:
+		title = commits[0].Subject
+		description = commits[0].Body
+	default:
+		// commits are newest first; the oldest one usually names the change best
+		title = commits[len(commits)-1].Subject
+		var b strings.Builder
+		b.WriteString("## Commits\n\n")
+		for i := len(commits) - 1; i >= 0; i-- {
+			b.WriteString(fmt.Sprintf("- %s\n", commits[i].Subject))
+		}
+		description = strings.TrimRight(b.String(), "\n")
+	}
+
+	parts := []string{}
+	if description != "" {
+		parts = append(parts, description)
+	}
+	if tpl := readPRTemplate(); tpl != "" {
+		parts = append(parts, tpl)
+	}
+	if !diagramInComment() {
+		parts = append(parts, strataSection(branch, stackDiagram))
+	}
+	return title, strings.Join(parts, "\n\n")
+}
+
+// readPRTemplate reads the repository's PR template; the paths are relative to the top of
+// the worktree, wherever in it strata runs.
+func readPRTemplate() string {
+	root, err := git.TopLevel()
+	if err != nil {
+		logs.Debug("Not looking for a PR template: %v", err)
+		return ""
+	}
+	for _, p := range prTemplatePaths {
+		content, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(p)))
+		if err == nil {
+			return strings.TrimSpace(string(content))
+		}
+	}
+	return ""
+}
+
+// editorCommand is $VISUAL, then $EDITOR, then vi. Variables holding only blanks count as
+// unset, so the command always has a program to run.
+func editorCommand() string {
+	editor := strings.TrimSpace(os.Getenv("VISUAL"))
+	if editor == "" {
+		editor = strings.TrimSpace(os.Getenv("EDITOR"))
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	return editor
+}
+
+// editPRContent lets the user adjust the title (first line) and body (after a blank line) in $EDITOR.
+func editPRContent(branch, title, body string) (string, string, error) {
+	editor := editorCommand()
+
+	f, err := os.CreateTemp("", "strata-pr-*.md")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create temp file for PR description: %v", err)
+	}
+	defer os.Remove(f.Name())
+
+	header := fmt.Sprintf("<!-- PR for '%s'. The first line is the title, the rest is the body. Empty the file to abort. -->\n", branch)
+	if _, err := f.WriteString(header + title + "\n\n" + body + "\n"); err != nil {
+		f.Close()
+		return "", "", fmt.Errorf("failed to write PR description: %v", err)
+	}
+	f.Close()
+
+	// EDITOR may carry arguments, e.g. "code --wait"
+	fields := strings.Fields(editor)
+	cmd := exec.Command(fields[0], append(fields[1:], f.Name())...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", "", fmt.Errorf("editor '%s' failed: %v", editor, err)
+	}
+
+	content, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read edited PR description: %v", err)
+	}
+	text := strings.TrimPrefix(string(content), header)
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", "", fmt.Errorf("empty PR description; aborting PR creation for '%s'", branch)
+	}
+	lines := strings.SplitN(text, "\n", 2)
+	newTitle := strings.TrimSpace(lines[0])
+	newBody := ""
+	if len(lines) == 2 {
+		newBody = strings.TrimSpace(lines[1])
+	}
+	return newTitle, newBody, nil
+}
+
+// upsertStackComment keeps the stack diagram in a single PR comment, identified by the
+// Strata markers, editing it in place instead of posting a new one on every update.
+func (p *PRService) upsertStackComment(branch string, prNumber int, stackDiagram string) error {
+	section := strataSection(branch, stackDiagram)
+
+	fg, err := p.forge()
+	if err != nil {
+		return err
+	}
+	if err := fg.UpsertComment(prNumber, strataBeginMarker, section); err != nil {
+		return err
+	}
+	logs.Info("Updated stack comment for '%s' (#%d)", branch, prNumber)
+	return nil
+}
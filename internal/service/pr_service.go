@@ -1,31 +1,33 @@
 package service
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"os/exec"
+	"sort"
+	"strata/internal/config"
+	"strata/internal/forge"
+	"strata/internal/git"
 	"strata/internal/logs"
 	"strata/internal/model"
+	"strata/internal/ui"
 	"strata/internal/utils"
 	"strings"
 )
 
-type PRService struct{}
+type PRService struct {
+	fg forge.Forge
+}
 
 var prSvc *PRService
 
-type prInfo struct {
-	URL    string `json:"url"`
-	Title  string `json:"title"`
-	State  string `json:"state"`
-	Body   string `json:"body"`
-	Number int    `json:"number"`
-}
-
 // branchPRInfo holds PR information for a branch
 type branchPRInfo struct {
-	URL   string
-	State string
+	URL    string
+	State  string
+	Number int
+	Body   string
+	Base   string
+	Review string // only known from the PR cache; see cachedPRMap
 }
 
 func GetPRService() *PRService {
@@ -35,41 +37,72 @@ func GetPRService() *PRService {
 	return prSvc
 }
 
-// getBranchPRMap returns a map of branch names to their PR info
+// getBranchPRMap returns a map of branch names to their open PR, fetched in one batch.
+// The result is written through to the PR cache.
 func (p *PRService) getBranchPRMap(stack map[string]*model.StackNode) (map[string]branchPRInfo, error) {
-	prMap := make(map[string]branchPRInfo)
-
-	// Get all PRs in one call
-	cmd := exec.Command("gh", "pr", "list",
-		"--json", "url,title,state,body,number,headRefName",
-		"--limit", "100", // Increase limit to get all stack PRs
-	)
-	out, err := cmd.CombinedOutput()
+	fg, err := p.forge()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get PR info: %v\n%s", err, string(out))
+		return nil, err
 	}
-
-	type extendedPRInfo struct {
-		prInfo
-		HeadRefName string `json:"headRefName"`
+	branches := make([]string, 0, len(stack))
+	for br := range stack {
+		branches = append(branches, br)
+	}
+	sort.Strings(branches)
+	changes, err := fg.OpenChanges(branches)
+	if err != nil {
+		return nil, err
+	}
+	prMap := make(map[string]branchPRInfo, len(changes))
+	for br, cr := range changes {
+		prMap[br] = newBranchPRInfo(cr)
 	}
+	recordPRs(branches, prMap, false)
+	return prMap, nil
+}
 
-	var prs []extendedPRInfo
-	if err := json.Unmarshal([]byte(out), &prs); err != nil {
-		return nil, fmt.Errorf("failed to parse PR info: %v", err)
+// getLatestPRMap is getBranchPRMap for any PR state: each branch maps to its open PR, or
+// else to the newest merged or closed one. This is what the PR cache holds.
+func (p *PRService) getLatestPRMap(stack map[string]*model.StackNode) (map[string]branchPRInfo, error) {
+	fg, err := p.forge()
+	if err != nil {
+		return nil, err
+	}
+	branches := make([]string, 0, len(stack))
+	for br := range stack {
+		branches = append(branches, br)
+	}
+	sort.Strings(branches)
+	changes, err := fg.LatestChanges(branches)
+	if err != nil {
+		return nil, err
 	}
+	prMap := make(map[string]branchPRInfo, len(changes))
+	for br, cr := range changes {
+		prMap[br] = newBranchPRInfo(cr)
+	}
+	recordPRs(branches, prMap, true)
+	return prMap, nil
+}
 
-	// Map PRs to their branches
-	for _, pr := range prs {
-		if _, exists := stack[pr.HeadRefName]; exists {
-			prMap[pr.HeadRefName] = branchPRInfo{
-				URL:   pr.URL,
-				State: pr.State,
-			}
+// forge returns the code host for this repository, chosen on first use (see forge.ForRepo).
+func (p *PRService) forge() (forge.Forge, error) {
+	if p.fg == nil {
+		fg, err := forge.ForRepo()
+		if err != nil {
+			return nil, err
 		}
+		p.fg = fg
 	}
+	return p.fg, nil
+}
 
-	return prMap, nil
+func newBranchPRInfo(cr forge.ChangeRequest) branchPRInfo {
+	state := cr.State
+	if cr.Draft && state == forge.StateOpen {
+		state = "DRAFT"
+	}
+	return branchPRInfo{URL: cr.URL, State: state, Number: cr.Number, Body: cr.Body, Base: cr.Base}
 }
 
 // generateStackDiagram creates a tree-like representation of the stack with PR links
@@ -77,10 +110,15 @@ func (p *PRService) generateStackDiagram(stack map[string]*model.StackNode, curr
 	var builder strings.Builder
 	builder.WriteString("## 🌳 Stack Structure\n\n")
 
-	// Get PR URLs for all branches
-	prMap, err := p.getBranchPRMap(stack)
-	if err != nil {
-		return "", fmt.Errorf("failed to get PR map: %v", err)
+	// PR links come from the cache, which the caller's own lookup has just refreshed
+	prMap := p.cachedPRMap(stack)
+
+	if config.GetConfigValue(diagramStyleKey) == GraphMermaid {
+		builder.WriteString("```mermaid\n")
+		builder.WriteString(renderMermaid(stack, currentBranch, prMap, false))
+		builder.WriteString("```\n")
+		builder.WriteString("\n_Green: open · Yellow: draft · Purple: merged · Red: closed · Blue outline: this PR_\n")
+		return builder.String(), nil
 	}
 
 	// Find top-level branches (where ParentBranch == "" or parent not in stack)
@@ -108,8 +146,8 @@ func (p *PRService) generateStackDiagram(stack map[string]*model.StackNode, curr
 }
 
 func (p *PRService) getStateEmoji(branch string, info branchPRInfo, prBranch string) string {
-	if branch == "main" {
-		return "🎯" // Special emoji for main branch
+	if GetStackService().IsTrunk(branch) {
+		return "🎯" // Special emoji for trunk branches
 	}
 	if branch == prBranch {
 		return "🔵"
@@ -152,7 +190,7 @@ func (p *PRService) printStackNode(b *strings.Builder, stack map[string]*model.S
 		}
 	}
 
-	if !hasVisibleChildren && node.BranchName != prBranch && prMap[node.BranchName].URL == "" && node.BranchName != "main" {
+	if !hasVisibleChildren && node.BranchName != prBranch && prMap[node.BranchName].URL == "" && !GetStackService().IsTrunk(node.BranchName) {
 		return
 	}
 
@@ -228,84 +266,167 @@ func (p *PRService) hasVisibleDescendants(stack map[string]*model.StackNode, bra
 	return false
 }
 
-// checkPRExists uses `gh` to check if a PR already exists for the given branch
-func (p *PRService) checkPRExists(branch string) (bool, string, error) {
-	cmd := exec.Command("gh", "pr", "list",
-		"--head", branch,
-		"--json", "url,title,state,body,number",
-		"--limit", "1",
-	)
-	out, err := cmd.CombinedOutput()
-	if err != nil {
-		if strings.Contains(string(out), "Authentication") {
-			logs.Error("GH CLI authentication error: %s", string(out))
-			return false, "", fmt.Errorf("gh auth error: %v", err)
-		}
-		return false, "", fmt.Errorf("failed to check PR existence: %v\n%s", err, string(out))
+// updatePRBody refreshes the Strata-owned part of branch's PR in prMap: the marked section
+// of the body, or the sticky stack comment when pr_diagram_location is "comment".
+// Everything the author or reviewers wrote outside the markers is kept. The new body is
+// recorded in prMap, so refreshing it again with the same diagram costs nothing.
+func (p *PRService) updatePRBody(branch string, prMap map[string]branchPRInfo, stackDiagram string) error {
+	pr := prMap[branch]
+	if diagramInComment() {
+		return p.upsertStackComment(branch, pr.Number, stackDiagram)
 	}
 
-	// If output is empty array "[]", no PR exists
-	if strings.TrimSpace(string(out)) == "[]" {
-		return false, "", nil
+	body := spliceStrataSection(pr.Body, strataSection(branch, stackDiagram))
+	if body == pr.Body {
+		logs.Debug("PR body for '%s' (#%d) already up to date", branch, pr.Number)
+		return nil
 	}
 
-	// Parse PR info from JSON response
-	var prs []prInfo
-	if err := json.Unmarshal([]byte(out), &prs); err != nil {
-		return false, "", fmt.Errorf("failed to parse PR info: %v", err)
+	fg, err := p.forge()
+	if err != nil {
+		return err
 	}
-	if len(prs) == 0 {
-		return false, "", nil
+	if err := fg.UpdateBody(pr.Number, body); err != nil {
+		return err
 	}
-
-	return true, prs[0].URL, nil
+	pr.Body = body
+	prMap[branch] = pr
+	logs.Info("Updated PR body for '%s' (#%d)", branch, pr.Number)
+	return nil
 }
 
-// updatePRBody updates an existing PR's body with the current stack diagram
-func (p *PRService) updatePRBody(branch string, prNumber int, stackDiagram string) error {
-	body := fmt.Sprintf("This PR is part of a stacked workflow.\n\n%s", stackDiagram)
+// refreshPRBodies brings the stack diagram of every PR in prMap up to date, once the PRs
+// of a run exist.
+func (p *PRService) refreshPRBodies(stack map[string]*model.StackNode, prMap map[string]branchPRInfo) {
+	branches := make([]string, 0, len(prMap))
+	for br := range prMap {
+		branches = append(branches, br)
+	}
+	sort.Strings(branches)
+	for _, br := range branches {
+		if info := prMap[br]; info.State == "MERGED" || info.State == "CLOSED" {
+			continue
+		}
+		diagram, err := p.generateStackDiagram(stack, br)
+		if err != nil {
+			logs.Warn("Failed to generate stack diagram for '%s': %v", br, err)
+			continue
+		}
+		if err := p.updatePRBody(br, prMap, diagram); err != nil {
+			logs.Warn("Failed to update PR body for '%s': %v", br, err)
+		}
+	}
+}
 
-	cmd := exec.Command("gh", "pr", "edit",
-		fmt.Sprintf("%d", prNumber),
-		"--body", body,
-	)
+// Actions reported in PRResult.
+const (
+	PRCreated = "created"
+	PRUpdated = "updated"
+	PRSkipped = "skipped"
+	PRFailed  = "failed"
+)
 
-	out, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("failed to update PR body: %v\n%s", err, string(out))
-	}
-	logs.Info("Updated PR body for '%s' (#%d)", branch, prNumber)
-	return nil
+// PRResult is what CreatePR did for one layer.
+type PRResult struct {
+	Branch string
+	Base   string
+	Action string
+	Number int
+	URL    string
+	// Reason explains a skipped or failed layer.
+	Reason string
 }
 
-// CreatePR uses `gh` to open PR(s). If all==true, open for every unmerged stack branch.
-func (p *PRService) CreatePR(all bool) error {
+// CreatePR opens or refreshes PRs, parents first. Without opts.All, opts.Stack, opts.From or
+// opts.Upto only the current branch is handled. opts.From keeps that layer and the ones
+// stacked on it, opts.Upto that layer and the ones below it. Layers without commits beyond
+// their parent are skipped, as are layers whose parent got no PR. Every layer is attempted
+// even when some fail; the results come back in the order they were handled.
+func (p *PRService) CreatePR(opts PROptions) ([]PRResult, error) {
 	s := GetStackService()
 	stack := s.GetStack()
+	opts = opts.withDefaults()
 
-	if all {
-		// Open PR for each branch that has a parent
-		for br, node := range stack {
-			if node.ParentBranch == "" {
-				continue
-			}
-			if err := p.createOrUpdatePR(br, node.ParentBranch, stack, all); err != nil {
-				return err
-			}
+	var order []string
+	if opts.All || opts.Stack != "" || opts.From != "" || opts.Upto != "" {
+		var err error
+		if order, err = s.layerRange(opts.Stack, opts.From, opts.Upto); err != nil {
+			return nil, err
 		}
-		return nil
+	} else {
+		curr := utils.CurrentBranch()
+		if curr == "" {
+			return nil, fmt.Errorf("cannot determine current branch to create PR")
+		}
+		order = []string{curr}
 	}
 
-	// otherwise, just for current branch
-	curr := utils.CurrentBranch()
-	if curr == "" {
-		return fmt.Errorf("cannot determine current branch to create PR")
+	// looked up once for the whole run; createOrUpdatePR adds the PRs it opens
+	prMap, err := p.getBranchPRMap(stack)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get PR map: %w", err)
+	}
+
+	// with several layers, every PR's diagram is refreshed once at the end, when it can
+	// link all the PRs opened on the way
+	refreshAll := len(order) > 1 || opts.All
+	results := []PRResult{}
+	// layers that ended up without a PR; their children can't get one either
+	noPR := map[string]bool{}
+	failed := 0
+	for _, br := range order {
+		base := s.TrunkFor(br)
+		if node, ok := stack[br]; ok && node.ParentBranch != "" {
+			base = node.ParentBranch
+		}
+		if noPR[base] {
+			noPR[br] = true
+			results = append(results, PRResult{Branch: br, Base: base, Action: PRSkipped, Reason: fmt.Sprintf("parent '%s' has no PR", base)})
+			continue
+		}
+
+		res, err := p.createOrUpdatePR(br, base, stack, prMap, refreshAll, opts)
+		if err != nil {
+			logs.Error("Failed to create PR for '%s': %v", br, err)
+			res = PRResult{Branch: br, Base: base, Action: PRFailed, Reason: err.Error()}
+			failed++
+		}
+		if res.Action == PRSkipped || res.Action == PRFailed {
+			noPR[br] = true
+		}
+		results = append(results, res)
 	}
-	parent := "main" // default
-	if node, ok := stack[curr]; ok && node.ParentBranch != "" {
-		parent = node.ParentBranch // use parent branch if exists
+	if refreshAll {
+		p.refreshPRBodies(stack, prMap)
 	}
-	return p.createOrUpdatePR(curr, parent, stack, all)
+
+	if failed > 0 {
+		return results, fmt.Errorf("failed to create %d of %d PR(s)", failed, len(order))
+	}
+	return results, nil
+}
+
+// RenderPRResults formats the outcome of CreatePR as an aligned table.
+func RenderPRResults(results []PRResult) string {
+	rows := [][]string{{"LAYER", "BASE", "ACTION", "PR"}}
+	for _, r := range results {
+		pr := r.URL
+		if r.Reason != "" {
+			pr = r.Reason
+		}
+		rows = append(rows, []string{r.Branch, r.Base, r.Action, pr})
+	}
+	lines := alignRows(rows)
+	lines[0] = ui.Colorize(lines[0], ui.Bold)
+	for i, r := range results {
+		switch r.Action {
+		case PRCreated:
+			lines[i+1] = ui.Colorize(lines[i+1], ui.FgGreen)
+		case PRFailed:
+			lines[i+1] = ui.Colorize(lines[i+1], ui.FgRed)
+		}
+	}
+	return strings.Join(lines, "\n")
 }
 
 // checkParentPRs verifies that all parent branches (recursively) have PRs
@@ -320,8 +441,8 @@ func (p *PRService) checkParentPRs(branch string, stack map[string]*model.StackN
 		return nil
 	}
 
-	// Skip check for main branch
-	if node.ParentBranch == "" || node.ParentBranch == "main" {
+	// Skip check for trunk branches
+	if node.ParentBranch == "" || GetStackService().IsTrunk(node.ParentBranch) {
 		return nil
 	}
 
@@ -335,121 +456,147 @@ func (p *PRService) checkParentPRs(branch string, stack map[string]*model.StackN
 	return p.checkParentPRs(node.ParentBranch, stack, prMap, visited)
 }
 
-func (p *PRService) createOrUpdatePR(branch, base string, stack map[string]*model.StackNode, updateAll bool) error {
+// createOrUpdatePR handles one layer. prMap holds the open PRs of stack; a PR opened here is
+// added to it, so the layers above see it. With refreshLater the body of an existing PR is
+// left to the caller, which refreshes all of them once the run is done.
+func (p *PRService) createOrUpdatePR(branch, base string, stack map[string]*model.StackNode, prMap map[string]branchPRInfo, refreshLater bool, opts PROptions) (PRResult, error) {
 	logs.Info("Creating/updating PR for branch '%s' -> base '%s'", branch, base)
+	res := PRResult{Branch: branch, Base: base}
 
-	// Get PR URLs for all branches in one call
-	prMap, err := p.getBranchPRMap(stack)
-	if err != nil {
-		return fmt.Errorf("failed to get PR map: %v", err)
+	prInfo, exists := prMap[branch]
+	if !exists {
+		if ahead, _, err := git.AheadBehind(base, branch); err == nil && ahead == 0 {
+			res.Action, res.Reason = PRSkipped, fmt.Sprintf("no commits beyond '%s'", base)
+			return res, nil
+		}
 	}
 
 	// Check if all parent branches have PRs before proceeding
 	if err := p.checkParentPRs(branch, stack, prMap, make(map[string]bool)); err != nil {
-		return fmt.Errorf("cannot create PR: %v", err)
+		return res, fmt.Errorf("cannot create PR: %v", err)
 	}
 
 	// Generate stack diagram specific to this PR's branch
 	stackDiagram, err := p.generateStackDiagram(stack, branch)
 	if err != nil {
-		return fmt.Errorf("failed to generate stack diagram: %v", err)
+		return res, fmt.Errorf("failed to generate stack diagram: %v", err)
+	}
+
+	fg, err := p.forge()
+	if err != nil {
+		return res, err
 	}
 
 	// Use the PR info from the map if it exists
-	prInfo, exists := prMap[branch]
 	if exists {
-		// Get PR number for existing PR
-		prs, err := p.getPRInfo(branch)
-		if err != nil || len(prs) == 0 {
-			return fmt.Errorf("failed to get PR number: %v", err)
+		if !refreshLater {
+			if err := p.updatePRBody(branch, prMap, stackDiagram); err != nil {
+				return res, err
+			}
 		}
-
-		if err := p.updatePRBody(branch, prs[0].Number, stackDiagram); err != nil {
-			return err
+		if err := fg.AddMetadata(prInfo.Number, opts.metadata()); err != nil {
+			logs.Warn("Failed to apply reviewers/labels to PR #%d: %v", prInfo.Number, err)
 		}
-		fmt.Printf("Updated existing PR for branch '%s': %s\n", branch, prInfo.URL)
+		res.Action, res.Number, res.URL = PRUpdated, prInfo.Number, prInfo.URL
 	} else {
 		// Create new PR
-		title := fmt.Sprintf("Strata PR for %s", branch)
-		body := fmt.Sprintf("This PR is part of a stacked workflow.\n\n%s", stackDiagram)
-
-		cmd := exec.Command("gh", "pr", "create",
-			"--base", base,
-			"--head", branch,
-			"--title", title,
-			"--body", body,
-		)
-
-		out, err := cmd.CombinedOutput()
-		if err != nil {
-			if strings.Contains(string(out), "Authentication") {
-				logs.Error("GH CLI authentication error: %s", string(out))
-				return fmt.Errorf("gh auth error: %v", err)
+		title, body := buildPRContent(branch, base, stackDiagram)
+		if opts.Edit {
+			if title, body, err = editPRContent(branch, title, body); err != nil {
+				return res, err
 			}
-			if strings.Contains(string(out), "already exists") {
-				// This is a race condition - try to update the PR body
-				logs.Info("PR was created concurrently for '%s', attempting to update body", branch)
-				if prs, err := p.getPRInfo(branch); err == nil && len(prs) > 0 {
-					if err := p.updatePRBody(branch, prs[0].Number, stackDiagram); err != nil {
+		}
+
+		cr, err := fg.Create(forge.CreateOptions{
+			Base:     base,
+			Head:     branch,
+			Title:    title,
+			Body:     body,
+			Draft:    opts.Draft,
+			Metadata: opts.metadata(),
+		})
+		if errors.Is(err, forge.ErrExists) {
+			// This is a race condition - try to update the PR body
+			logs.Info("PR was created concurrently for '%s', attempting to update body", branch)
+			res.Action = PRUpdated
+			if changes, err := fg.OpenChanges([]string{branch}); err == nil {
+				if existing, ok := changes[branch]; ok {
+					res.Number, res.URL = existing.Number, existing.URL
+					prMap[branch] = newBranchPRInfo(existing)
+					if err := p.updatePRBody(branch, prMap, stackDiagram); err != nil {
 						logs.Warn("Failed to update concurrent PR body: %v", err)
 					}
 				}
-				return nil
 			}
-			return fmt.Errorf("failed to create PR for '%s': %v\n%s", branch, err, string(out))
+			return res, nil
 		}
+		if err != nil {
+			return res, err
+		}
+		created := newBranchPRInfo(cr)
+		if created.Body == "" {
+			created.Body = body
+		}
+		res.Action, res.Number, res.URL = PRCreated, created.Number, created.URL
+		prMap[branch] = created
+		recordPRs([]string{branch}, map[string]branchPRInfo{branch: created}, false)
 
-		logs.Info("PR created successfully for '%s': %s", branch, string(out))
-		fmt.Printf("Created new PR for branch '%s': %s\n", branch, strings.TrimSpace(string(out)))
-	}
-
-	// Only update related PRs if updateAll is true
-	if updateAll {
-		// Update all related PRs in the stack
-		for br, info := range prMap {
-			if br != branch && info.State != "MERGED" && info.State != "CLOSED" {
-				// Generate stack diagram specific to this related PR
-				relatedDiagram, err := p.generateStackDiagram(stack, br)
-				if err != nil {
-					logs.Warn("Failed to generate stack diagram for '%s': %v", br, err)
-					continue
-				}
-
-				// Get PR number for this branch
-				relatedPRs, err := p.getPRInfo(br)
-				if err != nil || len(relatedPRs) == 0 {
-					logs.Warn("Failed to get PR info for '%s': %v", br, err)
-					continue
-				}
+		logs.Info("PR created successfully for '%s': %s", branch, created.URL)
 
-				// Update the PR body
-				if err := p.updatePRBody(br, relatedPRs[0].Number, relatedDiagram); err != nil {
-					logs.Warn("Failed to update PR body for '%s': %v", br, err)
-					continue
-				}
-				logs.Info("Updated related PR for '%s'", br)
+		if diagramInComment() && created.Number > 0 && !refreshLater {
+			if err := p.upsertStackComment(branch, created.Number, stackDiagram); err != nil {
+				logs.Warn("Failed to post stack comment on '%s': %v", branch, err)
 			}
 		}
 	}
 
-	return nil
+	return res, nil
+}
+
+// CheckSummary condenses the PR's review and CI state into a one-line summary such as
+// "3 passed, 1 failed, 0 pending".
+func (p *PRService) CheckSummary(branch string) (string, error) {
+	fg, err := p.forge()
+	if err != nil {
+		return "", err
+	}
+	cr, err := p.openChange(fg, branch)
+	if err != nil {
+		return "", err
+	}
+	st, err := fg.Status(cr.Number)
+	if err != nil {
+		return "", err
+	}
+	if len(st.Checks) == 0 {
+		return "no checks", nil
+	}
+	passed, failed, pending := st.CheckCounts(false)
+	return fmt.Sprintf("%d passed, %d failed, %d pending", passed, failed, pending), nil
 }
 
-// getPRInfo gets detailed PR information for a branch using the cached PR map
-func (p *PRService) getPRInfo(branch string) ([]prInfo, error) {
-	cmd := exec.Command("gh", "pr", "list",
-		"--head", branch,
-		"--json", "url,title,state,body,number",
-		"--limit", "1",
-	)
-	out, err := cmd.CombinedOutput()
+// openChange returns the open change request for branch.
+func (p *PRService) openChange(fg forge.Forge, branch string) (forge.ChangeRequest, error) {
+	changes, err := fg.OpenChanges([]string{branch})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get PR info: %v\n%s", err, string(out))
+		return forge.ChangeRequest{}, err
+	}
+	cr, ok := changes[branch]
+	if !ok {
+		return forge.ChangeRequest{}, fmt.Errorf("no open PR for '%s'", branch)
 	}
+	return cr, nil
+}
 
-	var prs []prInfo
-	if err := json.Unmarshal([]byte(out), &prs); err != nil {
-		return nil, fmt.Errorf("failed to parse PR info: %v", err)
+// OpenPRInBrowser opens the branch's PR in the default browser.
+func (p *PRService) OpenPRInBrowser(branch string) error {
+	fg, err := p.forge()
+	if err != nil {
+		return err
+	}
+	cr, err := p.openChange(fg, branch)
+	if err != nil {
+		return err
 	}
-	return prs, nil
+	return utils.OpenBrowser(cr.URL)
 }
@@ -0,0 +1,256 @@
+package service
+
+import (
+	"fmt"
+	"strata/internal/errs"
+	"strata/internal/forge"
+	"strata/internal/git"
+	"strata/internal/logs"
+	"strata/internal/model"
+	"strata/internal/ui"
+	"strings"
+)
+
+// LayerPRStatus is the review, CI and mergeability state of one layer's PR.
+type LayerPRStatus struct {
+	Branch string
+	Parent string
+	// Bottom layers target a trunk directly; they are next in line to land.
+	Bottom bool
+
+	PRNumber int
+	PRURL    string
+	Draft    bool
+
+	Review    string
+	Mergeable string
+	// Check counts cover required checks only, unless the forge marks none as required.
+	ChecksPassed      int
+	ChecksFailed      int
+	ChecksPending     int
+	UnresolvedThreads int
+
+	Base         string // the PR's current base
+	ExpectedBase string // what `strata pr sync` would set it to
+	BehindBase   int    // commits on ExpectedBase the layer doesn't have yet
+
+	// Blockers lists every reason the PR can't be merged right now.
+	Blockers []string
+}
+
+// BaseStale reports whether the PR targets the wrong branch or the layer needs a restack.
+func (l LayerPRStatus) BaseStale() bool {
+	return (l.PRNumber != 0 && l.Base != l.ExpectedBase) || l.BehindBase > 0
+}
+
+// StackPRStatus gathers the PR status of every layer, parents first. A non-empty stackName
+// limits it to that named stack. The returned error is errs.Blocked when a bottom layer
+// can't be merged; the statuses are returned either way.
+func (p *PRService) StackPRStatus(stackName string) ([]LayerPRStatus, error) {
+	s := GetStackService()
+	stack := model.StackTree(s.GetStack())
+	if stackName != "" {
+		layers, err := s.StackLayers(stackName)
+		if err != nil {
+			return nil, err
+		}
+		stack = layers
+	}
+
+	prMap, landed, err := p.stackPRs(stack)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get PR map: %w", err)
+	}
+	fg, err := p.forge()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := []LayerPRStatus{}
+	blockedBottom := []string{}
+	reviews := map[string]string{}
+	for _, br := range stack.Topological() {
+		node := stack[br]
+		if node.ParentBranch == "" || s.IsTrunk(br) {
+			continue
+		}
+		st := LayerPRStatus{Branch: br, Parent: node.ParentBranch, ExpectedBase: s.ExpectedBase(br, landed)}
+		st.Bottom = s.IsTrunk(st.ExpectedBase)
+		if _, behind, err := git.AheadBehind(st.ExpectedBase, br); err == nil {
+			st.BehindBase = behind
+		}
+
+		info, ok := prMap[br]
+		if !ok {
+			st.Blockers = append(st.Blockers, "no PR")
+		} else {
+			st.PRNumber, st.PRURL, st.Base = info.Number, info.URL, info.Base
+			st.Draft = info.State == "DRAFT"
+			fs, err := fg.Status(info.Number)
+			if err != nil {
+				logs.Warn("Failed to get status of PR #%d (%s): %v", info.Number, br, err)
+				st.Blockers = append(st.Blockers, "status unavailable")
+			} else {
+				p.applyForgeStatus(&st, fs)
+				reviews[br] = fs.Review
+			}
+		}
+
+		if len(st.Blockers) > 0 && st.Bottom {
+			blockedBottom = append(blockedBottom, br)
+		}
+		statuses = append(statuses, st)
+	}
+	recordReviews(reviews)
+
+	if len(blockedBottom) > 0 {
+		return statuses, errs.Blocked("bottom of the stack is blocked: %s", strings.Join(blockedBottom, ", "))
+	}
+	return statuses, nil
+}
+
+func (p *PRService) applyForgeStatus(st *LayerPRStatus, fs forge.Status) {
+	st.Review = fs.Review
+	st.Mergeable = fs.Mergeable
+	st.UnresolvedThreads = fs.UnresolvedThreads
+
+	requiredOnly := false
+	for _, c := range fs.Checks {
+		if c.Required {
+			requiredOnly = true
+			break
+		}
+	}
+	st.ChecksPassed, st.ChecksFailed, st.ChecksPending = fs.CheckCounts(requiredOnly)
+
+	if st.Draft {
+		st.Blockers = append(st.Blockers, "draft")
+	}
+	switch fs.Review {
+	case forge.ReviewChangesRequested:
+		st.Blockers = append(st.Blockers, "changes requested")
+	case forge.ReviewRequired:
+		st.Blockers = append(st.Blockers, "review required")
+	}
+	if st.ChecksFailed > 0 {
+		st.Blockers = append(st.Blockers, "failing: "+pluralize(st.ChecksFailed, "check"))
+	}
+	if st.ChecksPending > 0 {
+		st.Blockers = append(st.Blockers, "checks pending")
+	}
+	if fs.Mergeable == forge.MergeableNo {
+		st.Blockers = append(st.Blockers, "conflicts")
+	}
+	if fs.UnresolvedThreads > 0 {
+		st.Blockers = append(st.Blockers, "unresolved: "+pluralize(fs.UnresolvedThreads, "thread"))
+	}
+	if st.Base != st.ExpectedBase {
+		st.Blockers = append(st.Blockers, fmt.Sprintf("base is %s, expected %s", st.Base, st.ExpectedBase))
+	}
+}
+
+// RenderPRStatus formats statuses as an aligned table followed by the blockers of each layer.
+func RenderPRStatus(statuses []LayerPRStatus) string {
+	if len(statuses) == 0 {
+		return "No layers with pull requests."
+	}
+
+	header := []string{"LAYER", "PR", "REVIEW", "CHECKS", "MERGEABLE", "THREADS", "BASE"}
+	rows := [][]string{}
+	for _, st := range statuses {
+		layer := st.Branch
+		if st.Bottom {
+			layer = "▸ " + layer
+		} else {
+			layer = "  " + layer
+		}
+		if st.PRNumber == 0 {
+			rows = append(rows, []string{layer, "—", "", "", "", "", baseLabel(st)})
+			continue
+		}
+		pr := fmt.Sprintf("#%d", st.PRNumber)
+		if st.Draft {
+			pr += " (draft)"
+		}
+		rows = append(rows, []string{
+			layer,
+			pr,
+			reviewLabel(st.Review),
+			fmt.Sprintf("%d✓ %d✗ %d…", st.ChecksPassed, st.ChecksFailed, st.ChecksPending),
+			st.Mergeable,
+			fmt.Sprintf("%d", st.UnresolvedThreads),
+			baseLabel(st),
+		})
+	}
+
+	lines := alignRows(append([][]string{header}, rows...))
+	var b strings.Builder
+	b.WriteString(ui.Colorize(lines[0], ui.Bold) + "\n")
+	for i, line := range lines[1:] {
+		switch {
+		case len(statuses[i].Blockers) == 0:
+			line = ui.Colorize(line, ui.FgGreen)
+		case statuses[i].Bottom:
+			line = ui.Colorize(line, ui.FgRed)
+		}
+		b.WriteString(line + "\n")
+	}
+
+	for _, st := range statuses {
+		if len(st.Blockers) > 0 {
+			b.WriteString(fmt.Sprintf("\n%s: %s", st.Branch, strings.Join(st.Blockers, ", ")))
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func reviewLabel(review string) string {
+	switch review {
+	case forge.ReviewApproved:
+		return "approved"
+	case forge.ReviewChangesRequested:
+		return "changes requested"
+	case forge.ReviewRequired:
+		return "pending"
+	default:
+		return "not required"
+	}
+}
+
+func baseLabel(st LayerPRStatus) string {
+	parts := []string{}
+	if st.PRNumber != 0 && st.Base != st.ExpectedBase {
+		parts = append(parts, "retarget → "+st.ExpectedBase)
+	}
+	if st.BehindBase > 0 {
+		parts = append(parts, fmt.Sprintf("%d behind %s", st.BehindBase, st.ExpectedBase))
+	}
+	if len(parts) == 0 {
+		return "up to date"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// alignRows pads every cell to its column's width so the rows line up as a table.
+func alignRows(rows [][]string) []string {
+	widths := []int{}
+	for _, row := range rows {
+		for i, cell := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			if n := len([]rune(cell)); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+	lines := make([]string, len(rows))
+	for r, row := range rows {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = cell + strings.Repeat(" ", widths[i]-len([]rune(cell)))
+		}
+		lines[r] = strings.TrimRight(strings.Join(cells, "  "), " ")
+	}
+	return lines
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"fmt"
+	"strata/internal/forge"
+	"strata/internal/logs"
+	"strata/internal/model"
+)
+
+// BaseChange records a PR whose base branch no longer matches its layer's parent.
+type BaseChange struct {
+	Branch string
+	Number int
+	URL    string
+	From   string
+	To     string
+	// Applied is false for dry runs and for retargets that failed (see Error).
+	Applied bool
+	Error   string
+}
+
+// ExpectedBase is the branch a layer's PR should target: its parent, skipping parents that
+// have landed, or the layer's trunk. landed holds the layers whose PR was merged; see
+// stackPRs.
+func (s *StackService) ExpectedBase(branch string, landed map[string]bool) string {
+	node := s.stack[branch]
+	if node == nil {
+		return s.TrunkFor(branch)
+	}
+	visited := map[string]bool{branch: true}
+	cur := node.ParentBranch
+	for cur != "" && !visited[cur] {
+		visited[cur] = true
+		if s.IsTrunk(cur) || !landed[cur] {
+			return cur
+		}
+		parent := s.stack[cur]
+		if parent == nil {
+			break
+		}
+		cur = parent.ParentBranch
+	}
+	return s.TrunkFor(branch)
+}
+
+// stackPRs looks up the latest PR of the layers in stack and of all their parents in one
+// batch. It returns the open PRs of stack, like getBranchPRMap, and the layers that have
+// landed: their latest PR was merged. Whether the branch still exists doesn't matter, since
+// merging a PR doesn't always delete it and deleting a branch doesn't merge it.
+func (p *PRService) stackPRs(stack model.StackTree) (map[string]branchPRInfo, map[string]bool, error) {
+	all := GetStackService().GetStack()
+	scope := model.StackTree{}
+	for br, node := range stack {
+		for node != nil && scope[br] == nil {
+			scope[br] = node
+			br = node.ParentBranch
+			node = all[br]
+		}
+	}
+	latest, err := p.getLatestPRMap(scope)
+	if err != nil {
+		return nil, nil, err
+	}
+	prMap := map[string]branchPRInfo{}
+	landed := map[string]bool{}
+	for br, info := range latest {
+		switch info.State {
+		case forge.StateMerged:
+			landed[br] = true
+		case forge.StateOpen, "DRAFT":
+			if stack[br] != nil {
+				prMap[br] = info
+			}
+		}
+	}
+	return prMap, landed, nil
+}
+
+// SyncPRBases retargets every open PR whose base differs from ExpectedBase. A non-empty
+// stackName limits this to that named stack; dryRun only reports what would change.
+// All mismatches are reported even when some retargets fail.
+func (p *PRService) SyncPRBases(stackName string, dryRun bool) ([]BaseChange, error) {
+	s := GetStackService()
+	stack := model.StackTree(s.GetStack())
+	if stackName != "" {
+		layers, err := s.StackLayers(stackName)
+		if err != nil {
+			return nil, err
+		}
+		stack = layers
+	}
+
+	prMap, landed, err := p.stackPRs(stack)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get PR map: %w", err)
+	}
+	fg, err := p.forge()
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []BaseChange{}
+	failed := 0
+	// parents first, so a chain of retargets is applied bottom-up
+	for _, br := range stack.Topological() {
+		info, ok := prMap[br]
+		if !ok {
+			continue
+		}
+		want := s.ExpectedBase(br, landed)
+		if info.Base == want {
+			continue
+		}
+		change := BaseChange{Branch: br, Number: info.Number, URL: info.URL, From: info.Base, To: want}
+		if !dryRun {
+			if err := fg.SetBase(info.Number, want); err != nil {
+				logs.Error("Failed to retarget PR #%d (%s): %v", info.Number, br, err)
+				change.Error = err.Error()
+				failed++
+			} else {
+				logs.Info("Retargeted PR #%d (%s) from '%s' to '%s'", info.Number, br, info.Base, want)
+				change.Applied = true
+			}
+		}
+		changes = append(changes, change)
+	}
+
+	if failed > 0 {
+		return changes, fmt.Errorf("failed to retarget %d of %d PR(s)", failed, len(changes))
+	}
+	return changes, nil
+}
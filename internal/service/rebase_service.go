@@ -21,7 +21,7 @@ func GetRebaseService() *RebaseService {
 func (r *RebaseService) RebaseBranch(branch, onto string) error {
 	logs.Info("Performing direct rebase of '%s' onto '%s'", branch, onto)
 	if err := git.RebaseBranch(branch, onto); err != nil {
-		return fmt.Errorf("reabse failed: %v", err)
+		return fmt.Errorf("reabse failed: %w", err)
 	}
 	logs.Info("Rebase of '%s' onto '%s' completed successfully.", branch, onto)
 	return nil
@@ -2,6 +2,7 @@ package service
 
 import (
 	"fmt"
+	"strata/internal/errs"
 	"strata/internal/git"
 	"strata/internal/hooks"
 	"strata/internal/logs"
@@ -13,7 +14,8 @@ import (
 )
 
 type StackService struct {
-	stack model.StackTree
+	stack  model.StackTree
+	stacks model.Stacks
 }
 
 var stackSvc *StackService
@@ -25,12 +27,19 @@ func GetStackService() *StackService {
 			logs.Error("Failed to load stack from disk: %v", err)
 			st = model.StackTree{}
 		}
-		stackSvc = &StackService{stack: st}
+		stacks, err := store.LoadStacks()
+		if err != nil {
+			logs.Error("Failed to load named stacks from disk: %v", err)
+			stacks = model.Stacks{}
+		}
+		stackSvc = &StackService{stack: st, stacks: stacks}
 	}
 	return stackSvc
 }
 
-func (s *StackService) CreateNewLayer(branchName string) error {
+// CreateNewLayer branches off the current branch. The layer joins stackName, or the
+// parent's stack when stackName is empty.
+func (s *StackService) CreateNewLayer(branchName, stackName string) error {
 	if branchName == "" {
 		return fmt.Errorf("branch name cannot be empty")
 	}
@@ -40,6 +49,14 @@ func (s *StackService) CreateNewLayer(branchName string) error {
 		return fmt.Errorf("cannot determine current branch to stack on")
 	}
 
+	if stackName == "" {
+		if parent, ok := s.stack[current]; ok {
+			stackName = parent.Stack
+		}
+	} else if _, err := s.activeStack(stackName); err != nil {
+		return err
+	}
+
 	if err := git.CheckoutNewBranch(branchName); err != nil {
 		return err
 	}
@@ -49,6 +66,7 @@ func (s *StackService) CreateNewLayer(branchName string) error {
 		BranchName:   branchName,
 		ParentBranch: current,
 		Children:     []string{},
+		Stack:        stackName,
 		CreatedBy:    utils.GetGithubUsername(),
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
@@ -79,7 +97,7 @@ func (s *StackService) RenameLayer(oldName, newName string) error {
 	}
 
 	if _, ok := s.stack[oldName]; !ok {
-		return fmt.Errorf("branch '%s' not found in stack", oldName)
+		return errs.NotInStack("branch '%s' not found in stack", oldName)
 	}
 
 	if err := git.RenameBranch(oldName, newName); err != nil {
@@ -111,13 +129,13 @@ func (s *StackService) RenameLayer(oldName, newName string) error {
 func (s *StackService) MergeLayer(branch string) error {
 	node, exists := s.stack[branch]
 	if !exists {
-		return fmt.Errorf("branch '%s' not in stack", branch)
+		return errs.NotInStack("branch '%s' not in stack", branch)
 	}
 
 	parent := node.ParentBranch
 	if parent == "" {
-		// If no parent, assume main
-		parent = "main"
+		// If no parent, land on the trunk
+		parent = s.TrunkFor(branch)
 	}
 	logs.Info("Merging '%s' into '%s'", branch, parent)
 
@@ -144,14 +162,87 @@ func (s *StackService) MergeLayer(branch string) error {
 	return nil
 }
 
-// UpdateEntireStack attempts to rebase each child on its parent, topologically
-func (s *StackService) UpdateEntireStack() error {
+// RestackLayer rebases a single layer onto its parent.
+func (s *StackService) RestackLayer(branch string) error {
+	node, exists := s.stack[branch]
+	if !exists {
+		return errs.NotInStack("branch '%s' not in stack", branch)
+	}
+	if node.ParentBranch == "" {
+		return fmt.Errorf("branch '%s' has no parent to restack onto", branch)
+	}
+	logs.Info("Restacking '%s' onto '%s'", branch, node.ParentBranch)
+	if err := git.RebaseBranch(branch, node.ParentBranch); err != nil {
+		return err
+	}
+	node.UpdatedAt = time.Now()
+	return store.SaveStack(s.stack)
+}
+
+// DeleteLayer removes a layer from the stack and deletes the local branch.
+// Its children are re-parented onto the deleted layer's parent.
+func (s *StackService) DeleteLayer(branch string) error {
+	node, exists := s.stack[branch]
+	if !exists {
+		return errs.NotInStack("branch '%s' not in stack", branch)
+	}
+	if utils.CurrentBranch() == branch {
+		return fmt.Errorf("cannot delete '%s' while it is checked out", branch)
+	}
+	if err := git.DeleteBranch(branch); err != nil {
+		return err
+	}
+	s.detachNode(branch, node.ParentBranch)
+
+	if err := store.SaveStack(s.stack); err != nil {
+		return err
+	}
+	hooks.RunHooks("deleteLayer", branch)
+	return nil
+}
+
+// detachNode removes branch from the stack and hands its children over to newParent.
+func (s *StackService) detachNode(branch, newParent string) {
+	node := s.stack[branch]
+	if node == nil {
+		return
+	}
+
+	if parentNode, ok := s.stack[newParent]; ok {
+		newKids := []string{}
+		for _, c := range parentNode.Children {
+			if c != branch {
+				newKids = append(newKids, c)
+			}
+		}
+		parentNode.Children = append(newKids, node.Children...)
+	}
+	for _, c := range node.Children {
+		if child, ok := s.stack[c]; ok {
+			child.ParentBranch = newParent
+			child.UpdatedAt = time.Now()
+		}
+	}
+	delete(s.stack, branch)
+}
+
+// UpdateEntireStack attempts to rebase each child on its parent, topologically.
+// When stackName is set only that stack's layers are updated.
+func (s *StackService) UpdateEntireStack(stackName string) error {
+	scope := s.stack
+	if stackName != "" {
+		var err error
+		if scope, err = s.StackLayers(stackName); err != nil {
+			return err
+		}
+	}
+
 	// We'll do a topological sort: first update branches whose parents are up to date.
 	updated := map[string]bool{}
 
 	for {
 		progressed := false
-		for br, node := range s.stack {
+		for br, node := range scope {
 			if updated[br] {
 				continue
 			}
@@ -165,16 +256,16 @@ func (s *StackService) UpdateEntireStack() error {
 				updated[br] = true
 				progressed = true
 			} else {
-				// only proceed if parent is updated
-				if updated[p] {
+				// only proceed if parent is updated; parents outside the scope are taken as they are
+				if updated[p] || scope[p] == nil {
 					// rebase br onto p
 					logs.Info("Rebasing '%s' onto '%s' during stack updated...", br, p)
 					if err := git.RebaseBranch(br, p); err != nil {
-						return fmt.Errorf("rebase failed for '%s': %v", br, err)
+						return fmt.Errorf("rebase failed for '%s': %w", br, err)
 					}
 
-					// optionally push br
-					if e2 := git.PushCurrentBranch(); e2 != nil {
+					// optionally push br; it may have been rebased in its own worktree
+					if e2 := git.PushBranch(br, false); e2 != nil {
 						logs.Warn("push after rebase failed for '%s': %v", br, e2)
 					}
 					// Updating timestamps
@@ -198,15 +289,8 @@ func (s *StackService) ViewStackTree() (string, error) {
 	var builder strings.Builder
 	visited := map[string]bool{}
 
-	// find top-level branches (where ParentBranch == "" or parent not in stack)
-	topLevels := []string{}
-	for br, node := range s.stack {
-		if node.ParentBranch == "" || s.stack[node.ParentBranch] == nil {
-			topLevels = append(topLevels, br)
-		}
-	}
-
-	for _, tl := range topLevels {
+	// top-level branches are those where ParentBranch == "" or the parent is not in the stack
+	for _, tl := range s.stack.Roots() {
 		printNode(&builder, s.stack, s.stack[tl], 0, visited)
 	}
 	return builder.String(), nil
@@ -231,7 +315,12 @@ func (s *StackService) ReloadStack() error {
 	if err != nil {
 		return err
 	}
+	stacks, err := store.LoadStacks()
+	if err != nil {
+		return err
+	}
 	s.stack = st
+	s.stacks = stacks
 	return nil
 }
 
@@ -0,0 +1,228 @@
+package service
+
+import (
+	"fmt"
+	"strata/internal/git"
+	"strata/internal/logs"
+	"strata/internal/ui"
+	"strata/internal/utils"
+	"strings"
+)
+
+// BranchStatus describes one layer of the stack relative to its parent and its remote.
+type BranchStatus struct {
+	Branch    string
+	Parent    string
+	Current   bool
+	CreatedBy string
+
+	Commits      []git.Commit
+	AheadParent  int
+	BehindParent int
+	NeedsRestack bool
+
+	HasRemote    bool
+	AheadRemote  int
+	BehindRemote int
+
+	PRNumber int
+	PRState  string
+	PRURL    string
+	PRReview string
+}
+
+// BranchStatuses collects the status of every branch in the stack, parents first.
+// PR fields come from the PR cache and stay empty when withPRs is false or nothing is known.
+func (s *StackService) BranchStatuses(withPRs bool) ([]BranchStatus, error) {
+	current := utils.CurrentBranch()
+
+	prMap := map[string]branchPRInfo{}
+	if withPRs {
+		prMap = GetPRService().cachedPRMap(s.stack)
+	}
+
+	statuses := []BranchStatus{}
+	for _, br := range s.stack.Topological() {
+		node := s.stack[br]
+		st := BranchStatus{
+			Branch:    br,
+			Parent:    node.ParentBranch,
+			Current:   br == current,
+			CreatedBy: node.CreatedBy,
+		}
+
+		if !git.RefExists(br) {
+			logs.Warn("Branch '%s' is tracked in the stack but does not exist locally", br)
+			statuses = append(statuses, st)
+			continue
+		}
+
+		if node.ParentBranch != "" && git.RefExists(node.ParentBranch) {
+			commits, err := git.CommitsBetween(node.ParentBranch, br)
+			if err != nil {
+				return nil, err
+			}
+			st.Commits = commits
+			ahead, behind, err := git.AheadBehind(node.ParentBranch, br)
+			if err != nil {
+				return nil, err
+			}
+			st.AheadParent, st.BehindParent = ahead, behind
+			// If the parent has commits the branch lacks, the parent moved since the last restack.
+			st.NeedsRestack = behind > 0
+		}
+
+		remote := "origin/" + br
+		if git.RefExists(remote) {
+			st.HasRemote = true
+			ahead, behind, err := git.AheadBehind(remote, br)
+			if err != nil {
+				return nil, err
+			}
+			st.AheadRemote, st.BehindRemote = ahead, behind
+		}
+
+		if info, ok := prMap[br]; ok {
+			st.PRNumber = info.Number
+			st.PRState = info.State
+			st.PRURL = info.URL
+			st.PRReview = info.Review
+		}
+
+		statuses = append(statuses, st)
+	}
+	return statuses, nil
+}
+
+// RenderStackLog draws the stack as a box-drawing tree annotated with each layer's status.
+func (s *StackService) RenderStackLog(withPRs bool) (string, error) {
+	return s.renderStatusTree(withPRs, false)
+}
+
+// RenderStackTree is the compact form of RenderStackLog used by `strata view`: one line per
+// layer with its markers and counts, without the commit subjects.
+func (s *StackService) RenderStackTree(withPRs bool) (string, error) {
+	return s.renderStatusTree(withPRs, true)
+}
+
+func (s *StackService) renderStatusTree(withPRs, compact bool) (string, error) {
+	statuses, err := s.BranchStatuses(withPRs)
+	if err != nil {
+		return "", err
+	}
+	byBranch := map[string]BranchStatus{}
+	for _, st := range statuses {
+		byBranch[st.Branch] = st
+	}
+
+	var b strings.Builder
+	visited := map[string]bool{}
+	roots := s.stack.Roots()
+	for _, root := range roots {
+		s.writeLogNode(&b, root, "", "", byBranch, visited, compact)
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+// writeLogNode writes one branch line plus its detail lines, then recurses into children.
+// linePrefix is printed before the branch name, childPrefix before everything nested under it.
+// In compact mode the details go on the branch line and the commits are left out.
+func (s *StackService) writeLogNode(b *strings.Builder, br, linePrefix, childPrefix string, byBranch map[string]BranchStatus, visited map[string]bool, compact bool) {
+	node := s.stack[br]
+	if node == nil || visited[br] {
+		return
+	}
+	visited[br] = true
+	st := byBranch[br]
+
+	marker := ui.Colorize("○", ui.Dim)
+	name := ui.Colorize(br, ui.Bold)
+	if st.Current {
+		marker = ui.Colorize("●", ui.FgGreen, ui.Bold)
+		name = ui.Colorize(br, ui.FgGreen, ui.Bold)
+	}
+
+	header := []string{marker + " " + name}
+	if st.PRNumber != 0 {
+		header = append(header, prStateLabel(st.PRNumber, st.PRState))
+		if st.PRReview != "" {
+			header = append(header, ui.Colorize(reviewLabel(st.PRReview), ui.Dim))
+		}
+	}
+	if st.NeedsRestack {
+		header = append(header, ui.Colorize("needs restack", ui.FgYellow, ui.Bold))
+	}
+	details := []string{}
+	if st.Parent != "" {
+		details = append(details, fmt.Sprintf("%s · ↑%d ↓%d %s",
+			pluralize(len(st.Commits), "commit"), st.AheadParent, st.BehindParent, st.Parent))
+	}
+	if st.HasRemote {
+		details = append(details, fmt.Sprintf("↑%d ↓%d origin", st.AheadRemote, st.BehindRemote))
+	} else if st.Parent != "" {
+		details = append(details, "not pushed")
+	}
+	if st.CreatedBy != "" {
+		details = append(details, "by "+st.CreatedBy)
+	}
+
+	kids := []string{}
+	for _, c := range node.Children {
+		if s.stack[c] != nil && !visited[c] {
+			kids = append(kids, c)
+		}
+	}
+	detailPrefix := childPrefix + "   "
+	if len(kids) > 0 {
+		detailPrefix = childPrefix + "│  "
+	}
+	if compact {
+		if len(details) > 0 {
+			header = append(header, ui.Colorize(strings.Join(details, " · "), ui.Dim))
+		}
+		b.WriteString(linePrefix + strings.Join(header, "  ") + "\n")
+	} else {
+		b.WriteString(linePrefix + strings.Join(header, "  ") + "\n")
+		if len(details) > 0 {
+			b.WriteString(detailPrefix + ui.Colorize(strings.Join(details, " · "), ui.Dim) + "\n")
+		}
+		for _, c := range st.Commits {
+			short := c.Hash
+			if len(short) > 7 {
+				short = short[:7]
+			}
+			b.WriteString(detailPrefix + ui.Colorize(short, ui.FgYellow) + " " + c.Subject + "\n")
+		}
+	}
+
+	for i, c := range kids {
+		if i == len(kids)-1 {
+			s.writeLogNode(b, c, childPrefix+"└─ ", childPrefix+"   ", byBranch, visited, compact)
+		} else {
+			s.writeLogNode(b, c, childPrefix+"├─ ", childPrefix+"│  ", byBranch, visited, compact)
+		}
+	}
+}
+
+func prStateLabel(number int, state string) string {
+	label := fmt.Sprintf("#%d %s", number, strings.ToLower(state))
+	switch state {
+	case "OPEN":
+		return ui.Colorize(label, ui.FgGreen)
+	case "DRAFT":
+		return ui.Colorize(label, ui.FgYellow)
+	case "MERGED":
+		return ui.Colorize(label, ui.FgMagenta)
+	case "CLOSED":
+		return ui.Colorize(label, ui.FgRed)
+	default:
+		return label
+	}
+}
+
+func pluralize(n int, word string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, word)
+	}
+	return fmt.Sprintf("%d %ss", n, word)
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"strata/internal/config"
+	"strata/internal/git"
+	"strata/internal/logs"
+	"strings"
+)
+
+const (
+	// trunkConfigKey overrides the default trunk, e.g. "develop".
+	trunkConfigKey = "trunk_branch"
+	// extraTrunksConfigKey lists further long-lived branches stacks may land on, comma separated
+	// (e.g. "release/2.3,release/2.4").
+	extraTrunksConfigKey = "trunk_branches"
+)
+
+var defaultTrunk string
+
+// DefaultTrunk resolves the repository's main trunk: the trunk_branch config value, then
+// origin/HEAD, then whichever of main/master exists locally.
+func DefaultTrunk() string {
+	if defaultTrunk != "" {
+		return defaultTrunk
+	}
+	switch {
+	case config.GetConfigValue(trunkConfigKey) != "":
+		defaultTrunk = config.GetConfigValue(trunkConfigKey)
+	case git.RemoteDefaultBranch() != "":
+		defaultTrunk = git.RemoteDefaultBranch()
+	case !git.RefExists("main") && git.RefExists("master"):
+		defaultTrunk = "master"
+	default:
+		defaultTrunk = "main"
+	}
+	logs.Debug("Resolved default trunk: %s", defaultTrunk)
+	return defaultTrunk
+}
+
+// Trunks returns every branch treated as a trunk: the default trunk, the configured
+// extra trunks and the trunks of named stacks.
+func (s *StackService) Trunks() []string {
+	trunks := []string{DefaultTrunk()}
+	seen := map[string]bool{trunks[0]: true}
+	add := func(t string) {
+		t = strings.TrimSpace(t)
+		if t != "" && !seen[t] {
+			seen[t] = true
+			trunks = append(trunks, t)
+		}
+	}
+	for _, t := range strings.Split(config.GetConfigValue(extraTrunksConfigKey), ",") {
+		add(t)
+	}
+	for _, name := range s.StackNames(false) {
+		add(s.stacks[name].Trunk)
+	}
+	return trunks
+}
+
+// IsTrunk reports whether branch is one of the trunks layers land on.
+func (s *StackService) IsTrunk(branch string) bool {
+	if branch == "" {
+		return false
+	}
+	for _, t := range s.Trunks() {
+		if t == branch {
+			return true
+		}
+	}
+	return false
+}
+
+// TrunkFor resolves the trunk a layer ultimately lands on. A trunk at the bottom of the
+// layer's chain wins, then the trunk of its named stack, then the default trunk.
+func (s *StackService) TrunkFor(branch string) string {
+	visited := map[string]bool{}
+	cur := branch
+	for cur != "" && !visited[cur] {
+		visited[cur] = true
+		if cur != branch && s.IsTrunk(cur) {
+			return cur
+		}
+		node := s.stack[cur]
+		if node == nil {
+			break
+		}
+		cur = node.ParentBranch
+	}
+	if node := s.stack[branch]; node != nil && node.Stack != "" {
+		if st, ok := s.stacks[node.Stack]; ok && st.Trunk != "" {
+			return st.Trunk
+		}
+	}
+	return DefaultTrunk()
+}
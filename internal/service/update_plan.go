@@ -0,0 +1,269 @@
+package service
+
+import (
+	"fmt"
+	"strata/internal/git"
+	"strata/internal/ui"
+	"strata/internal/utils"
+	"strings"
+)
+
+// What `strata update` would do to a layer, as predicted by PlanUpdate.
+const (
+	PlanUpToDate    = "up_to_date"
+	PlanFastForward = "fast_forward"
+	PlanRebase      = "rebase"
+	PlanConflict    = "conflict"
+	// PlanBlocked layers sit on a layer whose update would fail, so update never gets to them.
+	PlanBlocked = "blocked"
+	PlanMissing = "missing"
+)
+
+// PlannedLayer is the predicted outcome of `strata update` for one branch.
+type PlannedLayer struct {
+	Branch string
+	Parent string
+	Action string
+	// Commits is how many commits would be replayed, or fast-forwarded for a trunk.
+	Commits int
+	// Files lists the files that would conflict.
+	Files []string
+	// Note adds context, e.g. that the current branch's working files would change.
+	Note string
+}
+
+// UpdatePlan is the dry run of `strata update`, parents first.
+type UpdatePlan struct {
+	Layers []PlannedLayer
+}
+
+// Count returns how many layers would get action.
+func (p UpdatePlan) Count(action string) int {
+	n := 0
+	for _, l := range p.Layers {
+		if l.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+// Commits returns how many commits would be replayed in total.
+func (p UpdatePlan) Commits() int {
+	n := 0
+	for _, l := range p.Layers {
+		if l.Action == PlanRebase || l.Action == PlanConflict {
+			n += l.Commits
+		}
+	}
+	return n
+}
+
+// PlanUpdate predicts what UpdateEntireStack would do without changing any ref or file:
+// whether each layer is up to date, replays cleanly or conflicts, and in which files.
+// Each layer is test-merged onto its parent with `git merge-tree`; when the parent would
+// be rewritten too, the layer is test-merged onto the parent's simulated result, so
+// conflicts further up the stack are found as well. The simulated commits are written to
+// the object database, where nothing refers to them. Trunks are compared with their
+// remote-tracking branches as last fetched.
+func (s *StackService) PlanUpdate(stackName string) (UpdatePlan, error) {
+	plan := UpdatePlan{}
+	scope := s.stack
+	if stackName != "" {
+		var err error
+		if scope, err = s.StackLayers(stackName); err != nil {
+			return plan, err
+		}
+	}
+
+	current := utils.CurrentBranch()
+	dirty := current != "" && !git.WorktreeClean("")
+	// tips maps a branch to the commit it would point to after the update
+	tips := map[string]string{}
+	failed := map[string]string{}
+	tipOf := func(br string) (string, bool) {
+		if tip, ok := tips[br]; ok {
+			return tip, true
+		}
+		tip, err := git.RevParse("refs/heads/" + br)
+		if err != nil {
+			return "", false
+		}
+		tips[br] = tip
+		return tip, true
+	}
+
+	for _, br := range scope.Topological() {
+		node := scope[br]
+		layer := PlannedLayer{Branch: br, Parent: node.ParentBranch}
+		tip, ok := tipOf(br)
+		switch {
+		case !ok:
+			layer.Action = PlanMissing
+			layer.Note = "no local branch"
+			failed[br] = br
+		case node.ParentBranch == "":
+			s.planTrunk(&layer, tip, tips)
+		case failed[node.ParentBranch] != "":
+			layer.Action = PlanBlocked
+			layer.Note = fmt.Sprintf("'%s' can't be updated", failed[node.ParentBranch])
+			failed[br] = failed[node.ParentBranch]
+		default:
+			parentTip, ok := tipOf(node.ParentBranch)
+			if !ok {
+				layer.Action = PlanMissing
+				layer.Note = fmt.Sprintf("parent '%s' has no local branch", node.ParentBranch)
+				failed[br] = br
+				break
+			}
+			if err := s.planRebase(&layer, tip, parentTip, tips); err != nil {
+				return plan, err
+			}
+			if layer.Action == PlanConflict {
+				failed[br] = br
+			}
+		}
+		if br == current && layer.Action != PlanUpToDate && layer.Action != PlanBlocked {
+			if dirty {
+				layer.Note = joinNote(layer.Note, "current branch has uncommitted changes; update will stop here")
+				failed[br] = br
+			} else {
+				layer.Note = joinNote(layer.Note, "current branch: your working files will change")
+			}
+		}
+		plan.Layers = append(plan.Layers, layer)
+	}
+	return plan, nil
+}
+
+// planTrunk predicts the fast-forward of a stack root from origin.
+func (s *StackService) planTrunk(layer *PlannedLayer, tip string, tips map[string]string) {
+	layer.Action = PlanUpToDate
+	remote := "refs/remotes/origin/" + layer.Branch
+	if !git.RefExists(remote) {
+		layer.Note = "not on origin"
+		return
+	}
+	ahead, behind, err := git.AheadBehind(layer.Branch, remote)
+	switch {
+	case err != nil || ahead == 0:
+		return
+	case behind > 0:
+		// behind counts the local commits origin lacks, ahead the ones origin has on top
+		layer.Note = fmt.Sprintf("diverged from origin: %d local and %d remote commits", behind, ahead)
+	default:
+		layer.Action = PlanFastForward
+		layer.Commits = ahead
+		if remoteTip, err := git.RevParse(remote); err == nil {
+			tips[layer.Branch] = remoteTip
+		}
+	}
+}
+
+// planRebase test-merges the layer at tip onto its parent's (possibly simulated) tip. A
+// clean result is recorded as a commit whose parents are the new parent tip and the old
+// layer tip, so the layers above merge onto it with the old tip as their merge base.
+func (s *StackService) planRebase(layer *PlannedLayer, tip, parentTip string, tips map[string]string) error {
+	if git.IsAncestor(parentTip, tip) {
+		layer.Action = PlanUpToDate
+		return nil
+	}
+	// against the predicted tip, since the parent may be rewritten or fast-forwarded first
+	n, err := git.ReplayCount(parentTip, tip)
+	if err != nil {
+		return err
+	}
+	layer.Commits = n
+	tree, files, err := git.MergeTreeWrite(parentTip, tip)
+	if err != nil {
+		return err
+	}
+	if len(files) > 0 {
+		layer.Action = PlanConflict
+		layer.Files = files
+		return nil
+	}
+	layer.Action = PlanRebase
+	sim, err := git.CommitTree(tree, "strata update --plan: "+layer.Branch, parentTip, tip)
+	if err != nil {
+		return err
+	}
+	tips[layer.Branch] = sim
+	return nil
+}
+
+func joinNote(a, b string) string {
+	if a == "" || b == "" {
+		return a + b
+	}
+	return a + "; " + b
+}
+
+// RenderUpdatePlan shows the plan as a table with the conflicting files under their layer,
+// followed by an estimate of the work.
+func RenderUpdatePlan(p UpdatePlan) string {
+	labels := map[string]string{
+		PlanUpToDate: "up to date", PlanFastForward: "fast-forward", PlanRebase: "rebase",
+		PlanConflict: "CONFLICT", PlanBlocked: "blocked", PlanMissing: "missing",
+	}
+	rows := [][]string{{"LAYER", "PARENT", "ACTION", "COMMITS", "NOTE"}}
+	for _, l := range p.Layers {
+		commits := ""
+		if l.Commits > 0 {
+			commits = fmt.Sprintf("%d", l.Commits)
+		}
+		note := l.Note
+		if l.Action == PlanConflict {
+			note = joinNote(pluralize(len(l.Files), "conflicting file"), note)
+		}
+		rows = append(rows, []string{l.Branch, l.Parent, labels[l.Action], commits, note})
+	}
+	lines := alignRows(rows)
+
+	var b strings.Builder
+	b.WriteString(ui.Colorize(lines[0], ui.Bold) + "\n")
+	for i, l := range p.Layers {
+		line := lines[i+1]
+		switch l.Action {
+		case PlanUpToDate:
+			line = ui.Colorize(line, ui.Dim)
+		case PlanRebase, PlanFastForward:
+			line = ui.Colorize(line, ui.FgGreen)
+		case PlanConflict:
+			line = ui.Colorize(line, ui.FgRed)
+		default:
+			line = ui.Colorize(line, ui.FgYellow)
+		}
+		b.WriteString(line + "\n")
+		for _, f := range l.Files {
+			b.WriteString("      " + f + "\n")
+		}
+	}
+
+	b.WriteString("\n")
+	rebases, conflicts := p.Count(PlanRebase), p.Count(PlanConflict)
+	if rebases+conflicts+p.Count(PlanFastForward) == 0 {
+		b.WriteString("Everything is up to date; update has nothing to do.")
+		return b.String()
+	}
+	work := []string{}
+	if n := p.Count(PlanFastForward); n > 0 {
+		work = append(work, pluralize(n, "trunk")+" to fast-forward")
+	}
+	if rebases+conflicts > 0 {
+		work = append(work, fmt.Sprintf("%s to rebase, replaying %s", pluralize(rebases+conflicts, "layer"), pluralize(p.Commits(), "commit")))
+	}
+	b.WriteString("Estimated work: " + strings.Join(work, "; ") + ".")
+	if conflicts > 0 {
+		files := 0
+		for _, l := range p.Layers {
+			files += len(l.Files)
+		}
+		b.WriteString(fmt.Sprintf("\n%s with %s to resolve by hand", pluralize(conflicts, "layer"), pluralize(files, "conflicting file")))
+		if n := p.Count(PlanBlocked); n > 0 {
+			b.WriteString(fmt.Sprintf("; %s above them will wait", pluralize(n, "layer")))
+		}
+		b.WriteString(".")
+	}
+	return b.String()
+}
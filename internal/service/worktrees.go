@@ -0,0 +1,156 @@
+package service
+
+import (
+	"fmt"
+	"path/filepath"
+	"strata/internal/config"
+	"strata/internal/errs"
+	"strata/internal/git"
+	"strata/internal/logs"
+	"strings"
+)
+
+// worktreeDirKey sets where `strata worktree add` creates layer worktrees. A relative path
+// is taken from the main worktree; the default is "../<repo>.worktrees".
+const worktreeDirKey = "worktree_dir"
+
+// LayerWorktree is a layer and the worktree it is checked out in.
+type LayerWorktree struct {
+	Branch string
+	// Path is empty when the layer isn't checked out anywhere.
+	Path string
+	// Current marks the worktree strata was run from.
+	Current bool
+	// Created is set by AddWorktrees for worktrees it made.
+	Created bool
+	// Reason explains why PruneWorktrees kept a worktree; it is empty for removed ones.
+	Reason string
+}
+
+// WorktreeRoot is the directory layer worktrees are created in.
+func WorktreeRoot() (string, error) {
+	main, err := git.MainWorktree()
+	if err != nil {
+		return "", err
+	}
+	if dir := config.GetConfigValue(worktreeDirKey); dir != "" {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(main, dir)
+		}
+		return filepath.Clean(dir), nil
+	}
+	return filepath.Join(filepath.Dir(main), filepath.Base(main)+".worktrees"), nil
+}
+
+// AddWorktrees gives each layer its own worktree under WorktreeRoot, named after the
+// branch. With all or stackName set, every layer (of the named stack) gets one. Layers
+// already checked out somewhere keep that worktree.
+func (s *StackService) AddWorktrees(branches []string, all bool, stackName string) ([]LayerWorktree, error) {
+	if all || stackName != "" {
+		var err error
+		if branches, err = s.layerRange(stackName, "", ""); err != nil {
+			return nil, err
+		}
+	}
+	if len(branches) == 0 {
+		return nil, fmt.Errorf("name the layers to check out, or pass --all or --stack")
+	}
+	for _, br := range branches {
+		if _, ok := s.stack[br]; !ok {
+			return nil, errs.NotInStack("branch '%s' not in stack", br)
+		}
+	}
+	root, err := WorktreeRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	results := []LayerWorktree{}
+	for _, br := range branches {
+		if dir := git.WorktreeFor(br); dir != "" {
+			results = append(results, LayerWorktree{Branch: br, Path: dir})
+			continue
+		}
+		dir := filepath.Join(root, br)
+		if err := git.CheckoutWorktree(dir, br); err != nil {
+			return results, err
+		}
+		logs.Info("Checked out '%s' in worktree %s", br, dir)
+		results = append(results, LayerWorktree{Branch: br, Path: dir, Created: true})
+	}
+	return results, nil
+}
+
+// LayerWorktrees lists every branch of the stack, parents first, with the worktree it is
+// checked out in.
+func (s *StackService) LayerWorktrees() ([]LayerWorktree, error) {
+	list, err := git.Worktrees()
+	if err != nil {
+		return nil, err
+	}
+	byBranch := map[string]string{}
+	for _, wt := range list {
+		if wt.Branch != "" {
+			byBranch[wt.Branch] = wt.Path
+		}
+	}
+	current, _ := git.TopLevel()
+	out := []LayerWorktree{}
+	for _, br := range s.stack.Topological() {
+		dir := byBranch[br]
+		out = append(out, LayerWorktree{Branch: br, Path: dir, Current: dir != "" && filepath.Clean(dir) == filepath.Clean(current)})
+	}
+	return out, nil
+}
+
+// LayerPath returns the worktree branch is checked out in, or "".
+func (s *StackService) LayerPath(branch string) string {
+	return git.WorktreeFor(branch)
+}
+
+// PruneWorktrees removes the worktrees under WorktreeRoot whose branch has left the stack,
+// been deleted or been detached, e.g. after the layer landed. Worktrees with uncommitted
+// changes are kept unless force is set, and so is the current one. Stale entries of
+// worktrees whose directory is gone are dropped as well. The removed worktrees come first
+// in the result, followed by the ones kept with their Reason.
+func (s *StackService) PruneWorktrees(force bool) ([]LayerWorktree, error) {
+	if err := git.PruneWorktrees(); err != nil {
+		return nil, err
+	}
+	root, err := WorktreeRoot()
+	if err != nil {
+		return nil, err
+	}
+	list, err := git.Worktrees()
+	if err != nil {
+		return nil, err
+	}
+	current, _ := git.TopLevel()
+
+	removed, kept := []LayerWorktree{}, []LayerWorktree{}
+	for _, wt := range list {
+		if !strings.HasPrefix(filepath.Clean(wt.Path), root+string(filepath.Separator)) {
+			continue
+		}
+		if wt.Branch != "" && s.stack[wt.Branch] != nil && git.RefExists("refs/heads/"+wt.Branch) {
+			continue
+		}
+		lw := LayerWorktree{Branch: wt.Branch, Path: wt.Path}
+		switch {
+		case filepath.Clean(wt.Path) == filepath.Clean(current):
+			lw.Reason = "it is the current worktree"
+		case !force && !git.WorktreeClean(wt.Path):
+			lw.Reason = "it has uncommitted changes; use --force to remove it anyway"
+		}
+		if lw.Reason != "" {
+			kept = append(kept, lw)
+			continue
+		}
+		if err := git.RemoveWorktree(wt.Path); err != nil {
+			return append(removed, kept...), err
+		}
+		logs.Info("Removed worktree %s of '%s'", wt.Path, wt.Branch)
+		removed = append(removed, lw)
+	}
+	return append(removed, kept...), nil
+}
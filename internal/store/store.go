@@ -1,12 +1,16 @@
 package store
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"time"
 
 	"gopkg.in/yaml.v3"
 	"strata/internal/config"
+	"strata/internal/git"
 	"strata/internal/logs"
 	"strata/internal/model"
 )
@@ -14,9 +18,15 @@ import (
 // We keep the stack data in .strata_repo_stack.yaml for clarity, separate from config.
 const StackFileName = "strata_repo_stack.yaml"
 
+// StackFilePath is where the stack file lives: at the top of the main worktree, shared by
+// the linked ones.
+func StackFilePath() string {
+	return config.RepoFile(StackFileName)
+}
+
 // LoadStack reads the stack data from disk
 func LoadStack() (model.StackTree, error) {
-	p := filepath.Join(".", StackFileName)
+	p := StackFilePath()
 	if _, err := os.Stat(p); os.IsNotExist(err) {
 		// If file doesn't exist, we can initialize an empty stack
 		logs.Info("No existing stack file found. Creating new empty stack.")
@@ -39,13 +49,46 @@ func SaveStack(st model.StackTree) error {
 	if err != nil {
 		return fmt.Errorf("failed to marshal stack data: %v", err)
 	}
-	p := filepath.Join(".", StackFileName)
+	p := StackFilePath()
 	if err := os.WriteFile(p, out, 0644); err != nil {
 		return fmt.Errorf("failed to write stack file: %v", err)
 	}
 	return nil
 }
 
+// StacksFileName holds the metadata of named stacks; layers reference them by name.
+const StacksFileName = "strata_repo_stacks.yaml"
+
+// LoadStacks reads the named stack metadata from disk
+func LoadStacks() (model.Stacks, error) {
+	p := config.RepoFile(StacksFileName)
+	if _, err := os.Stat(p); os.IsNotExist(err) {
+		return model.Stacks{}, nil
+	}
+	content, err := os.ReadFile(p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stacks file: %v", err)
+	}
+	stacks := model.Stacks{}
+	if err := yaml.Unmarshal(content, &stacks); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal stacks file: %v", err)
+	}
+	return stacks, nil
+}
+
+// SaveStacks writes the named stack metadata to disk
+func SaveStacks(stacks model.Stacks) error {
+	out, err := yaml.Marshal(stacks)
+	if err != nil {
+		return fmt.Errorf("failed to marshal stacks data: %v", err)
+	}
+	p := config.RepoFile(StacksFileName)
+	if err := os.WriteFile(p, out, 0644); err != nil {
+		return fmt.Errorf("failed to write stacks file: %v", err)
+	}
+	return nil
+}
+
 // The local config could define a custom path if needed, e.g., "stack_file = custom_stack.yml"
 func getStackPath() string {
 	custom := config.GetConfigValue("stack_file")
@@ -54,3 +97,257 @@ func getStackPath() string {
 	}
 	return StackFileName
 }
+
+// LandRunFileName holds the progress of a `strata land --queue` run while it lasts. It is
+// kept in StateDir, since the run belongs to this clone whichever worktree resumes it.
+const LandRunFileName = "land_run.yaml"
+
+func landRunPath() (string, error) {
+	dir, err := StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, LandRunFileName), nil
+}
+
+// LoadLandRun reads the saved merge queue run, or nil if none is in progress
+func LoadLandRun() (*model.LandRun, error) {
+	p, err := landRunPath()
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(p)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read land run file: %v", err)
+	}
+	run := &model.LandRun{}
+	if err := yaml.Unmarshal(content, run); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal land run file: %v", err)
+	}
+	return run, nil
+}
+
+// SaveLandRun writes the merge queue run to disk
+func SaveLandRun(run *model.LandRun) error {
+	out, err := yaml.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("failed to marshal land run: %v", err)
+	}
+	p, err := landRunPath()
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(p, out); err != nil {
+		return fmt.Errorf("failed to write land run file: %v", err)
+	}
+	return nil
+}
+
+// ClearLandRun removes the merge queue run once it has finished or been aborted
+func ClearLandRun() error {
+	p, err := landRunPath()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove land run file: %v", err)
+	}
+	return nil
+}
+
+// PRCacheFileName lives in .git/strata/: the cache is local to the clone and never committed.
+const PRCacheFileName = "pr_cache.yaml"
+
+// StateDir is .git/strata/, where Strata keeps the clone-local state shared by all worktrees.
+func StateDir() (string, error) {
+	dir, err := git.CommonDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "strata"), nil
+}
+
+func prCachePath() (string, error) {
+	dir, err := StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, PRCacheFileName), nil
+}
+
+// LoadPRCache reads the PR metadata cache, or an empty one if there is none yet
+func LoadPRCache() (*model.PRCache, error) {
+	c := &model.PRCache{Branches: map[string]model.CachedPR{}}
+	p, err := prCachePath()
+	if err != nil {
+		return c, err
+	}
+	content, err := os.ReadFile(p)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return c, fmt.Errorf("failed to read PR cache: %v", err)
+	}
+	if err := yaml.Unmarshal(content, c); err != nil {
+		return &model.PRCache{Branches: map[string]model.CachedPR{}}, fmt.Errorf("failed to unmarshal PR cache: %v", err)
+	}
+	if c.Branches == nil {
+		c.Branches = map[string]model.CachedPR{}
+	}
+	return c, nil
+}
+
+// UpdatePRCache applies update to the PR metadata cache on disk. The daemon refreshes the
+// cache while commands write their own lookups through, so the cache is read, updated and
+// written back under a lock file, and no writer drops entries another just wrote. An
+// unreadable cache is rebuilt.
+func UpdatePRCache(update func(*model.PRCache)) error {
+	p, err := prCachePath()
+	if err != nil {
+		return err
+	}
+	unlock, err := lockFile(p + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	c, err := LoadPRCache()
+	if err != nil {
+		logs.Debug("Rebuilding unreadable PR cache: %v", err)
+	}
+	update(c)
+	out, err := yaml.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to marshal PR cache: %v", err)
+	}
+	if err := writeFileAtomic(p, out); err != nil {
+		return fmt.Errorf("failed to write PR cache: %v", err)
+	}
+	return nil
+}
+
+// How long lockFile waits for a lock, and the age at which a lock is taken to be left over
+// from a process that died holding it.
+const (
+	lockWait  = 5 * time.Second
+	staleLock = 30 * time.Second
+)
+
+// lockFile takes the lock file at p, which other processes honour as well, and returns the
+// function releasing it.
+func lockFile(p string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(lockWait)
+	for {
+		f, err := os.OpenFile(p, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(p) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to lock %s: %v", p, err)
+		}
+		if info, err := os.Stat(p); err == nil && time.Since(info.ModTime()) > staleLock {
+			logs.Warn("Removing stale lock %s", p)
+			os.Remove(p)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for %s; remove it if no strata command is running", p)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// writeFileAtomic replaces the file at p with data through a temporary file, so concurrent
+// readers never see it half written.
+func writeFileAtomic(p string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p), filepath.Base(p)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+// ExecCacheFileName lives in .git/strata/ next to the PR cache.
+const ExecCacheFileName = "exec_cache.yaml"
+
+// LoadExecCache reads the results of earlier `strata exec` runs, or an empty cache.
+func LoadExecCache() (*model.ExecCache, error) {
+	c := &model.ExecCache{Results: map[string]map[string]model.ExecResult{}}
+	dir, err := StateDir()
+	if err != nil {
+		return c, err
+	}
+	content, err := os.ReadFile(filepath.Join(dir, ExecCacheFileName))
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return c, fmt.Errorf("failed to read exec cache: %v", err)
+	}
+	if err := yaml.Unmarshal(content, c); err != nil {
+		return &model.ExecCache{Results: map[string]map[string]model.ExecResult{}}, fmt.Errorf("failed to unmarshal exec cache: %v", err)
+	}
+	if c.Results == nil {
+		c.Results = map[string]map[string]model.ExecResult{}
+	}
+	return c, nil
+}
+
+// SaveExecCache writes the results of `strata exec` runs.
+func SaveExecCache(c *model.ExecCache) error {
+	out, err := yaml.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to marshal exec cache: %v", err)
+	}
+	dir, err := StateDir()
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(dir, ExecCacheFileName), out); err != nil {
+		return fmt.Errorf("failed to write exec cache: %v", err)
+	}
+	return nil
+}
+
+// PolicyFileName holds the org's merge rules checked by `strata ci check`; it is committed
+// with the code.
+const PolicyFileName = ".strata/policy.yaml"
+
+// LoadPolicy reads the policy file at path, or nil if there is none. Unknown keys are
+// rejected so a misspelt rule doesn't silently go unchecked.
+func LoadPolicy(path string) (*model.Policy, error) {
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %v", err)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(content))
+	dec.KnownFields(true)
+	policy := &model.Policy{}
+	if err := dec.Decode(policy); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to parse policy file %s: %v", path, err)
+	}
+	return policy, nil
+}
@@ -0,0 +1,531 @@
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strata/internal/git"
+	"strata/internal/logs"
+	"strata/internal/service"
+	"strata/internal/store"
+	"strata/internal/ui"
+	"strings"
+	"time"
+)
+
+// pollInterval controls how often the dashboard checks the stack file and refs for outside changes.
+const pollInterval = 2 * time.Second
+
+type treeRow struct {
+	branch string
+	prefix string
+}
+
+type cell struct {
+	text  string
+	codes []string
+}
+
+type checkResult struct {
+	branch  string
+	summary string
+}
+
+// Dashboard is the full-screen `strata tui` view: the stack tree on the left,
+// details for the selected layer on the right.
+type Dashboard struct {
+	term terminal
+
+	rows     []treeRow
+	selected int
+	offset   int
+
+	statuses  map[string]service.BranchStatus
+	prs       map[string]service.BranchStatus // last PR lookup, merged into statuses on refresh
+	checks    map[string]string
+	diffstats map[string]string
+	pending   map[string]bool
+	results   chan checkResult
+
+	message   string
+	snapshot  string
+	stackMod  time.Time
+	lastPoll  time.Time
+	needsDraw bool
+	quit      bool
+}
+
+// Run opens the dashboard and blocks until the user quits.
+func Run() error {
+	d := &Dashboard{
+		checks:  map[string]string{},
+		pending: map[string]bool{},
+		results: make(chan checkResult, 8),
+	}
+	d.refreshPRs()
+	if err := d.reload(); err != nil {
+		return err
+	}
+	if cur := d.indexOf(currentBranch(d.statuses)); cur >= 0 {
+		d.selected = cur
+	}
+
+	if err := d.term.enter(); err != nil {
+		return err
+	}
+	defer d.term.leave()
+
+	d.needsDraw = true
+	buf := make([]byte, 64)
+	for !d.quit {
+		if d.needsDraw {
+			d.draw()
+			d.needsDraw = false
+		}
+
+		// Returns after ~100ms when there's no input (see terminal.enter).
+		n, _ := os.Stdin.Read(buf)
+		for _, ev := range parseKeys(buf[:n]) {
+			d.handleKey(ev)
+			d.needsDraw = true
+		}
+
+		for drained := false; !drained; {
+			select {
+			case r := <-d.results:
+				d.checks[r.branch] = r.summary
+				delete(d.pending, r.branch)
+				d.needsDraw = true
+			default:
+				drained = true
+			}
+		}
+
+		if time.Since(d.lastPoll) > pollInterval {
+			d.lastPoll = time.Now()
+			if d.changedOnDisk() {
+				if err := d.reload(); err != nil {
+					d.message = err.Error()
+				}
+				d.needsDraw = true
+			}
+		}
+	}
+	return nil
+}
+
+// reload re-reads the stack file and recomputes every layer's status.
+func (d *Dashboard) reload() error {
+	svc := service.GetStackService()
+	if err := svc.ReloadStack(); err != nil {
+		return err
+	}
+	statuses, err := svc.BranchStatuses(false)
+	if err != nil {
+		return err
+	}
+
+	d.statuses = map[string]service.BranchStatus{}
+	for _, st := range statuses {
+		if pr, ok := d.prs[st.Branch]; ok {
+			st.PRNumber, st.PRState, st.PRURL = pr.PRNumber, pr.PRState, pr.PRURL
+		}
+		d.statuses[st.Branch] = st
+	}
+	d.diffstats = map[string]string{}
+
+	selected := ""
+	if d.selected < len(d.rows) {
+		selected = d.rows[d.selected].branch
+	}
+	d.rows = buildRows(svc)
+	if i := d.indexOf(selected); i >= 0 {
+		d.selected = i
+	} else if d.selected >= len(d.rows) {
+		d.selected = len(d.rows) - 1
+	}
+	if d.selected < 0 {
+		d.selected = 0
+	}
+
+	d.snapshot, _ = git.RefSnapshot()
+	if info, err := os.Stat(store.StackFilePath()); err == nil {
+		d.stackMod = info.ModTime()
+	}
+	return nil
+}
+
+// refreshPRs looks up PR state for every layer. This is the only call that needs the network.
+func (d *Dashboard) refreshPRs() {
+	statuses, err := service.GetStackService().BranchStatuses(true)
+	if err != nil {
+		d.message = fmt.Sprintf("PR lookup failed: %v", err)
+		return
+	}
+	d.prs = map[string]service.BranchStatus{}
+	for _, st := range statuses {
+		if st.PRNumber != 0 {
+			d.prs[st.Branch] = st
+		}
+	}
+	d.checks = map[string]string{}
+}
+
+func (d *Dashboard) changedOnDisk() bool {
+	if snap, err := git.RefSnapshot(); err == nil && snap != d.snapshot {
+		return true
+	}
+	if info, err := os.Stat(store.StackFilePath()); err == nil && !info.ModTime().Equal(d.stackMod) {
+		return true
+	}
+	return false
+}
+
+func buildRows(svc *service.StackService) []treeRow {
+	stack := svc.GetStack()
+	rows := []treeRow{}
+	visited := map[string]bool{}
+	var walk func(br, linePrefix, childPrefix string)
+	walk = func(br, linePrefix, childPrefix string) {
+		node := stack[br]
+		if node == nil || visited[br] {
+			return
+		}
+		visited[br] = true
+		rows = append(rows, treeRow{branch: br, prefix: linePrefix})
+		kids := []string{}
+		for _, c := range node.Children {
+			if stack[c] != nil && !visited[c] {
+				kids = append(kids, c)
+			}
+		}
+		for i, c := range kids {
+			if i == len(kids)-1 {
+				walk(c, childPrefix+"└─ ", childPrefix+"   ")
+			} else {
+				walk(c, childPrefix+"├─ ", childPrefix+"│  ")
+			}
+		}
+	}
+	for _, r := range stack.Roots() {
+		walk(r, "", "")
+	}
+	return rows
+}
+
+func (d *Dashboard) indexOf(branch string) int {
+	for i, r := range d.rows {
+		if r.branch == branch {
+			return i
+		}
+	}
+	return -1
+}
+
+func currentBranch(statuses map[string]service.BranchStatus) string {
+	for br, st := range statuses {
+		if st.Current {
+			return br
+		}
+	}
+	return ""
+}
+
+func (d *Dashboard) selectedBranch() string {
+	if d.selected < 0 || d.selected >= len(d.rows) {
+		return ""
+	}
+	return d.rows[d.selected].branch
+}
+
+func (d *Dashboard) handleKey(ev keyEvent) {
+	d.message = ""
+	branch := d.selectedBranch()
+
+	switch {
+	case ev.key == keyUp || (ev.key == keyRune && ev.r == 'k'):
+		if d.selected > 0 {
+			d.selected--
+		}
+	case ev.key == keyDown || (ev.key == keyRune && ev.r == 'j'):
+		if d.selected < len(d.rows)-1 {
+			d.selected++
+		}
+	case ev.key == keyRune && (ev.r == 'q' || ev.r == 0x03): // q or Ctrl+C
+		d.quit = true
+	case branch == "":
+		return
+	case ev.key == keyEnter || (ev.key == keyRune && ev.r == 'c'):
+		if err := git.CheckoutBranch(branch); err != nil {
+			d.message = firstLine(err.Error())
+		} else {
+			d.message = fmt.Sprintf("Checked out '%s'", branch)
+			d.reloadOrReport()
+		}
+	case ev.key != keyRune:
+		return
+	case ev.r == 'r':
+		d.suspend(fmt.Sprintf("Restacking '%s' onto its parent...", branch), func() error {
+			return service.GetStackService().RestackLayer(branch)
+		})
+	case ev.r == 'p':
+		d.suspend(fmt.Sprintf("Pushing '%s'...", branch), func() error {
+			return git.PushBranch(branch, true)
+		})
+	case ev.r == 'o':
+		if err := service.GetPRService().OpenPRInBrowser(branch); err != nil {
+			d.message = firstLine(err.Error())
+		}
+	case ev.r == 'n':
+		d.suspend(fmt.Sprintf("Renaming '%s'", branch), func() error {
+			newName := prompt("New name: ")
+			if newName == "" {
+				return fmt.Errorf("rename cancelled")
+			}
+			return service.GetStackService().RenameLayer(branch, newName)
+		})
+	case ev.r == 'f':
+		d.suspend(fmt.Sprintf("Folding '%s' into its parent", branch), func() error {
+			if !confirm(fmt.Sprintf("Merge '%s' into its parent and remove it from the stack?", branch)) {
+				return fmt.Errorf("fold cancelled")
+			}
+			return service.GetStackService().MergeLayer(branch)
+		})
+	case ev.r == 'd':
+		d.suspend(fmt.Sprintf("Deleting '%s'", branch), func() error {
+			if !confirm(fmt.Sprintf("Delete local branch '%s' and remove it from the stack?", branch)) {
+				return fmt.Errorf("delete cancelled")
+			}
+			return service.GetStackService().DeleteLayer(branch)
+		})
+	case ev.r == 'g':
+		d.refreshPRs()
+		d.reloadOrReport()
+		if d.message == "" {
+			d.message = "Refreshed PR state"
+		}
+	}
+}
+
+func (d *Dashboard) reloadOrReport() {
+	if err := d.reload(); err != nil {
+		d.message = firstLine(err.Error())
+	}
+}
+
+// suspend hands the terminal back for operations that print progress or prompt
+// (rebase conflicts, confirmations), then returns to the dashboard.
+func (d *Dashboard) suspend(title string, fn func() error) {
+	d.term.leave()
+	fmt.Println(title)
+	err := fn()
+	if err != nil {
+		logs.Warn("Dashboard action failed: %v", err)
+		fmt.Printf("%s %v\n", ui.Colorize("Failed:", ui.FgRed, ui.Bold), err)
+	} else {
+		fmt.Println(ui.Colorize("Done.", ui.FgGreen, ui.Bold))
+	}
+	prompt("Press Enter to return to the dashboard...")
+	if e := d.term.enter(); e != nil {
+		d.quit = true
+		return
+	}
+	if err != nil {
+		d.message = firstLine(err.Error())
+	}
+	d.reloadOrReport()
+}
+
+func prompt(label string) string {
+	fmt.Print(label)
+	scanner := bufio.NewScanner(os.Stdin)
+	if !scanner.Scan() {
+		return ""
+	}
+	return strings.TrimSpace(scanner.Text())
+}
+
+func confirm(question string) bool {
+	ans := strings.ToLower(prompt(question + " [y/N] "))
+	return ans == "y" || ans == "yes"
+}
+
+func firstLine(s string) string {
+	return strings.SplitN(s, "\n", 2)[0]
+}
+
+// loadDetails fetches the diffstat synchronously (local) and CI checks in the background (network).
+func (d *Dashboard) loadDetails(branch string) {
+	st := d.statuses[branch]
+	if _, ok := d.diffstats[branch]; !ok && st.Parent != "" && git.RefExists(branch) && git.RefExists(st.Parent) {
+		stat, err := git.DiffStat(st.Parent, branch)
+		if err != nil {
+			stat = firstLine(err.Error())
+		}
+		d.diffstats[branch] = stat
+	}
+	if st.PRNumber == 0 || d.pending[branch] {
+		return
+	}
+	if _, ok := d.checks[branch]; ok {
+		return
+	}
+	d.pending[branch] = true
+	go func() {
+		summary, err := service.GetPRService().CheckSummary(branch)
+		if err != nil {
+			summary = "unavailable: " + firstLine(err.Error())
+		}
+		d.results <- checkResult{branch: branch, summary: summary}
+	}()
+}
+
+func (d *Dashboard) draw() {
+	rows, cols := d.term.size()
+	leftW := cols / 3
+	if leftW < 24 {
+		leftW = 24
+	}
+	rightW := cols - leftW - 3
+	bodyH := rows - 3
+	if bodyH < 1 {
+		bodyH = 1
+	}
+
+	if d.selected < d.offset {
+		d.offset = d.selected
+	}
+	if d.selected >= d.offset+bodyH {
+		d.offset = d.selected - bodyH + 1
+	}
+
+	branch := d.selectedBranch()
+	if branch != "" {
+		d.loadDetails(branch)
+	}
+	left := d.treeCells()
+	right := d.detailCells(branch)
+
+	var b strings.Builder
+	b.WriteString(clearScreen)
+	b.WriteString(ui.Colorize(fit(" Strata · stack dashboard", cols), ui.Reverse, ui.Bold) + "\r\n")
+	for i := 0; i < bodyH; i++ {
+		l, r := cell{}, cell{}
+		if i+d.offset < len(left) {
+			l = left[i+d.offset]
+		}
+		if i < len(right) {
+			r = right[i]
+		}
+		b.WriteString(ui.Colorize(fit(l.text, leftW), l.codes...))
+		b.WriteString(ui.Colorize(" │ ", ui.Dim))
+		b.WriteString(ui.Colorize(fit(r.text, rightW), r.codes...))
+		b.WriteString("\r\n")
+	}
+	b.WriteString(ui.Colorize(fit(" "+d.message, cols), ui.FgYellow) + "\r\n")
+	b.WriteString(ui.Colorize(fit(" ↑/↓ move  ⏎ checkout  r restack  p push  o open PR  n rename  f fold  d delete  g refresh  q quit", cols), ui.Dim))
+	fmt.Print(b.String())
+}
+
+func (d *Dashboard) treeCells() []cell {
+	cells := []cell{}
+	for i, r := range d.rows {
+		st := d.statuses[r.branch]
+		marker := "○ "
+		if st.Current {
+			marker = "● "
+		}
+		// A visible cursor keeps the selection usable when colors are disabled.
+		cursor := " "
+		if i == d.selected {
+			cursor = ">"
+		}
+		text := cursor + r.prefix + marker + r.branch
+		if st.NeedsRestack {
+			text += " ↻"
+		}
+		codes := []string{}
+		switch {
+		case i == d.selected:
+			codes = append(codes, ui.Reverse)
+		case st.Current:
+			codes = append(codes, ui.FgGreen, ui.Bold)
+		}
+		cells = append(cells, cell{text: text, codes: codes})
+	}
+	if len(cells) == 0 {
+		cells = append(cells, cell{text: " No stack yet. Create a layer with `strata add <branch>`.", codes: []string{ui.Dim}})
+	}
+	return cells
+}
+
+func (d *Dashboard) detailCells(branch string) []cell {
+	if branch == "" {
+		return nil
+	}
+	st := d.statuses[branch]
+	cells := []cell{{text: branch, codes: []string{ui.Bold}}}
+	add := func(text string, codes ...string) {
+		cells = append(cells, cell{text: text, codes: codes})
+	}
+
+	if st.Parent != "" {
+		add(fmt.Sprintf("Parent:  %s (↑%d ↓%d)", st.Parent, st.AheadParent, st.BehindParent))
+	}
+	if st.NeedsRestack {
+		add("Needs restack: parent has moved", ui.FgYellow)
+	}
+	if st.HasRemote {
+		add(fmt.Sprintf("Remote:  ↑%d ↓%d origin/%s", st.AheadRemote, st.BehindRemote, branch))
+	} else {
+		add("Remote:  not pushed", ui.Dim)
+	}
+	if st.CreatedBy != "" {
+		add("Author:  " + st.CreatedBy)
+	}
+	if st.PRNumber != 0 {
+		add(fmt.Sprintf("PR:      #%d %s", st.PRNumber, strings.ToLower(st.PRState)), prCodes(st.PRState)...)
+		add("         "+st.PRURL, ui.Dim)
+		checks, ok := d.checks[branch]
+		if !ok {
+			checks = "loading..."
+		}
+		add("Checks:  " + checks)
+	} else {
+		add("PR:      none", ui.Dim)
+	}
+
+	add("")
+	add(fmt.Sprintf("Commits (%d)", len(st.Commits)), ui.Bold)
+	for _, c := range st.Commits {
+		short := c.Hash
+		if len(short) > 7 {
+			short = short[:7]
+		}
+		add(short + " " + c.Subject)
+	}
+
+	if stat := d.diffstats[branch]; stat != "" {
+		add("")
+		add("Diffstat", ui.Bold)
+		for _, l := range strings.Split(stat, "\n") {
+			add(l)
+		}
+	}
+	return cells
+}
+
+func prCodes(state string) []string {
+	switch state {
+	case "OPEN":
+		return []string{ui.FgGreen}
+	case "DRAFT":
+		return []string{ui.FgYellow}
+	case "MERGED":
+		return []string{ui.FgMagenta}
+	case "CLOSED":
+		return []string{ui.FgRed}
+	default:
+		return nil
+	}
+}
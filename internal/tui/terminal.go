@@ -0,0 +1,129 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// We drive the terminal with stty and plain ANSI sequences so the dashboard needs no extra dependencies.
+
+const (
+	enterAltScreen = "\033[?1049h"
+	leaveAltScreen = "\033[?1049l"
+	hideCursor     = "\033[?25l"
+	showCursor     = "\033[?25h"
+	clearScreen    = "\033[H\033[2J"
+)
+
+type terminal struct {
+	savedState string
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// enter switches stdin to raw mode and the display to the alternate screen.
+func (t *terminal) enter() error {
+	state, err := stty("-g")
+	if err != nil {
+		return fmt.Errorf("stdin is not a terminal: %v", err)
+	}
+	t.savedState = state
+	// min 0 / time 1 makes reads return after 100ms without input, so the loop can also poll for changes.
+	if _, err := stty("raw", "-echo", "min", "0", "time", "1"); err != nil {
+		return fmt.Errorf("failed to switch terminal to raw mode: %v", err)
+	}
+	fmt.Print(enterAltScreen + hideCursor)
+	return nil
+}
+
+// leave restores the terminal exactly as we found it.
+func (t *terminal) leave() {
+	fmt.Print(showCursor + leaveAltScreen)
+	if t.savedState != "" {
+		stty(t.savedState)
+	}
+}
+
+// size returns the terminal's rows and columns, falling back to 24x80.
+func (t *terminal) size() (int, int) {
+	out, err := stty("size")
+	if err == nil {
+		parts := strings.Fields(out)
+		if len(parts) == 2 {
+			rows, e1 := strconv.Atoi(parts[0])
+			cols, e2 := strconv.Atoi(parts[1])
+			if e1 == nil && e2 == nil && rows > 0 && cols > 0 {
+				return rows, cols
+			}
+		}
+	}
+	return 24, 80
+}
+
+type key int
+
+const (
+	keyNone key = iota
+	keyUp
+	keyDown
+	keyEnter
+	keyRune
+)
+
+type keyEvent struct {
+	key key
+	r   rune
+}
+
+// parseKeys splits a raw read into key events, recognising arrow-key escape sequences.
+func parseKeys(buf []byte) []keyEvent {
+	events := []keyEvent{}
+	for len(buf) > 0 {
+		if len(buf) >= 3 && buf[0] == 0x1b && buf[1] == '[' {
+			switch buf[2] {
+			case 'A':
+				events = append(events, keyEvent{key: keyUp})
+			case 'B':
+				events = append(events, keyEvent{key: keyDown})
+			}
+			buf = buf[3:]
+			continue
+		}
+		if buf[0] == '\r' || buf[0] == '\n' {
+			events = append(events, keyEvent{key: keyEnter})
+			buf = buf[1:]
+			continue
+		}
+		r, n := utf8.DecodeRune(buf)
+		events = append(events, keyEvent{key: keyRune, r: r})
+		buf = buf[n:]
+	}
+	return events
+}
+
+var ansiPattern = regexp.MustCompile("\033\\[[0-9;?]*[a-zA-Z]")
+
+// fit pads or truncates plain text to exactly width visible cells.
+func fit(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	runes := []rune(ansiPattern.ReplaceAllString(s, ""))
+	if len(runes) > width {
+		if width == 1 {
+			return "…"
+		}
+		return string(runes[:width-1]) + "…"
+	}
+	return string(runes) + strings.Repeat(" ", width-len(runes))
+}
@@ -1,7 +1,6 @@
 package ui
 
 import (
-	"fmt"
 	"strings"
 )
 
@@ -17,7 +16,7 @@ func ColorHeadings(text string) string {
 
 	// Replace each heading with its colorized version.
 	for _, heading := range headings {
-		text = strings.ReplaceAll(text, heading, fmt.Sprintf("%s%s%s%s", FgBlue, Bold, heading, Reset))
+		text = strings.ReplaceAll(text, heading, Colorize(heading, FgBlue, Bold))
 	}
 
 	return text
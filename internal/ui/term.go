@@ -0,0 +1,39 @@
+package ui
+
+import "os"
+
+var colorEnabled = detectColor()
+
+// detectColor disables escape codes when NO_COLOR is set, TERM is dumb or stdout is not a terminal.
+func detectColor() bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// ColorEnabled reports whether output should contain ANSI color codes.
+func ColorEnabled() bool {
+	return colorEnabled
+}
+
+// SetColorEnabled overrides terminal detection, e.g. for a --no-color flag.
+func SetColorEnabled(enabled bool) {
+	colorEnabled = enabled
+}
+
+// Colorize wraps text in the given codes, or returns it untouched when color is disabled.
+func Colorize(text string, codes ...string) string {
+	if !colorEnabled || len(codes) == 0 {
+		return text
+	}
+	prefix := ""
+	for _, c := range codes {
+		prefix += c
+	}
+	return prefix + text + Reset
+}
@@ -2,9 +2,11 @@ package utils
 
 import (
 	"bytes"
+	"fmt"
 	"math/rand"
 	"os"
 	"os/exec"
+	"runtime"
 	"strata/internal/logs"
 	"strings"
 	"time"
@@ -100,3 +102,20 @@ func tryGitConfigUserName() string {
 	}
 	return out
 }
+
+// OpenBrowser opens url with the platform's default handler.
+func OpenBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to open %s: %v", url, err)
+	}
+	return nil
+}
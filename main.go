@@ -1,16 +1,16 @@
 package main
 
 import (
-	"fmt"
 	"os"
 	"strata/cmd"
+	"strata/internal/errs"
 	"strata/internal/logs"
 )
 
 func main() {
 	if err := cmd.Execute(); err != nil {
 		logs.Error("CLI error: %v", err)
-		fmt.Fprintln(os.Stderr, "Error: ", err)
-		os.Exit(1)
+		cmd.ReportError(err)
+		os.Exit(errs.ExitCode(err))
 	}
 }